@@ -0,0 +1,120 @@
+package protoconv_test
+
+import (
+	"reflect"
+
+	"google.golang.org/protobuf/encoding/prototext"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/runtime/protoimpl"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+type Chunk struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Data []byte `protobuf:"bytes,1,opt,name=data,proto3"`
+}
+
+func (x *Chunk) ProtoReflect() protoreflect.Message { return cpReflect(x, 0) }
+
+type Blob struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Chunks []*Chunk `protobuf:"bytes,1,rep,name=chunks,proto3"`
+	Zone   string   `protobuf:"bytes,2,opt,name=zone,proto3"`
+	Event  *Event   `protobuf:"bytes,3,opt,name=event,proto3"`
+	Events []*Event `protobuf:"bytes,4,rep,name=events,proto3"`
+	Body   *Text    `protobuf:"bytes,5,opt,name=body,proto3"`
+}
+
+func (x *Blob) ProtoReflect() protoreflect.Message { return cpReflect(x, 1) }
+
+type Event struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	At   *timestamppb.Timestamp `protobuf:"bytes,1,opt,name=at,proto3"`
+	Zone string                 `protobuf:"bytes,2,opt,name=zone,proto3"`
+}
+
+func (x *Event) ProtoReflect() protoreflect.Message { return cpReflect(x, 2) }
+
+type Text struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Text    string `protobuf:"bytes,1,opt,name=text,proto3"`
+	Charset string `protobuf:"bytes,2,opt,name=charset,proto3"`
+}
+
+func (x *Text) ProtoReflect() protoreflect.Message { return cpReflect(x, 3) }
+
+func cpReflect(x interface{}, i int) protoreflect.Message {
+	mi := &cpMsgTypes[i]
+	rv := reflect.ValueOf(x)
+	if protoimpl.UnsafeEnabled && !rv.IsNil() {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(rv.Pointer()))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+var cpMsgTypes = make([]protoimpl.MessageInfo, 4)
+
+var cpFile = func() protoreflect.FileDescriptor {
+	fdp := &descriptorpb.FileDescriptorProto{}
+	if err := prototext.Unmarshal([]byte(`
+		name: "chunk.proto" package: "chunk" syntax: "proto3"
+		dependency: ["google/protobuf/timestamp.proto"]
+		message_type: [
+			{name: "Chunk" field: [{name: "data" number: 1 label: LABEL_OPTIONAL type: TYPE_BYTES json_name: "data"}]},
+			{name: "Blob" field: [
+				{name: "chunks" number: 1 label: LABEL_REPEATED type: TYPE_MESSAGE type_name: ".chunk.Chunk" json_name: "chunks"},
+				{name: "zone" number: 2 label: LABEL_OPTIONAL type: TYPE_STRING json_name: "zone"},
+				{name: "event" number: 3 label: LABEL_OPTIONAL type: TYPE_MESSAGE type_name: ".chunk.Event" json_name: "event"},
+				{name: "events" number: 4 label: LABEL_REPEATED type: TYPE_MESSAGE type_name: ".chunk.Event" json_name: "events"},
+				{name: "body" number: 5 label: LABEL_OPTIONAL type: TYPE_MESSAGE type_name: ".chunk.Text" json_name: "body"}
+			]},
+			{name: "Event" field: [
+				{name: "at" number: 1 label: LABEL_OPTIONAL type: TYPE_MESSAGE type_name: ".google.protobuf.Timestamp" json_name: "at"},
+				{name: "zone" number: 2 label: LABEL_OPTIONAL type: TYPE_STRING json_name: "zone"}
+			]},
+			{name: "Text" field: [
+				{name: "text" number: 1 label: LABEL_OPTIONAL type: TYPE_STRING json_name: "text"},
+				{name: "charset" number: 2 label: LABEL_OPTIONAL type: TYPE_STRING json_name: "charset"}
+			]}
+		]
+	`), fdp); err != nil {
+		panic(err)
+	}
+	raw, err := proto.Marshal(fdp)
+	if err != nil {
+		panic(err)
+	}
+	type x struct{}
+	return protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: raw,
+			NumMessages:   4,
+		},
+		GoTypes:           []interface{}{(*Chunk)(nil), (*Blob)(nil), (*Event)(nil), (*Text)(nil), (*timestamppb.Timestamp)(nil)},
+		DependencyIndexes: []int32{0, 2, 2, 3, 4, 5, 5, 5, 5, 0},
+		MessageInfos:      cpMsgTypes,
+	}.Build().File
+}()
+
+func blobField(name string) protoreflect.FieldDescriptor {
+	return cpFile.Messages().ByName("Blob").Fields().ByName(protoreflect.Name(name))
+}
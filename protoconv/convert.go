@@ -0,0 +1,1003 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package protoconv
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"reflect"
+
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	pref "google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/runtime/protoimpl"
+)
+
+// unwrapper unwraps the value to the underlying value.
+// This is implemented by List and Map.
+type unwrapper interface {
+	protoUnwrap() interface{}
+}
+
+// A Converter coverts to/from Go reflect.Value types and protobuf protoreflect.Value types.
+type Converter interface {
+	// PBValueOf converts a reflect.Value to a protoreflect.Value.
+	PBValueOf(reflect.Value) pref.Value
+
+	// GoValueOf converts a protoreflect.Value to a reflect.Value.
+	GoValueOf(pref.Value) reflect.Value
+
+	// IsValidPB returns whether a protoreflect.Value is compatible with this type.
+	IsValidPB(pref.Value) bool
+
+	// IsValidGo returns whether a reflect.Value is compatible with this type.
+	IsValidGo(reflect.Value) bool
+
+	// New returns a new field value.
+	// For scalars, it returns the default value of the field.
+	// For composite types, it returns a new mutable value.
+	New() pref.Value
+
+	// Zero returns a new field value.
+	// For scalars, it returns the default value of the field.
+	// For composite types, it returns an immutable, empty value.
+	Zero() pref.Value
+
+	// IsZeroValue reports whether v is the zero value of the field.
+	// For scalars, it reports whether v is the default value of the field,
+	// which need not be the zero value of the Go type.
+	// For lists and maps, it reports whether v is empty.
+	// For messages, it reports whether v is an invalid (nil) message.
+	IsZeroValue(v pref.Value) bool
+
+	// Pretty returns a human-readable rendering of v for diagnostics,
+	// such as the name of an enum value or a timestamp in RFC 3339 format.
+	// Long bytes values are elided. The output is not stable and
+	// must not be parsed.
+	Pretty(v pref.Value) string
+
+	// FieldNumber returns the number of the field the Converter was built
+	// for, as needed to encode values on the wire, or 0 if it was built
+	// from a Go type alone.
+	FieldNumber() protowire.Number
+}
+
+// A TryConverter is a Converter whose conversions may fail,
+// such as when parsing a string into a Go value.
+// Its PBValueOf and GoValueOf methods panic with the error
+// reported by the corresponding Try method.
+type TryConverter interface {
+	Converter
+
+	// TryPBValueOf is like PBValueOf, but reports conversion failures.
+	TryPBValueOf(reflect.Value) (pref.Value, error)
+
+	// TryGoValueOf is like GoValueOf, but reports conversion failures.
+	TryGoValueOf(pref.Value) (reflect.Value, error)
+}
+
+// An IntoConverter is a Converter for Go types that must not be copied,
+// such as types holding an atomic value or a strings.Builder.
+// Such Go types are pointers, and GoValueOf returns a newly allocated value.
+type IntoConverter interface {
+	Converter
+
+	// GoValueInto is like GoValueOf, but stores the value in place into dst,
+	// which must be a non-nil pointer of the Go type.
+	GoValueInto(dst reflect.Value, v pref.Value)
+}
+
+// NewConverter matches a Go type with a protobuf field and returns a Converter
+// that converts between the two. Enums must be a named int32 kind that
+// implements protoreflect.Enum, and messages must be pointer to a named
+// struct type that implements protoreflect.ProtoMessage.
+//
+// This matcher deliberately supports a wider range of Go types than what
+// protoc-gen-go historically generated to be able to automatically wrap some
+// v1 messages generated by other forks of protoc-gen-go.
+//
+// A Converter registered for t and fd with RegisterConverter takes precedence.
+// Converters built in advance by WarmConverters are reused.
+func NewConverter(t reflect.Type, fd pref.FieldDescriptor) Converter {
+	if c := cachedConverter(t, fd); c != nil {
+		return c
+	}
+	return ConverterOptions{}.New(t, fd)
+}
+
+// ConverterOptions configures the Converters returned by New.
+// The zero value matches the behavior of NewConverter.
+type ConverterOptions struct {
+	// EmptyStringAbsent specifies that string fields without explicit
+	// presence treat the empty string as not set.
+	// Proto3 semantics treat the empty string as a valid value,
+	// but some APIs use it to mean "unset" when diffing or merging.
+	EmptyStringAbsent bool
+
+	// Lenient accepts Go types that only approximately represent the field.
+	// A float64, or a []float64 for a repeated field, may back a float field,
+	// in which case each value is rounded to the nearest float32.
+	Lenient bool
+
+	// NormalizeString, if not nil, is applied to every Go string converted
+	// to a string field, so that equivalent strings serialize identically.
+	// It is typically a Unicode normalization such as norm.NFC.String
+	// from golang.org/x/text/unicode/norm.
+	NormalizeString func(string) string
+
+	// MaxMapEntries, if positive, limits the number of entries of a Go map
+	// converted to a map field, guarding against pathological inputs.
+	// Larger maps are reported by TryPBValueOf, or, if TruncateMaps is set,
+	// truncated to the entries with the smallest keys.
+	MaxMapEntries int
+	TruncateMaps  bool
+
+	// NilListAbsent specifies that a nil slice backing a repeated field
+	// converts to an absent list, whose IsValid method reports false,
+	// while an empty slice converts to a present but empty list.
+	// Converting back preserves the distinction, as does GoValueOfPresence.
+	NilListAbsent bool
+
+	// InternStrings specifies that a repeated string field backed by
+	// a slice of a Go string type deduplicates its elements when converted
+	// with ConvertSliceFromPB, so that equal elements share their backing
+	// memory. The intern table lives only for the duration of one call.
+	// Other fields, such as a repeated bytes field backed by []string,
+	// are not deduplicated.
+	InternStrings bool
+}
+
+// New is like NewConverter, but uses the provided options.
+func (o ConverterOptions) New(t reflect.Type, fd pref.FieldDescriptor) Converter {
+	if c := registeredConverter(t, fd); c != nil {
+		return c
+	}
+	switch {
+	case fd.IsList():
+		return o.newListConverter(t, fd)
+	case fd.IsMap():
+		return o.newMapConverter(t, fd)
+	default:
+		return o.newSingularConverter(t, fd)
+	}
+}
+
+var (
+	boolType    = reflect.TypeOf(bool(false))
+	int32Type   = reflect.TypeOf(int32(0))
+	int64Type   = reflect.TypeOf(int64(0))
+	uint32Type  = reflect.TypeOf(uint32(0))
+	uint64Type  = reflect.TypeOf(uint64(0))
+	float32Type = reflect.TypeOf(float32(0))
+	float64Type = reflect.TypeOf(float64(0))
+	stringType  = reflect.TypeOf(string(""))
+	bytesType   = reflect.TypeOf([]byte(nil))
+	byteType    = reflect.TypeOf(byte(0))
+)
+
+// cloneValue returns a deep copy of v, which must be a scalar,
+// message, or element of a list or map.
+func cloneValue(v pref.Value) pref.Value {
+	switch x := v.Interface().(type) {
+	case []byte:
+		return pref.ValueOfBytes(append([]byte(nil), x...))
+	case pref.Message:
+		return pref.ValueOfMessage(proto.Clone(x.Interface()).ProtoReflect())
+	default:
+		return v
+	}
+}
+
+// isZeroScalar reports whether the scalar value v is identical to def.
+// Floating-point values are compared by their bits so that a NaN default
+// is recognized, and -0 is distinct from +0.
+func isZeroScalar(v, def pref.Value) bool {
+	switch x := v.Interface().(type) {
+	case []byte:
+		return bytes.Equal(x, def.Bytes())
+	case float32, float64:
+		return math.Float64bits(v.Float()) == math.Float64bits(def.Float())
+	default:
+		return v.Interface() == def.Interface()
+	}
+}
+
+var (
+	boolZero    = pref.ValueOfBool(false)
+	int32Zero   = pref.ValueOfInt32(0)
+	int64Zero   = pref.ValueOfInt64(0)
+	uint32Zero  = pref.ValueOfUint32(0)
+	uint64Zero  = pref.ValueOfUint64(0)
+	float32Zero = pref.ValueOfFloat32(0)
+	float64Zero = pref.ValueOfFloat64(0)
+	stringZero  = pref.ValueOfString("")
+	bytesZero   = pref.ValueOfBytes(nil)
+)
+
+func newSingularConverter(t reflect.Type, fd pref.FieldDescriptor) Converter {
+	return ConverterOptions{}.newSingularConverter(t, fd)
+}
+
+func (o ConverterOptions) newSingularConverter(t reflect.Type, fd pref.FieldDescriptor) Converter {
+	defVal := func(fd pref.FieldDescriptor, zero pref.Value) pref.Value {
+		if fd.Cardinality() == pref.Repeated {
+			// Default isn't defined for repeated fields.
+			return zero
+		}
+		return fd.Default()
+	}
+	if t.Kind() == reflect.Ptr && fd.Message() == nil {
+		// Handle pointers to scalars, such as *int32 for an optional int32,
+		// by wrapping the converter for the element type.
+		return &ptrScalarConverter{t, o.newSingularConverter(t.Elem(), fd)}
+	}
+	switch fd.Kind() {
+	case pref.BoolKind:
+		if t.Kind() == reflect.Bool {
+			return &boolConverter{t, defVal(fd, boolZero), fd.Number()}
+		}
+	case pref.Int32Kind, pref.Sint32Kind, pref.Sfixed32Kind:
+		if t.Kind() == reflect.Int32 {
+			return &int32Converter{t, defVal(fd, int32Zero), fd.Kind(), fd.Number()}
+		}
+	case pref.Int64Kind, pref.Sint64Kind, pref.Sfixed64Kind:
+		if t.Kind() == reflect.Int64 {
+			return &int64Converter{t, defVal(fd, int64Zero), fd.Kind(), fd.Number()}
+		}
+	case pref.Uint32Kind, pref.Fixed32Kind:
+		if t.Kind() == reflect.Uint32 {
+			return &uint32Converter{t, defVal(fd, uint32Zero), fd.Kind(), fd.Number()}
+		}
+	case pref.Uint64Kind, pref.Fixed64Kind:
+		if t.Kind() == reflect.Uint64 {
+			return &uint64Converter{t, defVal(fd, uint64Zero), fd.Kind(), fd.Number()}
+		}
+	case pref.FloatKind:
+		if t.Kind() == reflect.Float32 || (o.Lenient && t.Kind() == reflect.Float64) {
+			return &float32Converter{t, defVal(fd, float32Zero), fd.Number()}
+		}
+	case pref.DoubleKind:
+		if t.Kind() == reflect.Float64 {
+			return &float64Converter{t, defVal(fd, float64Zero), fd.Number()}
+		}
+	case pref.StringKind:
+		if t.Kind() == reflect.String || (t.Kind() == reflect.Slice && t.Elem() == byteType) {
+			return &stringConverter{
+				goType:      t,
+				def:         defVal(fd, stringZero),
+				emptyAbsent: o.EmptyStringAbsent && !fd.HasPresence(),
+				normalize:   o.NormalizeString,
+				num:         fd.Number(),
+			}
+		}
+	case pref.BytesKind:
+		if t.Kind() == reflect.String || (t.Kind() == reflect.Slice && t.Elem() == byteType) {
+			return &bytesConverter{t, defVal(fd, bytesZero), fd.Number()}
+		}
+	case pref.EnumKind:
+		// Handle enums, which must be a named int32 type
+		// or a type that reports its own number. A named int64 type
+		// is also accepted, so long as its values fit in an int32.
+		if t.Kind() == reflect.Int32 || t.Kind() == reflect.Int64 || t.Implements(enumNumberType) {
+			return newEnumConverter(t, fd)
+		}
+	case pref.MessageKind, pref.GroupKind:
+		return newMessageConverter(t, fd)
+	}
+	panic(fmt.Sprintf("invalid Go type %v for field %v", t, fd.FullName()))
+}
+
+type boolConverter struct {
+	goType reflect.Type
+	def    pref.Value
+	num    protowire.Number
+}
+
+func (c *boolConverter) PBValueOf(v reflect.Value) pref.Value {
+	if v.Type() != c.goType {
+		panic(fmt.Sprintf("invalid type: got %v, want %v", v.Type(), c.goType))
+	}
+	return pref.ValueOfBool(v.Bool())
+}
+func (c *boolConverter) GoValueOf(v pref.Value) reflect.Value {
+	return reflect.ValueOf(v.Bool()).Convert(c.goType)
+}
+func (c *boolConverter) IsValidPB(v pref.Value) bool {
+	_, ok := v.Interface().(bool)
+	return ok
+}
+func (c *boolConverter) IsValidGo(v reflect.Value) bool {
+	return v.IsValid() && v.Type() == c.goType
+}
+func (c *boolConverter) New() pref.Value  { return c.def }
+func (c *boolConverter) Zero() pref.Value { return c.def }
+func (c *boolConverter) IsZeroValue(v pref.Value) bool {
+	return isZeroScalar(v, c.def)
+}
+func (c *boolConverter) Pretty(v pref.Value) string {
+	return prettyValue(v)
+}
+func (c *boolConverter) FieldNumber() protowire.Number { return c.num }
+
+type int32Converter struct {
+	goType reflect.Type
+	def    pref.Value
+	kind   pref.Kind
+	num    protowire.Number
+}
+
+func (c *int32Converter) PBValueOf(v reflect.Value) pref.Value {
+	if v.Type() != c.goType {
+		panic(fmt.Sprintf("invalid type: got %v, want %v", v.Type(), c.goType))
+	}
+	return pref.ValueOfInt32(int32(v.Int()))
+}
+func (c *int32Converter) GoValueOf(v pref.Value) reflect.Value {
+	return reflect.ValueOf(int32(v.Int())).Convert(c.goType)
+}
+func (c *int32Converter) IsValidPB(v pref.Value) bool {
+	_, ok := v.Interface().(int32)
+	return ok
+}
+func (c *int32Converter) IsValidGo(v reflect.Value) bool {
+	return v.IsValid() && v.Type() == c.goType
+}
+func (c *int32Converter) New() pref.Value  { return c.def }
+func (c *int32Converter) Zero() pref.Value { return c.def }
+func (c *int32Converter) IsZeroValue(v pref.Value) bool {
+	return isZeroScalar(v, c.def)
+}
+func (c *int32Converter) Pretty(v pref.Value) string {
+	return prettyValue(v)
+}
+func (c *int32Converter) FieldNumber() protowire.Number { return c.num }
+
+type int64Converter struct {
+	goType reflect.Type
+	def    pref.Value
+	kind   pref.Kind
+	num    protowire.Number
+}
+
+func (c *int64Converter) PBValueOf(v reflect.Value) pref.Value {
+	if v.Type() != c.goType {
+		panic(fmt.Sprintf("invalid type: got %v, want %v", v.Type(), c.goType))
+	}
+	return pref.ValueOfInt64(int64(v.Int()))
+}
+func (c *int64Converter) GoValueOf(v pref.Value) reflect.Value {
+	return reflect.ValueOf(int64(v.Int())).Convert(c.goType)
+}
+func (c *int64Converter) IsValidPB(v pref.Value) bool {
+	_, ok := v.Interface().(int64)
+	return ok
+}
+func (c *int64Converter) IsValidGo(v reflect.Value) bool {
+	return v.IsValid() && v.Type() == c.goType
+}
+func (c *int64Converter) New() pref.Value  { return c.def }
+func (c *int64Converter) Zero() pref.Value { return c.def }
+func (c *int64Converter) IsZeroValue(v pref.Value) bool {
+	return isZeroScalar(v, c.def)
+}
+func (c *int64Converter) Pretty(v pref.Value) string {
+	return prettyValue(v)
+}
+func (c *int64Converter) FieldNumber() protowire.Number { return c.num }
+
+type uint32Converter struct {
+	goType reflect.Type
+	def    pref.Value
+	kind   pref.Kind
+	num    protowire.Number
+}
+
+func (c *uint32Converter) PBValueOf(v reflect.Value) pref.Value {
+	if v.Type() != c.goType {
+		panic(fmt.Sprintf("invalid type: got %v, want %v", v.Type(), c.goType))
+	}
+	return pref.ValueOfUint32(uint32(v.Uint()))
+}
+func (c *uint32Converter) GoValueOf(v pref.Value) reflect.Value {
+	return reflect.ValueOf(uint32(v.Uint())).Convert(c.goType)
+}
+func (c *uint32Converter) IsValidPB(v pref.Value) bool {
+	_, ok := v.Interface().(uint32)
+	return ok
+}
+func (c *uint32Converter) IsValidGo(v reflect.Value) bool {
+	return v.IsValid() && v.Type() == c.goType
+}
+func (c *uint32Converter) New() pref.Value  { return c.def }
+func (c *uint32Converter) Zero() pref.Value { return c.def }
+func (c *uint32Converter) IsZeroValue(v pref.Value) bool {
+	return isZeroScalar(v, c.def)
+}
+func (c *uint32Converter) Pretty(v pref.Value) string {
+	return prettyValue(v)
+}
+func (c *uint32Converter) FieldNumber() protowire.Number { return c.num }
+
+type uint64Converter struct {
+	goType reflect.Type
+	def    pref.Value
+	kind   pref.Kind
+	num    protowire.Number
+}
+
+func (c *uint64Converter) PBValueOf(v reflect.Value) pref.Value {
+	if v.Type() != c.goType {
+		panic(fmt.Sprintf("invalid type: got %v, want %v", v.Type(), c.goType))
+	}
+	return pref.ValueOfUint64(uint64(v.Uint()))
+}
+func (c *uint64Converter) GoValueOf(v pref.Value) reflect.Value {
+	return reflect.ValueOf(uint64(v.Uint())).Convert(c.goType)
+}
+func (c *uint64Converter) IsValidPB(v pref.Value) bool {
+	_, ok := v.Interface().(uint64)
+	return ok
+}
+func (c *uint64Converter) IsValidGo(v reflect.Value) bool {
+	return v.IsValid() && v.Type() == c.goType
+}
+func (c *uint64Converter) New() pref.Value  { return c.def }
+func (c *uint64Converter) Zero() pref.Value { return c.def }
+func (c *uint64Converter) IsZeroValue(v pref.Value) bool {
+	return isZeroScalar(v, c.def)
+}
+func (c *uint64Converter) Pretty(v pref.Value) string {
+	return prettyValue(v)
+}
+func (c *uint64Converter) FieldNumber() protowire.Number { return c.num }
+
+// Bytes returns the little-endian encoding of v, which is identical to its
+// fixed64 wire encoding regardless of the byte order of the host.
+// It is intended for hashing and for formats other than protobuf.
+func (c *uint64Converter) Bytes(v pref.Value) []byte {
+	var b [8]byte
+	binary.LittleEndian.PutUint64(b[:], v.Uint())
+	return b[:]
+}
+
+type float32Converter struct {
+	goType reflect.Type
+	def    pref.Value
+	num    protowire.Number
+}
+
+func (c *float32Converter) PBValueOf(v reflect.Value) pref.Value {
+	if v.Type() != c.goType {
+		panic(fmt.Sprintf("invalid type: got %v, want %v", v.Type(), c.goType))
+	}
+	return pref.ValueOfFloat32(float32(v.Float()))
+}
+func (c *float32Converter) GoValueOf(v pref.Value) reflect.Value {
+	return reflect.ValueOf(float32(v.Float())).Convert(c.goType)
+}
+func (c *float32Converter) IsValidPB(v pref.Value) bool {
+	_, ok := v.Interface().(float32)
+	return ok
+}
+func (c *float32Converter) IsValidGo(v reflect.Value) bool {
+	return v.IsValid() && v.Type() == c.goType
+}
+func (c *float32Converter) New() pref.Value  { return c.def }
+func (c *float32Converter) Zero() pref.Value { return c.def }
+func (c *float32Converter) IsZeroValue(v pref.Value) bool {
+	return isZeroScalar(v, c.def)
+}
+func (c *float32Converter) Pretty(v pref.Value) string {
+	return prettyValue(v)
+}
+func (c *float32Converter) FieldNumber() protowire.Number { return c.num }
+
+type float64Converter struct {
+	goType reflect.Type
+	def    pref.Value
+	num    protowire.Number
+}
+
+func (c *float64Converter) PBValueOf(v reflect.Value) pref.Value {
+	if v.Type() != c.goType {
+		panic(fmt.Sprintf("invalid type: got %v, want %v", v.Type(), c.goType))
+	}
+	return pref.ValueOfFloat64(float64(v.Float()))
+}
+func (c *float64Converter) GoValueOf(v pref.Value) reflect.Value {
+	return reflect.ValueOf(float64(v.Float())).Convert(c.goType)
+}
+func (c *float64Converter) IsValidPB(v pref.Value) bool {
+	_, ok := v.Interface().(float64)
+	return ok
+}
+func (c *float64Converter) IsValidGo(v reflect.Value) bool {
+	return v.IsValid() && v.Type() == c.goType
+}
+func (c *float64Converter) New() pref.Value  { return c.def }
+func (c *float64Converter) Zero() pref.Value { return c.def }
+func (c *float64Converter) IsZeroValue(v pref.Value) bool {
+	return isZeroScalar(v, c.def)
+}
+func (c *float64Converter) Pretty(v pref.Value) string {
+	return prettyValue(v)
+}
+func (c *float64Converter) FieldNumber() protowire.Number { return c.num }
+
+type stringConverter struct {
+	goType      reflect.Type
+	def         pref.Value
+	emptyAbsent bool
+	normalize   func(string) string
+	num         protowire.Number
+}
+
+func (c *stringConverter) PBValueOf(v reflect.Value) pref.Value {
+	if v.Type() != c.goType {
+		panic(fmt.Sprintf("invalid type: got %v, want %v", v.Type(), c.goType))
+	}
+	s := v.Convert(stringType).String()
+	if c.normalize != nil {
+		s = c.normalize(s)
+	}
+	return pref.ValueOfString(s)
+}
+func (c *stringConverter) GoValueOf(v pref.Value) reflect.Value {
+	// pref.Value.String never panics, so we go through an interface
+	// conversion here to check the type.
+	s := v.Interface().(string)
+	if c.goType.Kind() == reflect.Slice && s == "" {
+		return reflect.Zero(c.goType) // ensure empty string is []byte(nil)
+	}
+	return reflect.ValueOf(s).Convert(c.goType)
+}
+
+// GoValueOfPresence is like GoValueOf, but also reports whether the value
+// is considered set. The empty string is reported as not set only if
+// ConverterOptions.EmptyStringAbsent was specified.
+func (c *stringConverter) GoValueOfPresence(v pref.Value) (reflect.Value, bool) {
+	rv := c.GoValueOf(v)
+	return rv, !c.emptyAbsent || rv.Len() > 0
+}
+func (c *stringConverter) IsValidPB(v pref.Value) bool {
+	s, ok := v.Interface().(string)
+	return ok && !(c.emptyAbsent && s == "")
+}
+func (c *stringConverter) IsValidGo(v reflect.Value) bool {
+	return v.IsValid() && v.Type() == c.goType
+}
+func (c *stringConverter) New() pref.Value  { return c.def }
+func (c *stringConverter) Zero() pref.Value { return c.def }
+func (c *stringConverter) IsZeroValue(v pref.Value) bool {
+	return isZeroScalar(v, c.def)
+}
+func (c *stringConverter) Pretty(v pref.Value) string {
+	return prettyValue(v)
+}
+func (c *stringConverter) FieldNumber() protowire.Number { return c.num }
+
+type bytesConverter struct {
+	goType reflect.Type
+	def    pref.Value
+	num    protowire.Number
+}
+
+func (c *bytesConverter) PBValueOf(v reflect.Value) pref.Value {
+	if v.Type() != c.goType {
+		panic(fmt.Sprintf("invalid type: got %v, want %v", v.Type(), c.goType))
+	}
+	if c.goType.Kind() == reflect.String && v.Len() == 0 {
+		return pref.ValueOfBytes(nil) // ensure empty string is []byte(nil)
+	}
+	return pref.ValueOfBytes(v.Convert(bytesType).Bytes())
+}
+func (c *bytesConverter) GoValueOf(v pref.Value) reflect.Value {
+	return reflect.ValueOf(v.Bytes()).Convert(c.goType)
+}
+func (c *bytesConverter) IsValidPB(v pref.Value) bool {
+	_, ok := v.Interface().([]byte)
+	return ok
+}
+func (c *bytesConverter) IsValidGo(v reflect.Value) bool {
+	return v.IsValid() && v.Type() == c.goType
+}
+func (c *bytesConverter) New() pref.Value  { return c.def }
+func (c *bytesConverter) Zero() pref.Value { return c.def }
+func (c *bytesConverter) IsZeroValue(v pref.Value) bool {
+	return isZeroScalar(v, c.def)
+}
+func (c *bytesConverter) Pretty(v pref.Value) string {
+	return prettyValue(v)
+}
+func (c *bytesConverter) FieldNumber() protowire.Number { return c.num }
+
+// enumNumber is implemented by Go enum types that report their own number,
+// such as all types that implement protoreflect.Enum.
+type enumNumber interface {
+	Number() pref.EnumNumber
+}
+
+var enumNumberType = reflect.TypeOf((*enumNumber)(nil)).Elem()
+
+// usesEnumNumber reports whether values of the Go enum type t must be
+// converted with their Number method. Types of an integer kind are converted
+// with reflect.Value.Int instead, which avoids an interface conversion and
+// method call per value.
+func usesEnumNumber(t reflect.Type) bool {
+	k := t.Kind()
+	return k != reflect.Int32 && k != reflect.Int64 && t.Implements(enumNumberType)
+}
+
+type enumConverter struct {
+	goType    reflect.Type
+	def       pref.Value
+	useNumber bool                              // goType is converted by its Number method
+	values    map[pref.EnumNumber]reflect.Value // Go values of declared numbers
+	num       protowire.Number
+}
+
+func newEnumConverter(goType reflect.Type, fd pref.FieldDescriptor) Converter {
+	var def pref.Value
+	if fd.Cardinality() == pref.Repeated {
+		def = pref.ValueOfEnum(fd.Enum().Values().Get(0).Number())
+	} else {
+		def = fd.Default()
+	}
+	c := &enumConverter{goType, def, usesEnumNumber(goType), nil, fd.Number()}
+	return c.withValues(fd.Enum())
+}
+
+// NewEnumConverterWithDefault is like NewConverter for the Go enum type t and
+// the singular enum field fd, but uses def as the default value of the field
+// in place of the default declared by fd. It panics if def is not a value
+// declared by the enum.
+func NewEnumConverterWithDefault(t reflect.Type, fd pref.FieldDescriptor, def pref.EnumNumber) Converter {
+	if fd.Kind() != pref.EnumKind || fd.IsList() {
+		panic(fmt.Sprintf("invalid field %v for enum default: want singular enum", fd.FullName()))
+	}
+	if fd.Enum().Values().ByNumber(def) == nil {
+		panic(fmt.Sprintf("invalid default %d for field %v: not a value of enum %v", def, fd.FullName(), fd.Enum().FullName()))
+	}
+	if t.Kind() != reflect.Int32 && t.Kind() != reflect.Int64 && !t.Implements(enumNumberType) {
+		panic(fmt.Sprintf("invalid Go type %v for field %v", t, fd.FullName()))
+	}
+	c := &enumConverter{t, pref.ValueOfEnum(def), usesEnumNumber(t), nil, fd.Number()}
+	return c.withValues(fd.Enum())
+}
+
+// withValues precomputes the Go values of the numbers declared by ed,
+// so that GoValueOf need not convert or construct them on every call.
+// It is a no-op for Go types that cannot construct their own values.
+func (c *enumConverter) withValues(ed pref.EnumDescriptor) *enumConverter {
+	if k := c.goType.Kind(); k != reflect.Int32 && k != reflect.Int64 {
+		if _, ok := reflect.Zero(c.goType).Interface().(pref.Enum); !ok {
+			return c
+		}
+	}
+	vals := ed.Values()
+	c.values = make(map[pref.EnumNumber]reflect.Value, vals.Len())
+	for i := 0; i < vals.Len(); i++ {
+		n := vals.Get(i).Number()
+		c.values[n] = c.newGoValue(n)
+	}
+	return c
+}
+
+// TryPBValueOf is like PBValueOf, but reports an error for a value of
+// an int64 Go type that does not fit in the 32 bits of an enum number.
+func (c *enumConverter) TryPBValueOf(v reflect.Value) (pref.Value, error) {
+	if v.Type() != c.goType {
+		panic(fmt.Sprintf("invalid type: got %v, want %v", v.Type(), c.goType))
+	}
+	if c.useNumber {
+		return pref.ValueOfEnum(v.Interface().(enumNumber).Number()), nil
+	}
+	n := v.Int()
+	if n < math.MinInt32 || n > math.MaxInt32 {
+		return pref.Value{}, fmt.Errorf("enum value %d of type %v overflows int32", n, c.goType)
+	}
+	return pref.ValueOfEnum(pref.EnumNumber(n)), nil
+}
+
+func (c *enumConverter) TryGoValueOf(v pref.Value) (reflect.Value, error) {
+	return c.GoValueOf(v), nil
+}
+
+func (c *enumConverter) PBValueOf(v reflect.Value) pref.Value {
+	pv, err := c.TryPBValueOf(v)
+	if err != nil {
+		panic(err)
+	}
+	return pv
+}
+
+func (c *enumConverter) GoValueOf(v pref.Value) reflect.Value {
+	if rv, ok := c.values[v.Enum()]; ok {
+		return rv
+	}
+	return c.newGoValue(v.Enum())
+}
+
+// newGoValue returns the Go value of the enum number n.
+func (c *enumConverter) newGoValue(n pref.EnumNumber) reflect.Value {
+	if k := c.goType.Kind(); k != reflect.Int32 && k != reflect.Int64 {
+		// The Go type is not directly convertible from a number,
+		// so it must construct its own values.
+		e, ok := reflect.Zero(c.goType).Interface().(pref.Enum)
+		if !ok {
+			panic(fmt.Sprintf("invalid enum type %v: cannot construct from number", c.goType))
+		}
+		return reflect.ValueOf(e.Type().New(n))
+	}
+	return reflect.ValueOf(n).Convert(c.goType)
+}
+
+func (c *enumConverter) IsValidPB(v pref.Value) bool {
+	_, ok := v.Interface().(pref.EnumNumber)
+	return ok
+}
+
+func (c *enumConverter) IsValidGo(v reflect.Value) bool {
+	return v.IsValid() && v.Type() == c.goType
+}
+
+func (c *enumConverter) New() pref.Value {
+	return c.def
+}
+
+func (c *enumConverter) Zero() pref.Value {
+	return c.def
+}
+
+func (c *enumConverter) IsZeroValue(v pref.Value) bool {
+	return v.Enum() == c.def.Enum()
+}
+
+func (c *enumConverter) Pretty(v pref.Value) string {
+	if e, ok := reflect.Zero(c.goType).Interface().(pref.Enum); ok {
+		return prettyEnum(e.Descriptor(), v.Enum())
+	}
+	return prettyValue(v)
+}
+
+func (c *enumConverter) FieldNumber() protowire.Number {
+	return c.num
+}
+
+// ptrScalarConverter converts between a pointer to a scalar, such as *int32,
+// and a scalar field, using c to convert the element. A nil pointer converts
+// to the default value of the field, and converting from the field always
+// allocates a new pointer, since the default cannot be told apart from unset.
+type ptrScalarConverter struct {
+	goType reflect.Type // *T
+	c      Converter    // converts T
+}
+
+func (c *ptrScalarConverter) PBValueOf(v reflect.Value) pref.Value {
+	if v.Type() != c.goType {
+		panic(fmt.Sprintf("invalid type: got %v, want %v", v.Type(), c.goType))
+	}
+	if v.IsNil() {
+		return c.c.Zero()
+	}
+	return c.c.PBValueOf(v.Elem())
+}
+
+func (c *ptrScalarConverter) GoValueOf(v pref.Value) reflect.Value {
+	pv := reflect.New(c.goType.Elem())
+	pv.Elem().Set(c.c.GoValueOf(v))
+	return pv
+}
+
+func (c *ptrScalarConverter) IsValidPB(v pref.Value) bool {
+	return c.c.IsValidPB(v)
+}
+
+func (c *ptrScalarConverter) IsValidGo(v reflect.Value) bool {
+	return v.IsValid() && v.Type() == c.goType
+}
+
+func (c *ptrScalarConverter) New() pref.Value {
+	return c.c.New()
+}
+
+func (c *ptrScalarConverter) Zero() pref.Value {
+	return c.c.Zero()
+}
+
+func (c *ptrScalarConverter) IsZeroValue(v pref.Value) bool {
+	return c.c.IsZeroValue(v)
+}
+
+func (c *ptrScalarConverter) Pretty(v pref.Value) string {
+	return c.c.Pretty(v)
+}
+
+func (c *ptrScalarConverter) FieldNumber() protowire.Number {
+	return c.c.FieldNumber()
+}
+
+type messageConverter struct {
+	goType reflect.Type
+
+	// embed is the index of the embedded message pointer field when goType
+	// is a pointer to a wrapper struct such as:
+	//
+	//	type MyFoo struct {
+	//		*pb.Foo
+	//		extra int
+	//	}
+	//
+	// The wrapper implements the message methods by promotion,
+	// so the messages it produces unwrap to the embedded type.
+	embed []int
+
+	// byValue reports whether goType is a non-pointer type that implements
+	// the message methods with value receivers, in which case values need
+	// not be addressable to be converted.
+	byValue bool
+
+	num protowire.Number
+}
+
+var protoMessageType = reflect.TypeOf((*pref.ProtoMessage)(nil)).Elem()
+
+func newMessageConverter(goType reflect.Type, fd pref.FieldDescriptor) Converter {
+	return &messageConverter{
+		goType:  goType,
+		embed:   embeddedMessageIndex(goType),
+		byValue: goType.Kind() != reflect.Ptr && goType.Implements(protoMessageType),
+		num:     fd.Number(),
+	}
+}
+
+// embeddedMessageIndex returns the index of the embedded message field of t,
+// or nil if t is not a pointer to a struct wrapping a message.
+func embeddedMessageIndex(t reflect.Type) []int {
+	if t.Kind() != reflect.Ptr || t.Elem().Kind() != reflect.Struct {
+		return nil
+	}
+	if f, ok := t.Elem().FieldByName(stateGoName); ok && len(f.Index) == 1 {
+		return nil // t is a generated message
+	}
+	if !t.Implements(protoMessageType) {
+		return nil
+	}
+	for i := 0; i < t.Elem().NumField(); i++ {
+		f := t.Elem().Field(i)
+		if f.Anonymous && f.Type.Kind() == reflect.Ptr && f.Type.Implements(protoMessageType) {
+			return f.Index
+		}
+	}
+	return nil
+}
+
+// wrap returns a new wrapper containing the embedded message rv.
+// Any other fields of the wrapper are left as the zero value.
+func (c *messageConverter) wrap(rv reflect.Value) reflect.Value {
+	w := reflect.New(c.goType.Elem())
+	w.Elem().FieldByIndex(c.embed).Set(rv)
+	return w
+}
+
+// isEmbedded reports whether t is the type of the embedded message.
+func (c *messageConverter) isEmbedded(t reflect.Type) bool {
+	return c.embed != nil && t == c.goType.Elem().FieldByIndex(c.embed).Type
+}
+
+func (c *messageConverter) PBValueOf(v reflect.Value) pref.Value {
+	if v.Type() != c.goType {
+		panic(fmt.Sprintf("invalid type: got %v, want %v", v.Type(), c.goType))
+	}
+	if c.isNonPointer() && !c.byValue {
+		if v.CanAddr() {
+			v = v.Addr() // T => *T
+		} else {
+			v = reflect.Zero(reflect.PtrTo(v.Type()))
+		}
+	}
+	return pref.ValueOfMessage(c.messageOf(v))
+}
+
+// messageOf returns the message for v, which must be a pointer to a message
+// or a message implemented with value receivers.
+func (c *messageConverter) messageOf(v reflect.Value) pref.Message {
+	if m, ok := v.Interface().(pref.ProtoMessage); ok {
+		return m.ProtoReflect()
+	}
+	return protoimpl.X.MessageOf(v.Interface())
+}
+
+func (c *messageConverter) GoValueOf(v pref.Value) reflect.Value {
+	m := v.Message()
+	var rv reflect.Value
+	if u, ok := m.(unwrapper); ok {
+		rv = reflect.ValueOf(u.protoUnwrap())
+	} else {
+		rv = reflect.ValueOf(m.Interface())
+	}
+	if c.byValue && rv.Type() == c.goType {
+		return rv
+	}
+	if c.isNonPointer() {
+		if rv.Type() != reflect.PtrTo(c.goType) {
+			panic(fmt.Sprintf("invalid type: got %v, want %v", rv.Type(), reflect.PtrTo(c.goType)))
+		}
+		if !rv.IsNil() {
+			rv = rv.Elem() // *T => T
+		} else {
+			rv = reflect.Zero(rv.Type().Elem())
+		}
+	}
+	if c.isEmbedded(rv.Type()) {
+		rv = c.wrap(rv)
+	}
+	if rv.Type() != c.goType {
+		panic(fmt.Sprintf("invalid type: got %v, want %v", rv.Type(), c.goType))
+	}
+	return rv
+}
+
+func (c *messageConverter) IsValidPB(v pref.Value) bool {
+	m := v.Message()
+	var rv reflect.Value
+	if u, ok := m.(unwrapper); ok {
+		rv = reflect.ValueOf(u.protoUnwrap())
+	} else {
+		rv = reflect.ValueOf(m.Interface())
+	}
+	if c.byValue && rv.Type() == c.goType {
+		return true
+	}
+	if c.isNonPointer() {
+		return rv.Type() == reflect.PtrTo(c.goType)
+	}
+	return rv.Type() == c.goType || c.isEmbedded(rv.Type())
+}
+
+func (c *messageConverter) IsValidGo(v reflect.Value) bool {
+	return v.IsValid() && v.Type() == c.goType
+}
+
+func (c *messageConverter) New() pref.Value {
+	if c.isNonPointer() {
+		return c.PBValueOf(reflect.New(c.goType).Elem())
+	}
+	if c.embed != nil {
+		return c.PBValueOf(c.wrap(reflect.New(c.goType.Elem().FieldByIndex(c.embed).Type.Elem())))
+	}
+	return c.PBValueOf(reflect.New(c.goType.Elem()))
+}
+
+// NewFromGo returns a new mutable message populated with a deep copy of v.
+func (c *messageConverter) NewFromGo(v reflect.Value) pref.Value {
+	nv := c.New()
+	proto.Merge(nv.Message().Interface(), c.PBValueOf(v).Message().Interface())
+	return nv
+}
+
+func (c *messageConverter) Zero() pref.Value {
+	return c.PBValueOf(reflect.Zero(c.goType))
+}
+
+func (c *messageConverter) IsZeroValue(v pref.Value) bool {
+	return !v.Message().IsValid()
+}
+
+func (c *messageConverter) Pretty(v pref.Value) string {
+	return prettyMessage(v.Message())
+}
+
+func (c *messageConverter) FieldNumber() protowire.Number {
+	return c.num
+}
+
+// isNonPointer reports whether the type is a non-pointer type.
+// This never occurs for generated message types.
+func (c *messageConverter) isNonPointer() bool {
+	return c.goType.Kind() != reflect.Ptr
+}
@@ -0,0 +1,179 @@
+package protoconv
+
+import (
+	"fmt"
+	"reflect"
+
+	pref "google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// BatchConverter is implemented by list Converters that can convert
+// every element of a slice at once.
+type BatchConverter interface {
+	Converter
+
+	// ConvertSliceToPB returns the protobuf value of every element of
+	// the Go slice v.
+	ConvertSliceToPB(v reflect.Value) []pref.Value
+
+	// ConvertSliceFromPB returns a Go slice holding the Go value of
+	// every element of vs.
+	ConvertSliceFromPB(vs []pref.Value) reflect.Value
+}
+
+var (
+	float32SliceType = reflect.TypeOf([]float32(nil))
+	float64SliceType = reflect.TypeOf([]float64(nil))
+	int32SliceType   = reflect.TypeOf([]int32(nil))
+	int64SliceType   = reflect.TypeOf([]int64(nil))
+	uint32SliceType  = reflect.TypeOf([]uint32(nil))
+	uint64SliceType  = reflect.TypeOf([]uint64(nil))
+)
+
+// numericSliceType reports the unnamed numeric slice type that the elements
+// of c may be converted through directly, or nil if c has none.
+// The element type must be the predeclared numeric type itself,
+// since a []T of some named T cannot be converted to a []T of another.
+func (c *listConverter) numericSliceType() reflect.Type {
+	var t reflect.Type
+	switch c.c.(type) {
+	case *float32Converter:
+		t = float32SliceType
+	case *float64Converter:
+		t = float64SliceType
+	case *int32Converter:
+		t = int32SliceType
+	case *int64Converter:
+		t = int64SliceType
+	case *uint32Converter:
+		t = uint32SliceType
+	case *uint64Converter:
+		t = uint64SliceType
+	default:
+		return nil
+	}
+	if c.goType.Elem() != t.Elem() {
+		return nil
+	}
+	return t
+}
+
+// ConvertSliceToPB converts every element of the slice v. Slices of
+// the predeclared numeric types are converted in a loop over the typed slice,
+// rather than through reflection on every element.
+func (c *listConverter) ConvertSliceToPB(v reflect.Value) []pref.Value {
+	if v.Type() != c.goType {
+		panic(fmt.Sprintf("invalid type: got %v, want %v", v.Type(), c.goType))
+	}
+	t := c.numericSliceType()
+	if t == nil {
+		vs := make([]pref.Value, v.Len())
+		for i := range vs {
+			vs[i] = c.c.PBValueOf(v.Index(i))
+		}
+		return vs
+	}
+	var vs []pref.Value
+	switch s := v.Convert(t).Interface().(type) {
+	case []float32:
+		vs = make([]pref.Value, len(s))
+		for i, x := range s {
+			vs[i] = pref.ValueOfFloat32(x)
+		}
+	case []float64:
+		vs = make([]pref.Value, len(s))
+		for i, x := range s {
+			vs[i] = pref.ValueOfFloat64(x)
+		}
+	case []int32:
+		vs = make([]pref.Value, len(s))
+		for i, x := range s {
+			vs[i] = pref.ValueOfInt32(x)
+		}
+	case []int64:
+		vs = make([]pref.Value, len(s))
+		for i, x := range s {
+			vs[i] = pref.ValueOfInt64(x)
+		}
+	case []uint32:
+		vs = make([]pref.Value, len(s))
+		for i, x := range s {
+			vs[i] = pref.ValueOfUint32(x)
+		}
+	case []uint64:
+		vs = make([]pref.Value, len(s))
+		for i, x := range s {
+			vs[i] = pref.ValueOfUint64(x)
+		}
+	}
+	return vs
+}
+
+// ConvertSliceFromPB is the inverse of ConvertSliceToPB.
+// A nil vs results in a nil slice. Equal strings share their backing
+// memory if ConverterOptions.InternStrings was specified.
+func (c *listConverter) ConvertSliceFromPB(vs []pref.Value) reflect.Value {
+	if vs == nil {
+		return reflect.Zero(c.goType)
+	}
+	t := c.numericSliceType()
+	if t == nil {
+		rv := reflect.MakeSlice(c.goType, len(vs), len(vs))
+		var interned map[string]string
+		if c.intern {
+			interned = make(map[string]string)
+		}
+		for i, v := range vs {
+			if interned != nil {
+				s, ok := interned[v.String()]
+				if !ok {
+					s = v.String()
+					interned[s] = s
+				}
+				v = pref.ValueOfString(s)
+			}
+			rv.Index(i).Set(c.c.GoValueOf(v))
+		}
+		return rv
+	}
+	var s interface{}
+	switch t {
+	case float32SliceType:
+		s2 := make([]float32, len(vs))
+		for i, v := range vs {
+			s2[i] = float32(v.Float())
+		}
+		s = s2
+	case float64SliceType:
+		s2 := make([]float64, len(vs))
+		for i, v := range vs {
+			s2[i] = v.Float()
+		}
+		s = s2
+	case int32SliceType:
+		s2 := make([]int32, len(vs))
+		for i, v := range vs {
+			s2[i] = int32(v.Int())
+		}
+		s = s2
+	case int64SliceType:
+		s2 := make([]int64, len(vs))
+		for i, v := range vs {
+			s2[i] = v.Int()
+		}
+		s = s2
+	case uint32SliceType:
+		s2 := make([]uint32, len(vs))
+		for i, v := range vs {
+			s2[i] = uint32(v.Uint())
+		}
+		s = s2
+	default:
+		s2 := make([]uint64, len(vs))
+		for i, v := range vs {
+			s2[i] = v.Uint()
+		}
+		s = s2
+	}
+	return reflect.ValueOf(s).Convert(c.goType)
+}
@@ -0,0 +1,143 @@
+package protoconv_test
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"unsafe"
+
+	"github.com/dnscrypt/dnscrypt-proxy/protoconv"
+	pref "google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// float64s is a named slice of float64, which cannot be converted
+// through []float64 directly.
+type float64s []float64
+
+func TestBatchConverter(t *testing.T) {
+	tests := []struct {
+		name  string
+		field string
+		in    interface{}
+	}{
+		{"float32", "rf", []float32{1, -2.5}},
+		{"float64", "rd", []float64{1, 2.5, -3}},
+		{"named float64", "rd", float64s{1, 2.5, -3}},
+		{"int32", "ri32", []int32{-1 << 31, 0, 1<<31 - 1}},
+		{"uint32", "ru32", []uint32{0, 1<<32 - 1}},
+		{"uint64", "rf64", []uint64{0, 1 << 63}},
+		{"string", "rs", []string{"a", "", "b"}},
+		{"empty", "rd", []float64{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			in := reflect.ValueOf(tt.in)
+			c := protoconv.NewConverter(in.Type(), field(tt.field)).(protoconv.BatchConverter)
+			vs := c.ConvertSliceToPB(in)
+			list := c.PBValueOf(in).List()
+			if len(vs) != list.Len() {
+				t.Fatalf("ConvertSliceToPB returned %d values, want %d", len(vs), list.Len())
+			}
+			for i, v := range vs {
+				if got, want := v.Interface(), list.Get(i).Interface(); got != want {
+					t.Errorf("element %d = %v, want %v", i, got, want)
+				}
+			}
+			out := c.ConvertSliceFromPB(vs)
+			if out.Type() != in.Type() || !reflect.DeepEqual(out.Interface(), tt.in) {
+				t.Errorf("ConvertSliceFromPB = %#v, want %#v", out.Interface(), tt.in)
+			}
+		})
+	}
+}
+
+func TestBatchConverterNil(t *testing.T) {
+	c := protoconv.NewConverter(reflect.TypeOf([]float64(nil)), field("rd")).(protoconv.BatchConverter)
+	if got := c.ConvertSliceFromPB(nil).Interface().([]float64); got != nil {
+		t.Errorf("ConvertSliceFromPB(nil) = %#v, want nil", got)
+	}
+}
+
+func BenchmarkBatchConverter(b *testing.B) {
+	c := protoconv.NewConverter(reflect.TypeOf([]float64(nil)), field("rd")).(protoconv.BatchConverter)
+	in := reflect.ValueOf(make([]float64, 1000000))
+	b.Run("Batch", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			c.ConvertSliceFromPB(c.ConvertSliceToPB(in))
+		}
+	})
+	b.Run("Reflect", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			vs := make([]pref.Value, in.Len())
+			for j := range vs {
+				vs[j] = pref.ValueOfFloat64(in.Index(j).Float())
+			}
+			rv := reflect.MakeSlice(in.Type(), len(vs), len(vs))
+			for j, v := range vs {
+				rv.Index(j).Set(reflect.ValueOf(v.Float()))
+			}
+		}
+	})
+}
+
+// stringData returns the address of the bytes of s.
+func stringData(s string) uintptr {
+	return (*reflect.StringHeader)(unsafe.Pointer(&s)).Data
+}
+
+// duplicateValues returns n string values cycling through 10 distinct tags.
+func duplicateValues(n int, bytes bool) []pref.Value {
+	vs := make([]pref.Value, n)
+	for i := range vs {
+		s := fmt.Sprint("tag-", i%10)
+		if bytes {
+			vs[i] = pref.ValueOfBytes([]byte(s))
+		} else {
+			vs[i] = pref.ValueOfString(s)
+		}
+	}
+	return vs
+}
+
+func TestInternStrings(t *testing.T) {
+	typ := reflect.TypeOf([]string(nil))
+	tests := []struct {
+		name       string
+		opts       protoconv.ConverterOptions
+		field      string
+		wantShared bool
+	}{
+		{"interned", protoconv.ConverterOptions{InternStrings: true}, "rs", true},
+		{"not interned", protoconv.ConverterOptions{}, "rs", false},
+		{"bytes field", protoconv.ConverterOptions{InternStrings: true}, "rb", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := tt.opts.New(typ, field(tt.field)).(protoconv.BatchConverter)
+			got := c.ConvertSliceFromPB(duplicateValues(20, tt.field == "rb")).Interface().([]string)
+			if got[0] != "tag-0" || got[10] != "tag-0" || got[1] != "tag-1" {
+				t.Fatalf("ConvertSliceFromPB = %q", got)
+			}
+			if shared := stringData(got[0]) == stringData(got[10]); shared != tt.wantShared {
+				t.Errorf("equal elements share memory = %v, want %v", shared, tt.wantShared)
+			}
+			if stringData(got[0]) == stringData(got[1]) {
+				t.Error("distinct elements share memory")
+			}
+		})
+	}
+}
+
+func BenchmarkInternStrings(b *testing.B) {
+	typ := reflect.TypeOf([]string(nil))
+	vs := duplicateValues(100000, false)
+	for _, intern := range []bool{false, true} {
+		c := protoconv.ConverterOptions{InternStrings: intern}.New(typ, field("rs")).(protoconv.BatchConverter)
+		b.Run(fmt.Sprint("intern=", intern), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				c.ConvertSliceFromPB(vs)
+			}
+		})
+	}
+}
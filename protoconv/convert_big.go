@@ -0,0 +1,136 @@
+package protoconv
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"reflect"
+
+	pref "google.golang.org/protobuf/reflect/protoreflect"
+)
+
+var bigIntType = reflect.TypeOf((*big.Int)(nil))
+
+// NewBitsetConverter returns a TryConverter between a *big.Int used as
+// a bitset and a repeated bool field, where element i reports whether
+// bit i is set.
+//
+// If n is positive, the list always has n elements and setting any bit
+// at or above n is an error. Otherwise, the list has BitLen elements,
+// so that trailing false elements are trimmed and a nil or zero bitset
+// produces an empty list. Negative integers are not valid bitsets.
+func NewBitsetConverter(n int, fd pref.FieldDescriptor) TryConverter {
+	if !fd.IsList() || fd.Kind() != pref.BoolKind {
+		panic(fmt.Sprintf("invalid field %v for bitset: want repeated bool", fd.FullName()))
+	}
+	pb := ConverterOptions{}.newListConverter(reflect.TypeOf([]bool(nil)), fd)
+	return &funcConverter{
+		goType: bigIntType,
+		pb:     pb,
+		toPB: func(v reflect.Value) (pref.Value, error) {
+			x := v.Interface().(*big.Int)
+			if x == nil {
+				x = new(big.Int)
+			}
+			if x.Sign() < 0 {
+				return pref.Value{}, fmt.Errorf("invalid negative bitset for field %v", fd.FullName())
+			}
+			bits := make([]bool, x.BitLen())
+			if n > 0 {
+				if len(bits) > n {
+					return pref.Value{}, fmt.Errorf("bitset for field %v exceeds %d bits", fd.FullName(), n)
+				}
+				bits = make([]bool, n)
+			}
+			for i := range bits {
+				bits[i] = x.Bit(i) == 1
+			}
+			return pb.PBValueOf(reflect.ValueOf(bits)), nil
+		},
+		toGo: func(v pref.Value) (reflect.Value, error) {
+			list := v.List()
+			if n > 0 && list.Len() > n {
+				return reflect.Value{}, fmt.Errorf("bitset for field %v exceeds %d bits", fd.FullName(), n)
+			}
+			x := new(big.Int)
+			for i := 0; i < list.Len(); i++ {
+				if list.Get(i).Bool() {
+					x.SetBit(x, i, 1)
+				}
+			}
+			return reflect.ValueOf(x), nil
+		},
+	}
+}
+
+var bigRatType = reflect.TypeOf((*big.Rat)(nil))
+
+// NewRatConverter returns a TryConverter between a *big.Rat and a string
+// field holding the fraction formatted by big.Rat.String, such as "1/3".
+// Integers are formatted with a denominator of 1, such as "2/1",
+// but any string accepted by big.Rat.SetString may be converted back,
+// including "2" and "0.5". A nil *big.Rat converts to the empty string,
+// and the empty string converts to a nil *big.Rat.
+func NewRatConverter(fd pref.FieldDescriptor) TryConverter {
+	if fd.Kind() != pref.StringKind {
+		panic(fmt.Sprintf("invalid field %v for big.Rat: got %v, want string", fd.FullName(), fd.Kind()))
+	}
+	return &funcConverter{
+		goType: bigRatType,
+		pb:     newSingularConverter(stringType, fd),
+		toPB: func(v reflect.Value) (pref.Value, error) {
+			x := v.Interface().(*big.Rat)
+			if x == nil {
+				return stringZero, nil
+			}
+			return pref.ValueOfString(x.String()), nil
+		},
+		toGo: func(v pref.Value) (reflect.Value, error) {
+			if v.String() == "" {
+				return reflect.Zero(bigRatType), nil
+			}
+			x, ok := new(big.Rat).SetString(v.String())
+			if !ok {
+				return reflect.Value{}, fmt.Errorf("invalid fraction %q for field %v", v.String(), fd.FullName())
+			}
+			return reflect.ValueOf(x), nil
+		},
+	}
+}
+
+var bigFloatType = reflect.TypeOf((*big.Float)(nil))
+
+// NewBigFloatConverter returns a TryConverter between a *big.Float and
+// a double field holding the value rounded by big.Float.Float64.
+// Precision beyond that of a float64 is lost, as is the accuracy of the
+// rounding, unless exact is set, in which case values that do not convert
+// exactly are reported by TryPBValueOf. A nil *big.Float converts to zero.
+// A double converts to a *big.Float with the 53 bits of precision of
+// big.Float.SetFloat64, and NaN, which a big.Float cannot hold,
+// is reported by TryGoValueOf.
+func NewBigFloatConverter(exact bool, fd pref.FieldDescriptor) TryConverter {
+	if fd.Kind() != pref.DoubleKind {
+		panic(fmt.Sprintf("invalid field %v for big.Float: got %v, want double", fd.FullName(), fd.Kind()))
+	}
+	return &funcConverter{
+		goType: bigFloatType,
+		pb:     newSingularConverter(float64Type, fd),
+		toPB: func(v reflect.Value) (pref.Value, error) {
+			x := v.Interface().(*big.Float)
+			if x == nil {
+				return float64Zero, nil
+			}
+			f, acc := x.Float64()
+			if exact && acc != big.Exact {
+				return pref.Value{}, fmt.Errorf("value %v for field %v is not exactly representable as a double", x, fd.FullName())
+			}
+			return pref.ValueOfFloat64(f), nil
+		},
+		toGo: func(v pref.Value) (reflect.Value, error) {
+			if math.IsNaN(v.Float()) {
+				return reflect.Value{}, fmt.Errorf("invalid NaN for field %v", fd.FullName())
+			}
+			return reflect.ValueOf(new(big.Float).SetFloat64(v.Float())), nil
+		},
+	}
+}
@@ -0,0 +1,146 @@
+package protoconv_test
+
+import (
+	"math"
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/dnscrypt/dnscrypt-proxy/protoconv"
+	pref "google.golang.org/protobuf/reflect/protoreflect"
+)
+
+func TestBitsetConverter(t *testing.T) {
+	tests := []struct {
+		name string
+		n    int
+		in   *big.Int
+		want []bool
+	}{
+		{"nil", 0, nil, []bool{}},
+		{"zero", 0, big.NewInt(0), []bool{}},
+		{"trimmed", 0, big.NewInt(0b1010), []bool{false, true, false, true}},
+		{"fixed length", 6, big.NewInt(0b101), []bool{true, false, true, false, false, false}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := protoconv.NewBitsetConverter(tt.n, field("rbool"))
+			v, err := c.TryPBValueOf(reflect.ValueOf(tt.in))
+			if err != nil {
+				t.Fatalf("TryPBValueOf(%v) error: %v", tt.in, err)
+			}
+			list := v.List()
+			got := make([]bool, list.Len())
+			for i := range got {
+				got[i] = list.Get(i).Bool()
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("TryPBValueOf(%v) = %v, want %v", tt.in, got, tt.want)
+			}
+			back, err := c.TryGoValueOf(v)
+			if err != nil {
+				t.Fatalf("TryGoValueOf error: %v", err)
+			}
+			want := tt.in
+			if want == nil {
+				want = new(big.Int)
+			}
+			if back.Interface().(*big.Int).Cmp(want) != 0 {
+				t.Errorf("TryGoValueOf = %v, want %v", back, want)
+			}
+		})
+	}
+}
+
+func TestBitsetConverterInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		n    int
+		in   *big.Int
+	}{
+		{"negative", 0, big.NewInt(-1)},
+		{"too long", 2, big.NewInt(0b100)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := protoconv.NewBitsetConverter(tt.n, field("rbool"))
+			if _, err := c.TryPBValueOf(reflect.ValueOf(tt.in)); err == nil {
+				t.Errorf("TryPBValueOf(%v) succeeded, want error", tt.in)
+			}
+		})
+	}
+}
+
+func TestRatConverter(t *testing.T) {
+	c := protoconv.NewRatConverter(field("s"))
+	tests := []struct {
+		in      string
+		want    *big.Rat
+		wantStr string // as formatted back
+	}{
+		{"1/3", big.NewRat(1, 3), "1/3"},
+		{"2/4", big.NewRat(1, 2), "1/2"},
+		{"2/1", big.NewRat(2, 1), "2/1"},
+		{"2", big.NewRat(2, 1), "2/1"},
+		{"0.5", big.NewRat(1, 2), "1/2"},
+		{"", nil, ""},
+	}
+	for _, tt := range tests {
+		rv, err := c.TryGoValueOf(pref.ValueOfString(tt.in))
+		if err != nil {
+			t.Errorf("TryGoValueOf(%q) error: %v", tt.in, err)
+			continue
+		}
+		got := rv.Interface().(*big.Rat)
+		if (got == nil) != (tt.want == nil) || (got != nil && got.Cmp(tt.want) != 0) {
+			t.Errorf("TryGoValueOf(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+		if s := c.PBValueOf(rv).String(); s != tt.wantStr {
+			t.Errorf("PBValueOf(%v) = %q, want %q", got, s, tt.wantStr)
+		}
+	}
+	if _, err := c.TryGoValueOf(pref.ValueOfString("1/x")); err == nil {
+		t.Error(`TryGoValueOf("1/x") succeeded, want error`)
+	}
+}
+
+func TestBigFloatConverter(t *testing.T) {
+	tenth, _ := new(big.Float).SetPrec(200).SetString("0.1")
+	tests := []struct {
+		name    string
+		in      *big.Float
+		exact   bool
+		want    float64
+		wantErr bool
+	}{
+		{"exact", big.NewFloat(0.5), false, 0.5, false},
+		{"exact under exact policy", big.NewFloat(0.5), true, 0.5, false},
+		{"inexact", tenth, false, 0.1, false},
+		{"inexact under exact policy", tenth, true, 0, true},
+		{"nil", nil, true, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := protoconv.NewBigFloatConverter(tt.exact, field("d"))
+			v, err := c.TryPBValueOf(reflect.ValueOf(tt.in))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("TryPBValueOf(%v) error = %v, want error %v", tt.in, err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if v.Float() != tt.want {
+				t.Errorf("TryPBValueOf(%v) = %v, want %v", tt.in, v.Float(), tt.want)
+			}
+			back := c.GoValueOf(v).Interface().(*big.Float)
+			if f, _ := back.Float64(); f != tt.want {
+				t.Errorf("GoValueOf(%v) = %v, want %v", v.Float(), back, tt.want)
+			}
+		})
+	}
+
+	c := protoconv.NewBigFloatConverter(false, field("d"))
+	if _, err := c.TryGoValueOf(pref.ValueOfFloat64(math.NaN())); err == nil {
+		t.Error("TryGoValueOf(NaN) succeeded, want error")
+	}
+}
@@ -0,0 +1,372 @@
+package protoconv
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/png"
+	"io"
+	"io/ioutil"
+	"reflect"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"google.golang.org/protobuf/proto"
+	pref "google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// NewByteListConverter returns a TryConverter between a []byte and
+// a repeated uint32 field holding one element per byte.
+// Elements greater than 255 are reported by TryGoValueOf.
+func NewByteListConverter(fd pref.FieldDescriptor) TryConverter {
+	if !fd.IsList() || (fd.Kind() != pref.Uint32Kind && fd.Kind() != pref.Fixed32Kind) {
+		panic(fmt.Sprintf("invalid field %v for byte list: want repeated uint32", fd.FullName()))
+	}
+	pb := ConverterOptions{}.newListConverter(reflect.TypeOf([]uint32(nil)), fd)
+	return &funcConverter{
+		goType: bytesType,
+		pb:     pb,
+		toPB: func(v reflect.Value) (pref.Value, error) {
+			b := v.Bytes()
+			s := make([]uint32, len(b))
+			for i, c := range b {
+				s[i] = uint32(c)
+			}
+			return pb.PBValueOf(reflect.ValueOf(s)), nil
+		},
+		toGo: func(v pref.Value) (reflect.Value, error) {
+			list := v.List()
+			if list.Len() == 0 {
+				return reflect.Zero(bytesType), nil
+			}
+			b := make([]byte, list.Len())
+			for i := range b {
+				n := list.Get(i).Uint()
+				if n > 255 {
+					return reflect.Value{}, fmt.Errorf("invalid byte %d at index %d of field %v", n, i, fd.FullName())
+				}
+				b[i] = byte(n)
+			}
+			return reflect.ValueOf(b), nil
+		},
+	}
+}
+
+// NewByteArrayConverter returns a TryConverter between a Go byte array type,
+// such as [4]byte for an IPv4 address or [16]byte for an IPv6 address,
+// and a bytes field. Values whose length differs from the length of the
+// array are reported by TryGoValueOf rather than truncated or padded.
+func NewByteArrayConverter(t reflect.Type, fd pref.FieldDescriptor) TryConverter {
+	if t.Kind() != reflect.Array || t.Elem() != byteType || fd.Kind() != pref.BytesKind {
+		panic(fmt.Sprintf("invalid Go type %v for field %v", t, fd.FullName()))
+	}
+	return &funcConverter{
+		goType: t,
+		pb:     newSingularConverter(bytesType, fd),
+		toPB: func(v reflect.Value) (pref.Value, error) {
+			b := make([]byte, t.Len())
+			reflect.Copy(reflect.ValueOf(b), v)
+			return pref.ValueOfBytes(b), nil
+		},
+		toGo: func(v pref.Value) (reflect.Value, error) {
+			b := v.Bytes()
+			if len(b) != t.Len() {
+				return reflect.Value{}, fmt.Errorf("invalid length %d for field %v: want %d bytes", len(b), fd.FullName(), t.Len())
+			}
+			rv := reflect.New(t).Elem()
+			reflect.Copy(rv, reflect.ValueOf(b))
+			return rv, nil
+		},
+	}
+}
+
+// NewGzipConverter returns a TryConverter between an uncompressed []byte
+// and a bytes field holding it compressed with gzip.
+// Empty values are stored as empty bytes rather than as a gzip stream
+// of no data, and empty bytes convert to a nil []byte.
+// Data that is not valid gzip, or that decompresses to more than maxSize
+// bytes, is reported by TryGoValueOf; decompression stops at the limit
+// rather than expanding a compression bomb without bound.
+func NewGzipConverter(maxSize int64, fd pref.FieldDescriptor) TryConverter {
+	if fd.Kind() != pref.BytesKind {
+		panic(fmt.Sprintf("invalid field %v for gzip: got %v, want bytes", fd.FullName(), fd.Kind()))
+	}
+	if maxSize < 0 {
+		panic(fmt.Sprintf("invalid size limit %d for field %v", maxSize, fd.FullName()))
+	}
+	return &funcConverter{
+		goType: bytesType,
+		pb:     newSingularConverter(bytesType, fd),
+		toPB: func(v reflect.Value) (pref.Value, error) {
+			if v.Len() == 0 {
+				return bytesZero, nil
+			}
+			var buf bytes.Buffer
+			zw := gzip.NewWriter(&buf)
+			if _, err := zw.Write(v.Bytes()); err != nil {
+				return pref.Value{}, err
+			}
+			if err := zw.Close(); err != nil {
+				return pref.Value{}, err
+			}
+			return pref.ValueOfBytes(buf.Bytes()), nil
+		},
+		toGo: func(v pref.Value) (reflect.Value, error) {
+			if len(v.Bytes()) == 0 {
+				return reflect.Zero(bytesType), nil
+			}
+			zr, err := gzip.NewReader(bytes.NewReader(v.Bytes()))
+			if err != nil {
+				return reflect.Value{}, fmt.Errorf("invalid gzip data for field %v: %w", fd.FullName(), err)
+			}
+			b, err := ioutil.ReadAll(io.LimitReader(zr, maxSize+1))
+			if err != nil {
+				return reflect.Value{}, fmt.Errorf("invalid gzip data for field %v: %w", fd.FullName(), err)
+			}
+			if int64(len(b)) > maxSize {
+				return reflect.Value{}, fmt.Errorf("decompressed contents of field %v exceed %d bytes", fd.FullName(), maxSize)
+			}
+			return reflect.ValueOf(b), nil
+		},
+	}
+}
+
+// NewUTF16Converter returns a TryConverter between a []byte holding
+// UTF-16LE text and a string or bytes field holding the same text as UTF-8.
+// Text with an odd number of bytes or an unpaired surrogate is reported by
+// TryPBValueOf, and invalid UTF-8 is reported by TryGoValueOf.
+func NewUTF16Converter(fd pref.FieldDescriptor) TryConverter {
+	if fd.Kind() != pref.StringKind && fd.Kind() != pref.BytesKind {
+		panic(fmt.Sprintf("invalid field %v for UTF-16: got %v, want string or bytes", fd.FullName(), fd.Kind()))
+	}
+	isBytes := fd.Kind() == pref.BytesKind
+	return &funcConverter{
+		goType: bytesType,
+		pb:     newSingularConverter(scalarGoType(fd.Kind()), fd),
+		toPB: func(v reflect.Value) (pref.Value, error) {
+			b := v.Bytes()
+			if len(b)%2 != 0 {
+				return pref.Value{}, fmt.Errorf("invalid UTF-16 for field %v: odd length %d", fd.FullName(), len(b))
+			}
+			var s []byte
+			for i := 0; i < len(b); i += 2 {
+				r := rune(binary.LittleEndian.Uint16(b[i:]))
+				if utf16.IsSurrogate(r) {
+					if i+4 > len(b) {
+						return pref.Value{}, fmt.Errorf("invalid UTF-16 for field %v: unpaired surrogate at offset %d", fd.FullName(), i)
+					}
+					r = utf16.DecodeRune(r, rune(binary.LittleEndian.Uint16(b[i+2:])))
+					if r == utf8.RuneError {
+						return pref.Value{}, fmt.Errorf("invalid UTF-16 for field %v: unpaired surrogate at offset %d", fd.FullName(), i)
+					}
+					i += 2
+				}
+				s = append(s, string(r)...)
+			}
+			if isBytes {
+				return pref.ValueOfBytes(s), nil
+			}
+			return pref.ValueOfString(string(s)), nil
+		},
+		toGo: func(v pref.Value) (reflect.Value, error) {
+			var s string
+			if isBytes {
+				s = string(v.Bytes())
+			} else {
+				s = v.String()
+			}
+			if !utf8.ValidString(s) {
+				return reflect.Value{}, fmt.Errorf("invalid UTF-8 in field %v", fd.FullName())
+			}
+			if s == "" {
+				return reflect.Zero(bytesType), nil
+			}
+			u := utf16.Encode([]rune(s))
+			b := make([]byte, 2*len(u))
+			for i, c := range u {
+				binary.LittleEndian.PutUint16(b[2*i:], c)
+			}
+			return reflect.ValueOf(b), nil
+		},
+	}
+}
+
+var (
+	binaryMarshalerType   = reflect.TypeOf((*encoding.BinaryMarshaler)(nil)).Elem()
+	binaryUnmarshalerType = reflect.TypeOf((*encoding.BinaryUnmarshaler)(nil)).Elem()
+)
+
+// NewBinaryMessageConverter returns a TryConverter between a Go type t that
+// implements encoding.BinaryMarshaler, and whose pointer or t itself implements
+// encoding.BinaryUnmarshaler, and a message field, where the binary form of t
+// is the wire encoding of the message. Values are marshaled and then
+// unmarshaled as the message, and the reverse.
+// Binary forms that are not a valid message are reported by TryPBValueOf,
+// and errors from UnmarshalBinary by TryGoValueOf.
+func NewBinaryMessageConverter(t reflect.Type, fd pref.FieldDescriptor) TryConverter {
+	newValue := unmarshalTarget(t, binaryUnmarshalerType)
+	if !t.Implements(binaryMarshalerType) || newValue == nil {
+		panic(fmt.Sprintf("invalid Go type %v for field %v: want encoding.BinaryMarshaler and encoding.BinaryUnmarshaler", t, fd.FullName()))
+	}
+	if fd.IsList() || fd.IsMap() {
+		panic(fmt.Sprintf("invalid field %v for binary message: want singular message", fd.FullName()))
+	}
+	mt := findMessageType(fd)
+	return &funcConverter{
+		goType: t,
+		pb:     newMessageConverter(reflect.TypeOf(mt.Zero().Interface()), fd),
+		toPB: func(v reflect.Value) (pref.Value, error) {
+			b, err := v.Interface().(encoding.BinaryMarshaler).MarshalBinary()
+			if err != nil {
+				return pref.Value{}, fmt.Errorf("cannot marshal field %v: %w", fd.FullName(), err)
+			}
+			m := mt.New()
+			if err := proto.Unmarshal(b, m.Interface()); err != nil {
+				return pref.Value{}, fmt.Errorf("invalid binary %v for field %v: %w", mt.Descriptor().FullName(), fd.FullName(), err)
+			}
+			return pref.ValueOfMessage(m), nil
+		},
+		toGo: func(v pref.Value) (reflect.Value, error) {
+			b, err := proto.Marshal(v.Message().Interface())
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			p, rv := newValue()
+			if err := p.Interface().(encoding.BinaryUnmarshaler).UnmarshalBinary(b); err != nil {
+				return reflect.Value{}, fmt.Errorf("cannot unmarshal field %v: %w", fd.FullName(), err)
+			}
+			return rv, nil
+		},
+	}
+}
+
+var imageType = reflect.TypeOf((*image.Image)(nil)).Elem()
+
+// NewPNGConverter returns a TryConverter between an image.Image and
+// a bytes field holding the image encoded as PNG. A nil image converts to
+// empty bytes, and empty bytes convert to a nil image.
+// Encoding errors are reported by TryPBValueOf, and decoding errors
+// by TryGoValueOf.
+func NewPNGConverter(fd pref.FieldDescriptor) TryConverter {
+	if fd.Kind() != pref.BytesKind || fd.IsList() {
+		panic(fmt.Sprintf("invalid field %v for PNG: got %v, want bytes", fd.FullName(), fd.Kind()))
+	}
+	return &funcConverter{
+		goType: imageType,
+		pb:     newSingularConverter(bytesType, fd),
+		toPB: func(v reflect.Value) (pref.Value, error) {
+			img, _ := v.Interface().(image.Image)
+			if img == nil {
+				return bytesZero, nil
+			}
+			var buf bytes.Buffer
+			if err := png.Encode(&buf, img); err != nil {
+				return pref.Value{}, fmt.Errorf("cannot encode field %v: %w", fd.FullName(), err)
+			}
+			return pref.ValueOfBytes(buf.Bytes()), nil
+		},
+		toGo: func(v pref.Value) (reflect.Value, error) {
+			if len(v.Bytes()) == 0 {
+				return reflect.Zero(imageType), nil
+			}
+			img, err := png.Decode(bytes.NewReader(v.Bytes()))
+			if err != nil {
+				return reflect.Value{}, fmt.Errorf("invalid PNG for field %v: %w", fd.FullName(), err)
+			}
+			rv := reflect.New(imageType).Elem()
+			rv.Set(reflect.ValueOf(img))
+			return rv, nil
+		},
+	}
+}
+
+var readerType = reflect.TypeOf((*io.Reader)(nil)).Elem()
+
+// NewReaderConverter returns a TryConverter between an io.Reader and
+// a bytes field holding its contents. PBValueOf reads the reader to EOF,
+// which consumes it, and reports contents longer than maxSize bytes
+// rather than reading without bound. GoValueOf returns a *bytes.Reader
+// over the field. A nil reader converts to empty bytes.
+func NewReaderConverter(maxSize int64, fd pref.FieldDescriptor) TryConverter {
+	if fd.Kind() != pref.BytesKind || fd.IsList() {
+		panic(fmt.Sprintf("invalid field %v for io.Reader: got %v, want bytes", fd.FullName(), fd.Kind()))
+	}
+	if maxSize < 0 {
+		panic(fmt.Sprintf("invalid size limit %d for field %v", maxSize, fd.FullName()))
+	}
+	return &funcConverter{
+		goType: readerType,
+		pb:     newSingularConverter(bytesType, fd),
+		toPB: func(v reflect.Value) (pref.Value, error) {
+			r, _ := v.Interface().(io.Reader)
+			if r == nil {
+				return bytesZero, nil
+			}
+			b, err := ioutil.ReadAll(io.LimitReader(r, maxSize+1))
+			if err != nil {
+				return pref.Value{}, fmt.Errorf("cannot read field %v: %w", fd.FullName(), err)
+			}
+			if int64(len(b)) > maxSize {
+				return pref.Value{}, fmt.Errorf("contents of field %v exceed %d bytes", fd.FullName(), maxSize)
+			}
+			return pref.ValueOfBytes(b), nil
+		},
+		toGo: func(v pref.Value) (reflect.Value, error) {
+			rv := reflect.New(readerType).Elem()
+			rv.Set(reflect.ValueOf(bytes.NewReader(v.Bytes())))
+			return rv, nil
+		},
+	}
+}
+
+// NewChunkConverter returns a Converter between a []byte and a repeated
+// message field whose messages, such as Chunk { bytes data = 1; }, hold
+// a single bytes field. The bytes are split into chunks of at most size
+// bytes, one per message, and the chunks are concatenated in order when
+// converting from the field. Empty bytes convert to an empty list.
+func NewChunkConverter(size int, fd pref.FieldDescriptor) Converter {
+	if size <= 0 {
+		panic(fmt.Sprintf("invalid chunk size %d for field %v", size, fd.FullName()))
+	}
+	if !fd.IsList() || fd.Message() == nil {
+		panic(fmt.Sprintf("invalid field %v for chunks: want repeated message", fd.FullName()))
+	}
+	mt := findMessageType(fd)
+	fds := mt.Descriptor().Fields()
+	if fds.Len() != 1 || fds.Get(0).Kind() != pref.BytesKind || fds.Get(0).IsList() {
+		panic(fmt.Sprintf("invalid message %v for field %v: want a single bytes field", mt.Descriptor().FullName(), fd.FullName()))
+	}
+	dataFd := fds.Get(0)
+	listType := reflect.SliceOf(reflect.TypeOf(mt.Zero().Interface()))
+	pb := ConverterOptions{}.newListConverter(listType, fd)
+	return &funcConverter{
+		goType: bytesType,
+		pb:     pb,
+		toPB: func(v reflect.Value) (pref.Value, error) {
+			b := v.Bytes()
+			chunks := reflect.MakeSlice(listType, 0, (len(b)+size-1)/size)
+			for len(b) > 0 {
+				n := size
+				if n > len(b) {
+					n = len(b)
+				}
+				m := mt.New()
+				m.Set(dataFd, pref.ValueOfBytes(b[:n:n]))
+				chunks = reflect.Append(chunks, reflect.ValueOf(m.Interface()))
+				b = b[n:]
+			}
+			return pb.PBValueOf(chunks), nil
+		},
+		toGo: func(v pref.Value) (reflect.Value, error) {
+			list := v.List()
+			var b []byte
+			for i := 0; i < list.Len(); i++ {
+				b = append(b, list.Get(i).Message().Get(dataFd).Bytes()...)
+			}
+			return reflect.ValueOf(b), nil
+		},
+	}
+}
@@ -0,0 +1,375 @@
+package protoconv_test
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"io"
+	"io/ioutil"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/dnscrypt/dnscrypt-proxy/protoconv"
+	"google.golang.org/protobuf/proto"
+	pref "google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+func TestByteListConverter(t *testing.T) {
+	c := protoconv.NewByteListConverter(field("ru32"))
+	for _, in := range [][]byte{nil, {0}, {1, 2, 255}} {
+		v := c.PBValueOf(reflect.ValueOf(in))
+		if got := v.List().Len(); got != len(in) {
+			t.Errorf("PBValueOf(%v) has %d elements, want %d", in, got, len(in))
+		}
+		got, err := c.TryGoValueOf(v)
+		if err != nil {
+			t.Fatalf("TryGoValueOf error: %v", err)
+		}
+		if !bytes.Equal(got.Bytes(), in) {
+			t.Errorf("round trip of %v = %v", in, got.Bytes())
+		}
+	}
+
+	v := c.New()
+	v.List().Append(pref.ValueOfUint32(1))
+	v.List().Append(pref.ValueOfUint32(256))
+	if _, err := c.TryGoValueOf(v); err == nil {
+		t.Error("TryGoValueOf with element 256 succeeded, want error")
+	}
+}
+
+func TestByteArrayConverter(t *testing.T) {
+	tests := []struct {
+		name string
+		in   interface{}
+		want []byte
+	}{
+		{"IPv4", [4]byte{192, 0, 2, 1}, []byte{192, 0, 2, 1}},
+		{"IPv6", [16]byte{0x20, 0x01, 0x0d, 0xb8, 15: 1}, []byte{0x20, 0x01, 0x0d, 0xb8, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := protoconv.NewByteArrayConverter(reflect.TypeOf(tt.in), field("b"))
+			v := c.PBValueOf(reflect.ValueOf(tt.in))
+			if !bytes.Equal(v.Bytes(), tt.want) {
+				t.Errorf("PBValueOf(%v) = %v, want %v", tt.in, v.Bytes(), tt.want)
+			}
+			got, err := c.TryGoValueOf(v)
+			if err != nil || got.Interface() != tt.in {
+				t.Errorf("TryGoValueOf(%v) = %v, %v, want %v", v.Bytes(), got, err, tt.in)
+			}
+		})
+	}
+
+	c := protoconv.NewByteArrayConverter(reflect.TypeOf([4]byte{}), field("b"))
+	for _, b := range [][]byte{{1, 2, 3}, {1, 2, 3, 4, 5}, make([]byte, 16)} {
+		if _, err := c.TryGoValueOf(pref.ValueOfBytes(b)); err == nil {
+			t.Errorf("TryGoValueOf(%v) for [4]byte succeeded, want length error", b)
+		}
+	}
+}
+
+func TestGzipConverter(t *testing.T) {
+	c := protoconv.NewGzipConverter(1<<20, field("b"))
+	tests := []struct {
+		name string
+		in   []byte
+	}{
+		{"empty", nil},
+		{"short", []byte("x")},
+		{"compressible", bytes.Repeat([]byte("dnscrypt "), 10000)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := c.PBValueOf(reflect.ValueOf(tt.in))
+			if len(tt.in) == 0 && len(v.Bytes()) != 0 {
+				t.Errorf("PBValueOf(empty) = %x, want empty bytes", v.Bytes())
+			}
+			if len(tt.in) > 1000 && len(v.Bytes()) >= len(tt.in)/10 {
+				t.Errorf("PBValueOf compressed %d bytes to %d", len(tt.in), len(v.Bytes()))
+			}
+			got, err := c.TryGoValueOf(v)
+			if err != nil || !bytes.Equal(got.Bytes(), tt.in) {
+				t.Errorf("TryGoValueOf = %d bytes, %v, want %d bytes", got.Len(), err, len(tt.in))
+			}
+		})
+	}
+
+	if _, err := c.TryGoValueOf(pref.ValueOfBytes([]byte("not gzip"))); err == nil {
+		t.Error("TryGoValueOf(corrupt data) succeeded, want error")
+	}
+}
+
+func TestGzipConverterLimit(t *testing.T) {
+	bomb := protoconv.NewGzipConverter(1<<30, field("b")).PBValueOf(reflect.ValueOf(make([]byte, 1<<20)))
+	tests := []struct {
+		maxSize int64
+		wantErr bool
+	}{
+		{1 << 20, false},
+		{1<<20 - 1, true},
+		{0, true},
+	}
+	for _, tt := range tests {
+		c := protoconv.NewGzipConverter(tt.maxSize, field("b"))
+		if _, err := c.TryGoValueOf(bomb); (err != nil) != tt.wantErr {
+			t.Errorf("maxSize %d: TryGoValueOf error = %v, want error %v", tt.maxSize, err, tt.wantErr)
+		}
+	}
+}
+
+func TestUTF16Converter(t *testing.T) {
+	tests := []struct {
+		name string
+		in   []byte
+		want string
+	}{
+		{"empty", nil, ""},
+		{"ascii", []byte{'h', 0, 'i', 0}, "hi"},
+		{"BMP", []byte{0xac, 0x20}, "€"},
+		{"surrogate pair", []byte{0x3d, 0xd8, 0x00, 0xde}, "😀"},
+	}
+	for _, fieldName := range []string{"s", "b"} {
+		c := protoconv.NewUTF16Converter(field(fieldName))
+		for _, tt := range tests {
+			t.Run(fieldName+"/"+tt.name, func(t *testing.T) {
+				v, err := c.TryPBValueOf(reflect.ValueOf(tt.in))
+				if err != nil {
+					t.Fatalf("TryPBValueOf(%x) error: %v", tt.in, err)
+				}
+				got := v.String()
+				if fieldName == "b" {
+					got = string(v.Bytes())
+				}
+				if got != tt.want {
+					t.Errorf("TryPBValueOf(%x) = %q, want %q", tt.in, got, tt.want)
+				}
+				if back, err := c.TryGoValueOf(v); err != nil || !bytes.Equal(back.Bytes(), tt.in) {
+					t.Errorf("TryGoValueOf(%q) = %x, %v, want %x", tt.want, back.Bytes(), err, tt.in)
+				}
+			})
+		}
+	}
+}
+
+func TestUTF16ConverterInvalid(t *testing.T) {
+	c := protoconv.NewUTF16Converter(field("s"))
+	tests := []struct {
+		name string
+		in   []byte
+	}{
+		{"odd length", []byte{'h', 0, 'i'}},
+		{"lone high surrogate", []byte{0x3d, 0xd8}},
+		{"high surrogate then letter", []byte{0x3d, 0xd8, 'a', 0}},
+		{"lone low surrogate", []byte{0x00, 0xde}},
+	}
+	for _, tt := range tests {
+		if _, err := c.TryPBValueOf(reflect.ValueOf(tt.in)); err == nil {
+			t.Errorf("%s: TryPBValueOf(%x) succeeded, want error", tt.name, tt.in)
+		}
+	}
+	if _, err := protoconv.NewUTF16Converter(field("b")).TryGoValueOf(pref.ValueOfBytes([]byte{0xff})); err == nil {
+		t.Error("TryGoValueOf(invalid UTF-8) succeeded, want error")
+	}
+}
+
+func TestLatin1Converter(t *testing.T) {
+	c := protoconv.NewLatin1Converter(field("s"))
+	all := make([]byte, 256)
+	for i := range all {
+		all[i] = byte(i)
+	}
+	tests := []struct {
+		name string
+		in   []byte
+		want string
+	}{
+		{"empty", nil, ""},
+		{"ascii", []byte("hi"), "hi"},
+		{"high bytes", []byte{0xe9, 0xff}, "éÿ"},
+		{"all bytes", all, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v, err := c.TryPBValueOf(reflect.ValueOf(tt.in))
+			if err != nil {
+				t.Fatalf("TryPBValueOf(%x) error: %v", tt.in, err)
+			}
+			if tt.want != "" && v.String() != tt.want {
+				t.Errorf("TryPBValueOf(%x) = %q, want %q", tt.in, v.String(), tt.want)
+			}
+			back, err := c.TryGoValueOf(v)
+			if err != nil || !bytes.Equal(back.Bytes(), tt.in) {
+				t.Errorf("TryGoValueOf(%q) = %x, %v, want %x", v.String(), back.Bytes(), err, tt.in)
+			}
+		})
+	}
+}
+
+func TestLatin1ConverterInvalid(t *testing.T) {
+	c := protoconv.NewLatin1Converter(field("s"))
+	for _, s := range []string{"a€", "\u0100", "\xff"} {
+		if _, err := c.TryGoValueOf(pref.ValueOfString(s)); err == nil {
+			t.Errorf("TryGoValueOf(%q) succeeded, want error", s)
+		}
+	}
+}
+
+// rawDuration is the wire encoding of a Duration.
+type rawDuration []byte
+
+func (b rawDuration) MarshalBinary() ([]byte, error) { return b, nil }
+
+func (b *rawDuration) UnmarshalBinary(data []byte) error {
+	*b = append(rawDuration(nil), data...)
+	return nil
+}
+
+func TestBinaryMessageConverter(t *testing.T) {
+	c := protoconv.NewBinaryMessageConverter(reflect.TypeOf(rawDuration(nil)), field("dur"))
+	raw, err := proto.Marshal(&durationpb.Duration{Seconds: 5, Nanos: 6})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name    string
+		in      rawDuration
+		wantErr bool
+	}{
+		{"valid", raw, false},
+		{"empty", rawDuration{}, false},
+		{"corrupt", rawDuration{0xff, 0xff}, true},
+		{"truncated", raw[:len(raw)-1], true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v, err := c.TryPBValueOf(reflect.ValueOf(tt.in))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("TryPBValueOf(%x) error = %v, want error %v", tt.in, err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			back, err := c.TryGoValueOf(v)
+			if err != nil || !bytes.Equal(back.Interface().(rawDuration), tt.in) {
+				t.Errorf("TryGoValueOf = %x, %v, want %x", back.Interface(), err, tt.in)
+			}
+		})
+	}
+}
+
+// imageValue returns img as a reflect.Value of type image.Image.
+func imageValue(img image.Image) reflect.Value {
+	rv := reflect.New(reflect.TypeOf((*image.Image)(nil)).Elem()).Elem()
+	if img != nil {
+		rv.Set(reflect.ValueOf(img))
+	}
+	return rv
+}
+
+func TestPNGConverter(t *testing.T) {
+	c := protoconv.NewPNGConverter(field("b"))
+	img := image.NewNRGBA(image.Rect(0, 0, 3, 2))
+	img.Set(1, 1, color.NRGBA{R: 255, A: 255})
+	img.Set(2, 0, color.NRGBA{G: 128, B: 64, A: 255})
+
+	v, err := c.TryPBValueOf(imageValue(img))
+	if err != nil {
+		t.Fatalf("TryPBValueOf error: %v", err)
+	}
+	rv, err := c.TryGoValueOf(v)
+	if err != nil {
+		t.Fatalf("TryGoValueOf error: %v", err)
+	}
+	got := rv.Interface().(image.Image)
+	if got.Bounds() != img.Bounds() {
+		t.Fatalf("bounds = %v, want %v", got.Bounds(), img.Bounds())
+	}
+	for y := 0; y < 2; y++ {
+		for x := 0; x < 3; x++ {
+			if g, w := color.NRGBAModel.Convert(got.At(x, y)), img.At(x, y); g != w {
+				t.Errorf("pixel (%d, %d) = %v, want %v", x, y, g, w)
+			}
+		}
+	}
+
+	nv := c.PBValueOf(imageValue(nil))
+	if len(nv.Bytes()) != 0 {
+		t.Errorf("PBValueOf(nil) = %d bytes, want none", len(nv.Bytes()))
+	}
+	if got := c.GoValueOf(nv); !got.IsNil() {
+		t.Errorf("GoValueOf(empty) = %v, want nil image", got)
+	}
+	if _, err := c.TryGoValueOf(pref.ValueOfBytes([]byte("not a png"))); err == nil {
+		t.Error("TryGoValueOf(corrupt data) succeeded, want error")
+	}
+}
+
+// readerValue returns r as a reflect.Value of type io.Reader.
+func readerValue(r io.Reader) reflect.Value {
+	return reflect.ValueOf(&r).Elem()
+}
+
+func TestReaderConverter(t *testing.T) {
+	c := protoconv.NewReaderConverter(5, field("b"))
+	tests := []struct {
+		name    string
+		in      io.Reader
+		want    string
+		wantErr bool
+	}{
+		{"nil", nil, "", false},
+		{"small", strings.NewReader("hi"), "hi", false},
+		{"at limit", strings.NewReader("hello"), "hello", false},
+		{"over limit", strings.NewReader("hello!"), "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v, err := c.TryPBValueOf(readerValue(tt.in))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("TryPBValueOf error = %v, want error %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if got := string(v.Bytes()); got != tt.want {
+				t.Errorf("TryPBValueOf = %q, want %q", got, tt.want)
+			}
+			b, err := ioutil.ReadAll(c.GoValueOf(v).Interface().(io.Reader))
+			if err != nil || string(b) != tt.want {
+				t.Errorf("GoValueOf reads %q, %v, want %q", b, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestChunkConverter(t *testing.T) {
+	c := protoconv.NewChunkConverter(4, blobField("chunks"))
+	tests := []struct {
+		name       string
+		in         []byte
+		wantChunks []string
+	}{
+		{"empty", nil, nil},
+		{"smaller than a chunk", []byte("ab"), []string{"ab"}},
+		{"one full chunk", []byte("abcd"), []string{"abcd"}},
+		{"several chunks", []byte("abcdefghi"), []string{"abcd", "efgh", "i"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			list := c.PBValueOf(reflect.ValueOf(tt.in)).List()
+			var got []string
+			for i := 0; i < list.Len(); i++ {
+				got = append(got, string(list.Get(i).Message().Interface().(*Chunk).Data))
+			}
+			if !reflect.DeepEqual(got, tt.wantChunks) {
+				t.Errorf("PBValueOf(%q) chunks = %q, want %q", tt.in, got, tt.wantChunks)
+			}
+			if back := c.GoValueOf(pref.ValueOfList(list)).Bytes(); !bytes.Equal(back, tt.in) {
+				t.Errorf("GoValueOf = %q, want %q", back, tt.in)
+			}
+		})
+	}
+}
@@ -0,0 +1,194 @@
+package protoconv
+
+import (
+	"fmt"
+	"reflect"
+	"unicode/utf8"
+
+	pref "google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// Charset transcodes between text in some character encoding and UTF-8.
+// Encodings other than those provided here, such as Shift-JIS, may be
+// adapted from the decoders and encoders of golang.org/x/text/encoding.
+type Charset struct {
+	// Name identifies the charset, such as "ISO-8859-1".
+	// It is recorded with text converted by NewCharsetConverter.
+	Name string
+
+	// Decode converts text in the charset to UTF-8.
+	Decode func([]byte) (string, error)
+
+	// Encode converts UTF-8 text to the charset.
+	Encode func(string) ([]byte, error)
+}
+
+// UTF8Charset is the UTF-8 encoding, which only validates the text.
+var UTF8Charset = &Charset{
+	Name: "UTF-8",
+	Decode: func(b []byte) (string, error) {
+		if !utf8.Valid(b) {
+			return "", fmt.Errorf("invalid UTF-8")
+		}
+		return string(b), nil
+	},
+	Encode: func(s string) ([]byte, error) {
+		return []byte(s), nil
+	},
+}
+
+// Latin1Charset is the ISO-8859-1 encoding, in which every byte is the
+// code point of a character. Every byte sequence decodes losslessly,
+// but characters above U+00FF cannot be encoded.
+var Latin1Charset = &Charset{
+	Name: "ISO-8859-1",
+	Decode: func(b []byte) (string, error) {
+		rs := make([]rune, len(b))
+		for i, c := range b {
+			rs[i] = rune(c)
+		}
+		return string(rs), nil
+	},
+	Encode: func(s string) ([]byte, error) {
+		b := make([]byte, 0, len(s))
+		for i, r := range s {
+			if r > 0xff {
+				return nil, fmt.Errorf("character %U at offset %d is not in Latin-1", r, i)
+			}
+			b = append(b, byte(r))
+		}
+		return b, nil
+	},
+}
+
+// NewCharsetConverter returns a TryConverter between a []byte holding text
+// of some charset and the message field fd, whose string fields named text
+// and charset hold the text as UTF-8 and the name of the charset it was
+// decoded from. The charset of every value is determined by detect, or is
+// fallback if detect is nil or returns nil. Text converted from the message
+// is encoded in the charset it names, which is looked up by name among
+// charsets, fallback, UTF8Charset and Latin1Charset; an empty name stands
+// for fallback. Text that cannot be transcoded is reported by TryPBValueOf
+// and TryGoValueOf, as are names of unknown charsets by TryGoValueOf.
+//
+// The field is a message rather than a string because the charset is
+// detected per value: a string field cannot record which charset the text
+// was decoded from, so the original bytes could not be restored. For text
+// of a single known charset, use NewStringCharsetConverter instead.
+//
+// No Shift-JIS Charset is provided, since golang.org/x/text/encoding/japanese
+// is not a dependency of this module; callers that need it should pass
+// a Charset wrapping its decoder and encoder in charsets.
+func NewCharsetConverter(detect func([]byte) *Charset, fallback *Charset, fd pref.FieldDescriptor, text, charset pref.Name, charsets ...*Charset) TryConverter {
+	if fallback == nil {
+		panic(fmt.Sprintf("invalid fallback charset for field %v: must not be nil", fd.FullName()))
+	}
+	if fd.IsList() || fd.IsMap() || fd.Message() == nil {
+		panic(fmt.Sprintf("invalid field %v for charset text: want singular message", fd.FullName()))
+	}
+	mt := findMessageType(fd)
+	textFd := mt.Descriptor().Fields().ByName(text)
+	charsetFd := mt.Descriptor().Fields().ByName(charset)
+	for _, f := range []pref.FieldDescriptor{textFd, charsetFd} {
+		if f == nil || f.Kind() != pref.StringKind || f.IsList() {
+			panic(fmt.Sprintf("invalid message %v for field %v: want string fields %v and %v", mt.Descriptor().FullName(), fd.FullName(), text, charset))
+		}
+	}
+	byName := make(map[string]*Charset)
+	for _, cs := range append([]*Charset{UTF8Charset, Latin1Charset, fallback}, charsets...) {
+		if cs == nil {
+			panic(fmt.Sprintf("invalid nil charset for field %v", fd.FullName()))
+		}
+		byName[cs.Name] = cs
+	}
+	return &funcConverter{
+		goType: bytesType,
+		pb:     newMessageConverter(reflect.TypeOf(mt.Zero().Interface()), fd),
+		toPB: func(v reflect.Value) (pref.Value, error) {
+			cs := fallback
+			if detect != nil {
+				if d := detect(v.Bytes()); d != nil {
+					cs = d
+				}
+			}
+			s, err := cs.Decode(v.Bytes())
+			if err != nil {
+				return pref.Value{}, fmt.Errorf("invalid %v text for field %v: %w", cs.Name, fd.FullName(), err)
+			}
+			m := mt.New()
+			m.Set(textFd, pref.ValueOfString(s))
+			m.Set(charsetFd, pref.ValueOfString(cs.Name))
+			return pref.ValueOfMessage(m), nil
+		},
+		toGo: func(v pref.Value) (reflect.Value, error) {
+			m := v.Message()
+			s := m.Get(textFd).String()
+			if !utf8.ValidString(s) {
+				return reflect.Value{}, fmt.Errorf("invalid UTF-8 in field %v", fd.FullName())
+			}
+			if s == "" {
+				return reflect.Zero(bytesType), nil
+			}
+			cs := fallback
+			if name := m.Get(charsetFd).String(); name != "" {
+				if cs = byName[name]; cs == nil {
+					return reflect.Value{}, fmt.Errorf("unknown charset %q in field %v", name, fd.FullName())
+				}
+			}
+			b, err := cs.Encode(s)
+			if err != nil {
+				return reflect.Value{}, fmt.Errorf("cannot encode field %v as %v: %w", fd.FullName(), cs.Name, err)
+			}
+			return reflect.ValueOf(b), nil
+		},
+	}
+}
+
+// NewStringCharsetConverter returns a TryConverter between a []byte holding
+// text in the charset cs and a string field holding the text as UTF-8.
+// Text that cs cannot decode is reported by TryPBValueOf, and strings that
+// are not valid UTF-8 or that cs cannot encode are reported by TryGoValueOf.
+// The empty string converts to a nil []byte.
+func NewStringCharsetConverter(cs *Charset, fd pref.FieldDescriptor) TryConverter {
+	if cs == nil {
+		panic(fmt.Sprintf("invalid nil charset for field %v", fd.FullName()))
+	}
+	if fd.Kind() != pref.StringKind || fd.IsList() {
+		panic(fmt.Sprintf("invalid field %v for %v text: got %v, want string", fd.FullName(), cs.Name, fd.Kind()))
+	}
+	return &funcConverter{
+		goType: bytesType,
+		pb:     newSingularConverter(stringType, fd),
+		toPB: func(v reflect.Value) (pref.Value, error) {
+			s, err := cs.Decode(v.Bytes())
+			if err != nil {
+				return pref.Value{}, fmt.Errorf("invalid %v text for field %v: %w", cs.Name, fd.FullName(), err)
+			}
+			return pref.ValueOfString(s), nil
+		},
+		toGo: func(v pref.Value) (reflect.Value, error) {
+			s := v.String()
+			if !utf8.ValidString(s) {
+				return reflect.Value{}, fmt.Errorf("invalid UTF-8 in field %v", fd.FullName())
+			}
+			if s == "" {
+				return reflect.Zero(bytesType), nil
+			}
+			b, err := cs.Encode(s)
+			if err != nil {
+				return reflect.Value{}, fmt.Errorf("cannot encode field %v as %v: %w", fd.FullName(), cs.Name, err)
+			}
+			return reflect.ValueOf(b), nil
+		},
+	}
+}
+
+// NewLatin1Converter returns a TryConverter between a []byte and a string
+// field holding the same bytes as ISO-8859-1 (Latin-1) text, in which every
+// byte is the code point of a character. Unlike a plain conversion to
+// a string, every byte round-trips, since bytes of 0x80 and above become
+// valid UTF-8. Strings with characters above U+00FF, or with invalid UTF-8,
+// are reported by TryGoValueOf.
+func NewLatin1Converter(fd pref.FieldDescriptor) TryConverter {
+	return NewStringCharsetConverter(Latin1Charset, fd)
+}
@@ -0,0 +1,157 @@
+package protoconv_test
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/dnscrypt/dnscrypt-proxy/protoconv"
+	pref "google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// shiftJIS transcodes only the characters of "日本", enough to exercise
+// detection and re-encoding.
+var shiftJIS = &protoconv.Charset{
+	Name: "Shift_JIS",
+	Decode: func(b []byte) (string, error) {
+		return strings.NewReplacer("\x93\xfa", "日", "\x96\x7b", "本").Replace(string(b)), nil
+	},
+	Encode: func(s string) ([]byte, error) {
+		return []byte(strings.NewReplacer("日", "\x93\xfa", "本", "\x96\x7b").Replace(s)), nil
+	},
+}
+
+func detectCharset(b []byte) *protoconv.Charset {
+	switch {
+	case bytes.HasPrefix(b, []byte{0x93, 0xfa}):
+		return shiftJIS
+	case utf8.Valid(b):
+		return protoconv.UTF8Charset
+	}
+	return nil
+}
+
+func TestCharsetConverter(t *testing.T) {
+	c := protoconv.NewCharsetConverter(detectCharset, protoconv.Latin1Charset, blobField("body"), "text", "charset", shiftJIS)
+	tests := []struct {
+		name        string
+		in          string
+		wantText    string
+		wantCharset string
+	}{
+		{"UTF-8", "héllo", "héllo", "UTF-8"},
+		{"Latin-1 fallback", "caf\xe9", "café", "ISO-8859-1"},
+		{"Shift-JIS", "\x93\xfa\x96\x7b", "日本", "Shift_JIS"},
+		{"empty", "", "", "UTF-8"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v, err := c.TryPBValueOf(reflect.ValueOf([]byte(tt.in)))
+			if err != nil {
+				t.Fatalf("TryPBValueOf(%q) error: %v", tt.in, err)
+			}
+			text := v.Message().Interface().(*Text)
+			if text.Text != tt.wantText || text.Charset != tt.wantCharset {
+				t.Errorf("TryPBValueOf(%q) = %q in %v, want %q in %v", tt.in, text.Text, text.Charset, tt.wantText, tt.wantCharset)
+			}
+			back, err := c.TryGoValueOf(v)
+			if err != nil || string(back.Bytes()) != tt.in {
+				t.Errorf("TryGoValueOf = %q, %v, want %q", back.Bytes(), err, tt.in)
+			}
+		})
+	}
+}
+
+func TestCharsetConverterInbound(t *testing.T) {
+	c := protoconv.NewCharsetConverter(detectCharset, protoconv.Latin1Charset, blobField("body"), "text", "charset", shiftJIS)
+	tests := []struct {
+		name    string
+		in      *Text
+		want    string
+		wantErr bool
+	}{
+		{"stored charset", &Text{Text: "日本", Charset: "Shift_JIS"}, "\x93\xfa\x96\x7b", false},
+		{"no charset uses fallback", &Text{Text: "café"}, "caf\xe9", false},
+		{"absent", &Text{}, "", false},
+		{"unknown charset", &Text{Text: "x", Charset: "EBCDIC"}, "", true},
+		{"not encodable", &Text{Text: "日本", Charset: "ISO-8859-1"}, "", true},
+		{"invalid UTF-8", &Text{Text: "\xff"}, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := c.TryGoValueOf(pref.ValueOfMessage(tt.in.ProtoReflect()))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("TryGoValueOf(%v) error = %v, want error %v", tt.in, err, tt.wantErr)
+			}
+			if err == nil && string(got.Bytes()) != tt.want {
+				t.Errorf("TryGoValueOf(%v) = %q, want %q", tt.in, got.Bytes(), tt.want)
+			}
+		})
+	}
+}
+
+func TestCharsetConverterInvalid(t *testing.T) {
+	tests := []struct {
+		name     string
+		fallback *protoconv.Charset
+		fd       pref.FieldDescriptor
+		charsets []*protoconv.Charset
+	}{
+		{"nil fallback", nil, blobField("body"), nil},
+		{"nil charset", protoconv.UTF8Charset, blobField("body"), []*protoconv.Charset{nil}},
+		{"string field", protoconv.UTF8Charset, field("s"), nil},
+		{"wrong message", protoconv.UTF8Charset, blobField("event"), nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				if r := recover(); r == nil {
+					t.Error("NewCharsetConverter did not panic")
+				}
+			}()
+			protoconv.NewCharsetConverter(nil, tt.fallback, tt.fd, "text", "charset", tt.charsets...)
+		})
+	}
+}
+
+func TestStringCharsetConverter(t *testing.T) {
+	tests := []struct {
+		name    string
+		cs      *protoconv.Charset
+		in      []byte
+		want    string
+		wantErr bool
+	}{
+		{"Shift_JIS", shiftJIS, []byte("\x93\xfa\x96\x7b"), "日本", false},
+		{"UTF-8", protoconv.UTF8Charset, []byte("日本"), "日本", false},
+		{"Latin-1", protoconv.Latin1Charset, []byte{0xe9}, "é", false},
+		{"empty", protoconv.UTF8Charset, nil, "", false},
+		{"invalid UTF-8", protoconv.UTF8Charset, []byte{0xff}, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := protoconv.NewStringCharsetConverter(tt.cs, field("s"))
+			v, err := c.TryPBValueOf(reflect.ValueOf(tt.in))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("TryPBValueOf(%x) error = %v, want error %v", tt.in, err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if v.String() != tt.want {
+				t.Errorf("TryPBValueOf(%x) = %q, want %q", tt.in, v.String(), tt.want)
+			}
+			back, err := c.TryGoValueOf(v)
+			if err != nil || !bytes.Equal(back.Bytes(), tt.in) {
+				t.Errorf("TryGoValueOf(%q) = %x, %v, want %x", v.String(), back.Bytes(), err, tt.in)
+			}
+		})
+	}
+
+	c := protoconv.NewStringCharsetConverter(protoconv.Latin1Charset, field("s"))
+	if _, err := c.TryGoValueOf(pref.ValueOfString("日本")); err == nil {
+		t.Error("TryGoValueOf of text outside Latin-1 succeeded, want error")
+	}
+}
@@ -0,0 +1,38 @@
+package protoconv
+
+import (
+	"fmt"
+	"reflect"
+
+	pref "google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// NewComplexListConverter returns a TryConverter between a complex type,
+// such as complex128, and a repeated double field holding exactly two
+// elements: the real and then the imaginary part. Lists of any other length
+// are reported by TryGoValueOf.
+func NewComplexListConverter(t reflect.Type, fd pref.FieldDescriptor) TryConverter {
+	if t.Kind() != reflect.Complex64 && t.Kind() != reflect.Complex128 {
+		panic(fmt.Sprintf("invalid Go type %v for field %v: want complex", t, fd.FullName()))
+	}
+	if !fd.IsList() || fd.Kind() != pref.DoubleKind {
+		panic(fmt.Sprintf("invalid field %v for %v: want repeated double", fd.FullName(), t))
+	}
+	pb := ConverterOptions{}.newListConverter(reflect.TypeOf([]float64(nil)), fd)
+	return &funcConverter{
+		goType: t,
+		pb:     pb,
+		toPB: func(v reflect.Value) (pref.Value, error) {
+			c := v.Complex()
+			return pb.PBValueOf(reflect.ValueOf([]float64{real(c), imag(c)})), nil
+		},
+		toGo: func(v pref.Value) (reflect.Value, error) {
+			list := v.List()
+			if list.Len() != 2 {
+				return reflect.Value{}, fmt.Errorf("invalid length %d for field %v: want [real, imag]", list.Len(), fd.FullName())
+			}
+			c := complex(list.Get(0).Float(), list.Get(1).Float())
+			return reflect.ValueOf(c).Convert(t), nil
+		},
+	}
+}
@@ -0,0 +1,60 @@
+package protoconv_test
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/dnscrypt/dnscrypt-proxy/protoconv"
+	pref "google.golang.org/protobuf/reflect/protoreflect"
+)
+
+func TestComplexListConverter(t *testing.T) {
+	tests := []struct {
+		name string
+		in   interface{}
+		want []float64
+	}{
+		{"complex128", complex(1.5, -2), []float64{1.5, -2}},
+		{"complex64", complex64(complex(0.25, 4)), []float64{0.25, 4}},
+		{"zero", complex128(0), []float64{0, 0}},
+		{"infinite", complex(math.Inf(1), math.Inf(-1)), []float64{math.Inf(1), math.Inf(-1)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			in := reflect.ValueOf(tt.in)
+			c := protoconv.NewComplexListConverter(in.Type(), field("rd"))
+			v, err := c.TryPBValueOf(in)
+			if err != nil {
+				t.Fatalf("TryPBValueOf(%v) error: %v", tt.in, err)
+			}
+			var got []float64
+			for i := 0; i < v.List().Len(); i++ {
+				got = append(got, v.List().Get(i).Float())
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("TryPBValueOf(%v) = %v, want %v", tt.in, got, tt.want)
+			}
+			back, err := c.TryGoValueOf(v)
+			if err != nil {
+				t.Fatalf("TryGoValueOf error: %v", err)
+			}
+			if back.Interface() != tt.in {
+				t.Errorf("round trip of %v = %v", tt.in, back)
+			}
+		})
+	}
+}
+
+func TestComplexListConverterLength(t *testing.T) {
+	c := protoconv.NewComplexListConverter(reflect.TypeOf(complex128(0)), field("rd"))
+	for _, n := range []int{0, 1, 3} {
+		list := c.New().List()
+		for i := 0; i < n; i++ {
+			list.Append(pref.ValueOfFloat64(float64(i)))
+		}
+		if _, err := c.TryGoValueOf(pref.ValueOfList(list)); err == nil {
+			t.Errorf("TryGoValueOf of %d elements succeeded, want error", n)
+		}
+	}
+}
@@ -0,0 +1,228 @@
+package protoconv
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"google.golang.org/protobuf/encoding/protowire"
+	pref "google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// EnumNameOptions configures the Converters returned by New, NewList
+// and NewNameField. Names that are not declared by the enum are reported
+// as errors by the Converters returned by New and NewList, but convert to
+// the zero value of the Go enum type with those returned by NewNameField.
+type EnumNameOptions struct {
+	// Normalize specifies that names are converted to SCREAMING_SNAKE_CASE
+	// before being looked up, by upper-casing them and replacing hyphens
+	// and spaces with underscores, so that "my-value" and "my value" both
+	// refer to MY_VALUE. The original spelling is not preserved.
+	Normalize bool
+
+	// PreserveUnknown specifies that numbers not declared by the enum
+	// are formatted as "UNKNOWN(n)" rather than rejected, and that such
+	// names are parsed back into the number n. This preserves values set
+	// by newer versions of the schema.
+	PreserveUnknown bool
+
+	// AcceptAliases specifies that every name of an enum declared with
+	// allow_alias is accepted, including later names of a number, such as
+	// deprecated spellings. Otherwise only the first declared name of
+	// a number is accepted. Converting from a number always results in
+	// the first declared name.
+	AcceptAliases bool
+}
+
+// New returns a TryConverter between a Go string type holding the name of
+// an enum value and the enum field fd.
+// Names that are not declared by the enum are reported by TryPBValueOf,
+// and undeclared numbers are reported by TryGoValueOf.
+func (o EnumNameOptions) New(t reflect.Type, fd pref.FieldDescriptor) TryConverter {
+	if fd.Enum() == nil || t.Kind() != reflect.String {
+		panic(fmt.Sprintf("invalid Go type %v for enum name field %v", t, fd.FullName()))
+	}
+	return &enumNameConverter{
+		goType: t,
+		fd:     fd,
+		ed:     fd.Enum(),
+		opts:   o,
+		enum:   newEnumConverter(reflect.TypeOf(pref.EnumNumber(0)), fd),
+	}
+}
+
+// NewList returns a Converter between a slice of a Go string type holding
+// enum value names, such as []string, and the repeated enum field fd.
+// Every element is converted as by New, and the index of every invalid
+// name is reported by TryPBValuesOf in ListErrors.
+func (o EnumNameOptions) NewList(t reflect.Type, fd pref.FieldDescriptor) Converter {
+	if t.Kind() != reflect.Slice || !fd.IsList() {
+		panic(fmt.Sprintf("invalid Go type %v for enum name list field %v", t, fd.FullName()))
+	}
+	return &listConverter{goType: t, c: o.New(t.Elem(), fd)}
+}
+
+// NewNameField returns a TryConverter between the Go enum type t, which must
+// implement protoreflect.Enum, and a string or bytes field holding the name
+// of the enum value, encoded as UTF-8 for a bytes field.
+// Names that are not declared by the enum convert to the zero value of t,
+// and undeclared numbers are reported by TryPBValueOf unless
+// PreserveUnknown is set.
+func (o EnumNameOptions) NewNameField(t reflect.Type, fd pref.FieldDescriptor) TryConverter {
+	e, ok := reflect.Zero(t).Interface().(pref.Enum)
+	if !ok || (fd.Kind() != pref.StringKind && fd.Kind() != pref.BytesKind) {
+		panic(fmt.Sprintf("invalid Go type %v for enum name field %v", t, fd.FullName()))
+	}
+	names := &enumNameConverter{goType: stringType, fd: fd, ed: e.Descriptor(), opts: o}
+	enum := &enumConverter{goType: t, useNumber: usesEnumNumber(t)}
+	isBytes := fd.Kind() == pref.BytesKind
+	return &funcConverter{
+		goType: t,
+		pb:     newSingularConverter(scalarGoType(fd.Kind()), fd),
+		toPB: func(v reflect.Value) (pref.Value, error) {
+			name, err := names.nameOf(enum.PBValueOf(v).Enum())
+			if err != nil {
+				return pref.Value{}, err
+			}
+			if isBytes {
+				return pref.ValueOfBytes([]byte(name)), nil
+			}
+			return pref.ValueOfString(name), nil
+		},
+		toGo: func(v pref.Value) (reflect.Value, error) {
+			var name string
+			if isBytes {
+				name = string(v.Bytes())
+			} else {
+				name = v.String()
+			}
+			n, err := names.numberOf(name)
+			if err != nil {
+				n = 0 // unknown names are the zero value
+			}
+			return enum.GoValueOf(pref.ValueOfEnum(n)), nil
+		},
+	}
+}
+
+type enumNameConverter struct {
+	goType reflect.Type
+	fd     pref.FieldDescriptor
+	ed     pref.EnumDescriptor
+	opts   EnumNameOptions
+	enum   Converter // converts enum numbers
+}
+
+func (c *enumNameConverter) TryPBValueOf(v reflect.Value) (pref.Value, error) {
+	if v.Type() != c.goType {
+		panic(fmt.Sprintf("invalid type: got %v, want %v", v.Type(), c.goType))
+	}
+	n, err := c.numberOf(v.String())
+	if err != nil {
+		return pref.Value{}, err
+	}
+	return pref.ValueOfEnum(n), nil
+}
+
+func (c *enumNameConverter) TryGoValueOf(v pref.Value) (reflect.Value, error) {
+	name, err := c.nameOf(v.Enum())
+	if err != nil {
+		return reflect.Value{}, err
+	}
+	return reflect.ValueOf(name).Convert(c.goType), nil
+}
+
+// numberOf returns the number of the enum value with the given name.
+func (c *enumNameConverter) numberOf(name string) (pref.EnumNumber, error) {
+	if c.opts.Normalize {
+		name = normalizeEnumName(name)
+	}
+	if ev := c.ed.Values().ByName(pref.Name(name)); ev != nil {
+		if !c.opts.AcceptAliases && c.ed.Values().ByNumber(ev.Number()) != ev {
+			return 0, fmt.Errorf("invalid alias %q for enum %v of field %v: want %v", name, c.ed.FullName(), c.fd.FullName(), c.ed.Values().ByNumber(ev.Number()).Name())
+		}
+		return ev.Number(), nil
+	}
+	if c.opts.PreserveUnknown && strings.HasPrefix(name, unknownEnumPrefix) && strings.HasSuffix(name, ")") {
+		s := strings.TrimSuffix(strings.TrimPrefix(name, unknownEnumPrefix), ")")
+		if n, err := strconv.ParseInt(s, 10, 32); err == nil {
+			return pref.EnumNumber(n), nil
+		}
+	}
+	return 0, fmt.Errorf("invalid value %q for enum %v of field %v", name, c.ed.FullName(), c.fd.FullName())
+}
+
+// nameOf returns the name of the enum value with the given number,
+// which is the first declared name if the number has aliases.
+func (c *enumNameConverter) nameOf(n pref.EnumNumber) (string, error) {
+	if ev := c.ed.Values().ByNumber(n); ev != nil {
+		return string(ev.Name()), nil
+	}
+	if c.opts.PreserveUnknown {
+		return unknownEnumPrefix + strconv.Itoa(int(n)) + ")", nil
+	}
+	return "", fmt.Errorf("invalid number %d for enum %v of field %v", n, c.ed.FullName(), c.fd.FullName())
+}
+
+// unknownEnumPrefix prefixes the names of undeclared enum numbers
+// when EnumNameOptions.PreserveUnknown is set.
+const unknownEnumPrefix = "UNKNOWN("
+
+// normalizeEnumName converts s to the SCREAMING_SNAKE_CASE
+// conventionally used for enum value names.
+func normalizeEnumName(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '-' || r == ' ' {
+			return '_'
+		}
+		return r
+	}, strings.ToUpper(s))
+}
+
+func (c *enumNameConverter) PBValueOf(v reflect.Value) pref.Value {
+	pv, err := c.TryPBValueOf(v)
+	if err != nil {
+		panic(err)
+	}
+	return pv
+}
+
+func (c *enumNameConverter) GoValueOf(v pref.Value) reflect.Value {
+	rv, err := c.TryGoValueOf(v)
+	if err != nil {
+		panic(err)
+	}
+	return rv
+}
+
+func (c *enumNameConverter) IsValidPB(v pref.Value) bool {
+	return c.enum.IsValidPB(v)
+}
+
+func (c *enumNameConverter) IsValidGo(v reflect.Value) bool {
+	return v.IsValid() && v.Type() == c.goType
+}
+
+func (c *enumNameConverter) New() pref.Value {
+	return c.enum.New()
+}
+
+func (c *enumNameConverter) Zero() pref.Value {
+	return c.enum.Zero()
+}
+
+func (c *enumNameConverter) IsZeroValue(v pref.Value) bool {
+	return c.enum.IsZeroValue(v)
+}
+
+func (c *enumNameConverter) Pretty(v pref.Value) string {
+	if name, err := c.nameOf(v.Enum()); err == nil {
+		return name
+	}
+	return prettyValue(v)
+}
+
+func (c *enumNameConverter) FieldNumber() protowire.Number {
+	return c.fd.Number()
+}
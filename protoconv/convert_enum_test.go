@@ -0,0 +1,314 @@
+package protoconv_test
+
+import (
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/dnscrypt/dnscrypt-proxy/protoconv"
+	"google.golang.org/protobuf/encoding/prototext"
+	"google.golang.org/protobuf/reflect/protodesc"
+	pref "google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+func TestEnumNameNormalize(t *testing.T) {
+	tests := []struct {
+		in        string
+		normalize bool
+		want      pref.EnumNumber
+		wantErr   bool
+	}{
+		{"MY_VALUE", false, 1, false},
+		{"my-value", false, 0, true},
+		{"MY_VALUE", true, 1, false},
+		{"my-value", true, 1, false},
+		{"my value", true, 1, false},
+		{"My_Value", true, 1, false},
+		{"other", true, 2, false},
+		{"no-such-value", true, 0, true},
+	}
+	for _, tt := range tests {
+		c := protoconv.EnumNameOptions{Normalize: tt.normalize}.New(stringType, field("e"))
+		v, err := c.TryPBValueOf(reflect.ValueOf(tt.in))
+		if (err != nil) != tt.wantErr {
+			t.Errorf("Normalize=%v: TryPBValueOf(%q) error = %v, want error %v", tt.normalize, tt.in, err, tt.wantErr)
+			continue
+		}
+		if err == nil && v.Enum() != tt.want {
+			t.Errorf("Normalize=%v: TryPBValueOf(%q) = %v, want %v", tt.normalize, tt.in, v.Enum(), tt.want)
+		}
+	}
+}
+
+func TestEnumNamePreserveUnknown(t *testing.T) {
+	tests := []struct {
+		n        pref.EnumNumber
+		preserve bool
+		want     string
+		wantErr  bool
+	}{
+		{1, false, "MY_VALUE", false},
+		{1, true, "MY_VALUE", false},
+		{42, false, "", true},
+		{42, true, "UNKNOWN(42)", false},
+		{-3, true, "UNKNOWN(-3)", false},
+	}
+	for _, tt := range tests {
+		c := protoconv.EnumNameOptions{PreserveUnknown: tt.preserve}.New(stringType, field("e"))
+		rv, err := c.TryGoValueOf(pref.ValueOfEnum(tt.n))
+		if (err != nil) != tt.wantErr {
+			t.Errorf("PreserveUnknown=%v: TryGoValueOf(%d) error = %v, want error %v", tt.preserve, tt.n, err, tt.wantErr)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if rv.String() != tt.want {
+			t.Errorf("PreserveUnknown=%v: TryGoValueOf(%d) = %q, want %q", tt.preserve, tt.n, rv.String(), tt.want)
+		}
+		v, err := c.TryPBValueOf(rv)
+		if err != nil || v.Enum() != tt.n {
+			t.Errorf("PreserveUnknown=%v: TryPBValueOf(%q) = %v, %v, want %d", tt.preserve, tt.want, v, err, tt.n)
+		}
+	}
+}
+
+func TestEnumNameField(t *testing.T) {
+	tests := []struct {
+		field string
+		name  pref.Value
+		want  E
+	}{
+		{"s", pref.ValueOfString("MY_VALUE"), 1},
+		{"s", pref.ValueOfString("OTHER"), 2},
+		{"s", pref.ValueOfString("NO_SUCH_VALUE"), 0},
+		{"b", pref.ValueOfBytes([]byte("MY_VALUE")), 1},
+		{"b", pref.ValueOfBytes([]byte("NO_SUCH_VALUE")), 0},
+		{"b", pref.ValueOfBytes(nil), 0},
+	}
+	for _, tt := range tests {
+		c := protoconv.EnumNameOptions{}.NewNameField(reflect.TypeOf(E(0)), field(tt.field))
+		got, err := c.TryGoValueOf(tt.name)
+		if err != nil || got.Interface() != tt.want {
+			t.Errorf("field %v: TryGoValueOf(%v) = %v, %v, want %v", tt.field, tt.name, got, err, tt.want)
+		}
+	}
+}
+
+func TestEnumNameFieldOutbound(t *testing.T) {
+	c := protoconv.EnumNameOptions{}.NewNameField(reflect.TypeOf(E(0)), field("b"))
+	if got := string(c.PBValueOf(reflect.ValueOf(E(2))).Bytes()); got != "OTHER" {
+		t.Errorf("PBValueOf(2) = %q, want %q", got, "OTHER")
+	}
+	if _, err := c.TryPBValueOf(reflect.ValueOf(E(9))); err == nil {
+		t.Error("TryPBValueOf(9) succeeded, want error for an undeclared number")
+	}
+
+	// Unlike NewNameField, New reports unknown names.
+	names := protoconv.EnumNameOptions{}.New(stringType, field("e"))
+	if _, err := names.TryPBValueOf(reflect.ValueOf("NO_SUCH_VALUE")); err == nil {
+		t.Error("New: TryPBValueOf of an unknown name succeeded, want error")
+	}
+}
+
+func TestEnumConverterWithDefault(t *testing.T) {
+	tests := []struct {
+		name string
+		def  pref.EnumNumber
+	}{
+		{"zero", 0},
+		{"declared override", 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := protoconv.NewEnumConverterWithDefault(reflect.TypeOf(E(0)), field("e"), tt.def)
+			if got := c.Zero().Enum(); got != tt.def {
+				t.Errorf("Zero() = %v, want %v", got, tt.def)
+			}
+			if got := c.New().Enum(); got != tt.def {
+				t.Errorf("New() = %v, want %v", got, tt.def)
+			}
+			if !c.IsZeroValue(pref.ValueOfEnum(tt.def)) {
+				t.Errorf("IsZeroValue(%v) = false, want true", tt.def)
+			}
+			if got := c.GoValueOf(c.Zero()).Interface(); got != E(tt.def) {
+				t.Errorf("GoValueOf(Zero()) = %v, want %v", got, E(tt.def))
+			}
+		})
+	}
+
+	defer func() {
+		if r := recover(); r == nil {
+			t.Error("NewEnumConverterWithDefault with an undeclared default did not panic")
+		}
+	}()
+	protoconv.NewEnumConverterWithDefault(reflect.TypeOf(E(0)), field("e"), 9)
+}
+
+func TestEnumNameList(t *testing.T) {
+	c := protoconv.EnumNameOptions{}.NewList(reflect.TypeOf([]string(nil)), field("re")).(valuesConverter)
+	tests := []struct {
+		name        string
+		in          []string
+		want        []pref.EnumNumber
+		wantIndexes []int
+	}{
+		{"valid", []string{"MY_VALUE", "OTHER"}, []pref.EnumNumber{1, 2}, nil},
+		{"empty", []string{}, []pref.EnumNumber{}, nil},
+		{"one invalid", []string{"MY_VALUE", "NOPE"}, nil, []int{1}},
+		{"several invalid", []string{"BAD", "MY_VALUE", "NOPE", "OTHER", ""}, nil, []int{0, 2, 4}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vs, err := c.TryPBValuesOf(reflect.ValueOf(tt.in))
+			if tt.wantIndexes != nil {
+				var errs protoconv.ListErrors
+				if !errors.As(err, &errs) {
+					t.Fatalf("TryPBValuesOf(%q) error = %v, want ListErrors", tt.in, err)
+				}
+				var got []int
+				for _, e := range errs {
+					got = append(got, e.Index)
+				}
+				if !reflect.DeepEqual(got, tt.wantIndexes) {
+					t.Errorf("TryPBValuesOf(%q) reported indexes %v, want %v", tt.in, got, tt.wantIndexes)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("TryPBValuesOf(%q) error: %v", tt.in, err)
+			}
+			got := make([]pref.EnumNumber, len(vs))
+			for i, v := range vs {
+				got[i] = v.Enum()
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("TryPBValuesOf(%q) = %v, want %v", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+// wideEnum is an enum stored in an int64.
+type wideEnum int64
+
+func TestWideEnumConverter(t *testing.T) {
+	c := protoconv.NewConverter(reflect.TypeOf(wideEnum(0)), field("e")).(protoconv.TryConverter)
+	tests := []struct {
+		name    string
+		in      wideEnum
+		wantErr bool
+	}{
+		{"declared", 2, false},
+		{"max int32", math.MaxInt32, false},
+		{"min int32", math.MinInt32, false},
+		{"above int32", math.MaxInt32 + 1, true},
+		{"below int32", math.MinInt32 - 1, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v, err := c.TryPBValueOf(reflect.ValueOf(tt.in))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("TryPBValueOf(%d) error = %v, want error %v", tt.in, err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if got := v.Enum(); int64(got) != int64(tt.in) {
+				t.Errorf("TryPBValueOf(%d) = %v", tt.in, got)
+			}
+			if got := c.GoValueOf(v).Interface(); got != tt.in {
+				t.Errorf("GoValueOf = %v, want %v", got, tt.in)
+			}
+		})
+	}
+
+	l := protoconv.NewConverter(reflect.TypeOf([]wideEnum(nil)), field("re"))
+	if got := l.PBValueOf(reflect.ValueOf([]wideEnum{1, 2})).List().Get(1).Enum(); got != 2 {
+		t.Errorf("list element 1 = %v, want 2", got)
+	}
+}
+
+// aliasField is an enum field whose enum declares OLD as an alias of NEW.
+var aliasField = func() pref.FieldDescriptor {
+	fdp := &descriptorpb.FileDescriptorProto{}
+	if err := prototext.Unmarshal([]byte(`
+		name: "alias.proto" package: "alias" syntax: "proto3"
+		enum_type: [{name: "A" value: [
+			{name: "A_ZERO" number: 0}, {name: "NEW" number: 1}, {name: "OLD" number: 1}
+		] options: {allow_alias: true}}]
+		message_type: [{name: "M" field: [{name: "a" number: 1 label: LABEL_OPTIONAL type: TYPE_ENUM type_name: ".alias.A" json_name: "a"}]}]
+	`), fdp); err != nil {
+		panic(err)
+	}
+	f, err := protodesc.NewFile(fdp, nil)
+	if err != nil {
+		panic(err)
+	}
+	return f.Messages().Get(0).Fields().Get(0)
+}()
+
+func TestEnumNameAliases(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    protoconv.EnumNameOptions
+		in      string
+		wantErr bool
+	}{
+		{"first name", protoconv.EnumNameOptions{}, "NEW", false},
+		{"alias rejected", protoconv.EnumNameOptions{}, "OLD", true},
+		{"first name accepting aliases", protoconv.EnumNameOptions{AcceptAliases: true}, "NEW", false},
+		{"alias accepted", protoconv.EnumNameOptions{AcceptAliases: true}, "OLD", false},
+		{"normalized alias accepted", protoconv.EnumNameOptions{AcceptAliases: true, Normalize: true}, "old", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := tt.opts.New(stringType, aliasField)
+			v, err := c.TryPBValueOf(reflect.ValueOf(tt.in))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("TryPBValueOf(%q) error = %v, want error %v", tt.in, err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if v.Enum() != 1 {
+				t.Errorf("TryPBValueOf(%q) = %v, want 1", tt.in, v.Enum())
+			}
+			if got := c.GoValueOf(v).String(); got != "NEW" {
+				t.Errorf("GoValueOf(%v) = %q, want the first declared name %q", v.Enum(), got, "NEW")
+			}
+		})
+	}
+}
+
+func TestEnumConverterGoValues(t *testing.T) {
+	tests := []struct {
+		name string
+		typ  reflect.Type
+		c    protoconv.Converter
+	}{
+		{"generated", reflect.TypeOf(E(0)), protoconv.NewConverter(reflect.TypeOf(E(0)), field("e"))},
+		{"int64", reflect.TypeOf(wideEnum(0)), protoconv.NewConverter(reflect.TypeOf(wideEnum(0)), field("e"))},
+		{"with default", reflect.TypeOf(E(0)), protoconv.NewEnumConverterWithDefault(reflect.TypeOf(E(0)), field("e"), 1)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for n := pref.EnumNumber(0); n < 4; n++ { // 3 is undeclared
+				got := tt.c.GoValueOf(pref.ValueOfEnum(n))
+				if got.Type() != tt.typ || got.Int() != int64(n) {
+					t.Errorf("GoValueOf(%d) = %v (%v), want %d (%v)", n, got, got.Type(), n, tt.typ)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkEnumConverterGoValue(b *testing.B) {
+	c := protoconv.NewConverter(reflect.TypeOf(E(0)), field("e"))
+	v := pref.ValueOfEnum(2)
+	for i := 0; i < b.N; i++ {
+		c.GoValueOf(v)
+	}
+}
@@ -0,0 +1,138 @@
+package protoconv
+
+import (
+	"fmt"
+	"reflect"
+
+	"google.golang.org/protobuf/encoding/protowire"
+	pref "google.golang.org/protobuf/reflect/protoreflect"
+	preg "google.golang.org/protobuf/reflect/protoregistry"
+)
+
+// funcConverter is a TryConverter implemented by a pair of functions.
+// It is the basis for converters of Go types that protoc-gen-go never
+// generates and that are only used through RegisterConverter.
+type funcConverter struct {
+	goType reflect.Type
+
+	// pb converts the protobuf value to the Go type natively used
+	// for the field and provides validation and default values.
+	pb Converter
+
+	toPB func(reflect.Value) (pref.Value, error)
+	toGo func(pref.Value) (reflect.Value, error)
+}
+
+func (c *funcConverter) TryPBValueOf(v reflect.Value) (pref.Value, error) {
+	if v.Type() != c.goType {
+		panic(fmt.Sprintf("invalid type: got %v, want %v", v.Type(), c.goType))
+	}
+	return c.toPB(v)
+}
+
+func (c *funcConverter) TryGoValueOf(v pref.Value) (reflect.Value, error) {
+	return c.toGo(v)
+}
+
+func (c *funcConverter) PBValueOf(v reflect.Value) pref.Value {
+	pv, err := c.TryPBValueOf(v)
+	if err != nil {
+		panic(err)
+	}
+	return pv
+}
+
+func (c *funcConverter) GoValueOf(v pref.Value) reflect.Value {
+	rv, err := c.TryGoValueOf(v)
+	if err != nil {
+		panic(err)
+	}
+	return rv
+}
+
+func (c *funcConverter) IsValidPB(v pref.Value) bool {
+	return c.pb.IsValidPB(v)
+}
+
+func (c *funcConverter) IsValidGo(v reflect.Value) bool {
+	return v.IsValid() && v.Type() == c.goType
+}
+
+func (c *funcConverter) New() pref.Value {
+	return c.pb.New()
+}
+
+func (c *funcConverter) Zero() pref.Value {
+	return c.pb.Zero()
+}
+
+func (c *funcConverter) IsZeroValue(v pref.Value) bool {
+	return c.pb.IsZeroValue(v)
+}
+
+func (c *funcConverter) Pretty(v pref.Value) string {
+	return c.pb.Pretty(v)
+}
+
+func (c *funcConverter) FieldNumber() protowire.Number {
+	return c.pb.FieldNumber()
+}
+
+// intoConverter is a funcConverter for a pointer Go type whose values
+// are stored in place by into. Its toGo function need not be set.
+type intoConverter struct {
+	*funcConverter
+	into func(dst reflect.Value, v pref.Value)
+}
+
+func newIntoConverter(c *funcConverter, into func(dst reflect.Value, v pref.Value)) *intoConverter {
+	ic := &intoConverter{c, into}
+	c.toGo = func(v pref.Value) (reflect.Value, error) {
+		dst := reflect.New(c.goType.Elem())
+		into(dst, v)
+		return dst, nil
+	}
+	return ic
+}
+
+func (c *intoConverter) GoValueInto(dst reflect.Value, v pref.Value) {
+	if dst.Type() != c.goType {
+		panic(fmt.Sprintf("invalid type: got %v, want %v", dst.Type(), c.goType))
+	}
+	if dst.IsNil() {
+		panic(fmt.Sprintf("invalid nil %v", c.goType))
+	}
+	c.into(dst, v)
+}
+
+// tryPBValueOf calls c.TryPBValueOf if c is a TryConverter
+// and c.PBValueOf otherwise.
+func tryPBValueOf(c Converter, v reflect.Value) (pref.Value, error) {
+	if tc, ok := c.(TryConverter); ok {
+		return tc.TryPBValueOf(v)
+	}
+	return c.PBValueOf(v), nil
+}
+
+// tryGoValueOf calls c.TryGoValueOf if c is a TryConverter
+// and c.GoValueOf otherwise.
+func tryGoValueOf(c Converter, v pref.Value) (reflect.Value, error) {
+	if tc, ok := c.(TryConverter); ok {
+		return tc.TryGoValueOf(v)
+	}
+	return c.GoValueOf(v), nil
+}
+
+// findMessageType returns the message type of the message field fd
+// from the global registry.
+func findMessageType(fd pref.FieldDescriptor) pref.MessageType {
+	md := fd.Message()
+	if md == nil {
+		panic(fmt.Sprintf("invalid field %v: want message", fd.FullName()))
+	}
+	mt, err := preg.GlobalTypes.FindMessageByName(md.FullName())
+	if err != nil {
+		panic(fmt.Sprintf("cannot resolve message %v for field %v: %v", md.FullName(), fd.FullName(), err))
+	}
+	return mt
+}
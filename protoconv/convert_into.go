@@ -0,0 +1,74 @@
+package protoconv
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	pref "google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// NewAtomicPointerConverter returns an IntoConverter between a pointer to
+// an atomic.Pointer[M], where *M is a message, and a message field.
+// Any pointer type t with methods Load() *M and Store(*M) is accepted,
+// since the generic type cannot be named here.
+// PBValueOf loads the message, and GoValueInto stores it.
+// A nil stored pointer converts to an absent message, and the reverse.
+func NewAtomicPointerConverter(t reflect.Type, fd pref.FieldDescriptor) IntoConverter {
+	load, okLoad := t.MethodByName("Load")
+	store, okStore := t.MethodByName("Store")
+	if t.Kind() != reflect.Ptr || !okLoad || !okStore ||
+		load.Type.NumIn() != 1 || load.Type.NumOut() != 1 ||
+		store.Type.NumIn() != 2 || store.Type.NumOut() != 0 ||
+		store.Type.In(1) != load.Type.Out(0) {
+		panic(fmt.Sprintf("invalid Go type %v for field %v: want *atomic.Pointer", t, fd.FullName()))
+	}
+	if fd.Message() == nil || fd.IsList() || fd.IsMap() {
+		panic(fmt.Sprintf("invalid field %v for atomic pointer: want singular message", fd.FullName()))
+	}
+	msgType := load.Type.Out(0)
+	pb := newMessageConverter(msgType, fd)
+	return newIntoConverter(&funcConverter{
+		goType: t,
+		pb:     pb,
+		toPB: func(v reflect.Value) (pref.Value, error) {
+			if v.IsNil() {
+				return pb.Zero(), nil
+			}
+			return pb.PBValueOf(load.Func.Call([]reflect.Value{v})[0]), nil
+		},
+	}, func(dst reflect.Value, v pref.Value) {
+		m := reflect.Zero(msgType)
+		if v.Message().IsValid() {
+			m = pb.GoValueOf(v)
+		}
+		store.Func.Call([]reflect.Value{dst, m})
+	})
+}
+
+var stringsBuilderType = reflect.TypeOf((*strings.Builder)(nil))
+
+// NewStringsBuilderConverter returns an IntoConverter between
+// a *strings.Builder and a string field holding its contents.
+// GoValueInto resets the builder before writing the string to it.
+// A nil builder converts to the empty string.
+func NewStringsBuilderConverter(fd pref.FieldDescriptor) IntoConverter {
+	if fd.Kind() != pref.StringKind || fd.IsList() {
+		panic(fmt.Sprintf("invalid field %v for strings.Builder: got %v, want string", fd.FullName(), fd.Kind()))
+	}
+	return newIntoConverter(&funcConverter{
+		goType: stringsBuilderType,
+		pb:     newSingularConverter(stringType, fd),
+		toPB: func(v reflect.Value) (pref.Value, error) {
+			b := v.Interface().(*strings.Builder)
+			if b == nil {
+				return stringZero, nil
+			}
+			return pref.ValueOfString(b.String()), nil
+		},
+	}, func(dst reflect.Value, v pref.Value) {
+		b := dst.Interface().(*strings.Builder)
+		b.Reset()
+		b.WriteString(v.String())
+	})
+}
@@ -0,0 +1,79 @@
+package protoconv_test
+
+import (
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/dnscrypt/dnscrypt-proxy/protoconv"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+func TestAtomicPointerConverter(t *testing.T) {
+	c := protoconv.NewAtomicPointerConverter(reflect.TypeOf((*atomic.Pointer[durationpb.Duration])(nil)), field("dur"))
+	tests := []struct {
+		name   string
+		stored *durationpb.Duration
+	}{
+		{"populated", &durationpb.Duration{Seconds: 7}},
+		{"nil", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := new(atomic.Pointer[durationpb.Duration])
+			p.Store(tt.stored)
+			v := c.PBValueOf(reflect.ValueOf(p))
+			if got := v.Message().IsValid(); got != (tt.stored != nil) {
+				t.Fatalf("PBValueOf message valid = %v, want %v", got, tt.stored != nil)
+			}
+
+			dst := new(atomic.Pointer[durationpb.Duration])
+			dst.Store(&durationpb.Duration{Seconds: 99})
+			c.GoValueInto(reflect.ValueOf(dst), v)
+			if got := dst.Load(); got != tt.stored {
+				t.Errorf("GoValueInto stored %v, want %v", got, tt.stored)
+			}
+			if got := c.GoValueOf(v).Interface().(*atomic.Pointer[durationpb.Duration]).Load(); got != tt.stored {
+				t.Errorf("GoValueOf loads %v, want %v", got, tt.stored)
+			}
+		})
+	}
+}
+
+func TestStringsBuilderConverter(t *testing.T) {
+	c := protoconv.NewStringsBuilderConverter(field("s"))
+	var nilBuilder *strings.Builder
+	tests := []struct {
+		name string
+		in   *strings.Builder
+		want string
+	}{
+		{"nil", nilBuilder, ""},
+		{"empty", new(strings.Builder), ""},
+		{"written", func() *strings.Builder {
+			b := new(strings.Builder)
+			b.WriteString("dns")
+			b.WriteString("crypt")
+			return b
+		}(), "dnscrypt"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := c.PBValueOf(reflect.ValueOf(tt.in))
+			if got := v.String(); got != tt.want {
+				t.Errorf("PBValueOf = %q, want %q", got, tt.want)
+			}
+
+			dst := new(strings.Builder)
+			dst.WriteString("stale")
+			c.GoValueInto(reflect.ValueOf(dst), v)
+			if got := dst.String(); got != tt.want {
+				t.Errorf("GoValueInto left %q, want %q", got, tt.want)
+			}
+			if got := c.GoValueOf(v).Interface().(*strings.Builder).String(); got != tt.want {
+				t.Errorf("GoValueOf = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
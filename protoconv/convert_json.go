@@ -0,0 +1,348 @@
+package protoconv
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+
+	pref "google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// The functions in this file convert between JSON values, as decoded by
+// encoding/json into an interface{}, and the messages of struct.proto:
+// google.protobuf.Struct, Value, and ListValue.
+
+var jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+
+// NewJSONMarshalerConverter returns a TryConverter between a Go type t that
+// implements json.Marshaler and a google.protobuf.Struct, Value, or ListValue
+// field. Values are marshaled to JSON, which must be an object for a Struct
+// and an array for a ListValue, and parsed into the message. JSON null
+// converts to an absent Struct or ListValue. Converting from the field
+// renders the message as JSON and unmarshals it into a new value of type t
+// with json.Unmarshal, where an absent message is JSON null.
+func NewJSONMarshalerConverter(t reflect.Type, fd pref.FieldDescriptor) TryConverter {
+	if !t.Implements(jsonMarshalerType) {
+		panic(fmt.Sprintf("invalid Go type %v for field %v: want json.Marshaler", t, fd.FullName()))
+	}
+	if fd.IsList() || fd.IsMap() {
+		panic(fmt.Sprintf("invalid field %v for JSON: want singular message", fd.FullName()))
+	}
+	mt := wellKnownMessageType(fd, structFile)
+	return &funcConverter{
+		goType: t,
+		pb:     newMessageConverter(reflect.TypeOf(mt.Zero().Interface()), fd),
+		toPB: func(v reflect.Value) (pref.Value, error) {
+			b, err := json.Marshal(v.Interface())
+			if err != nil {
+				return pref.Value{}, fmt.Errorf("cannot marshal field %v: %w", fd.FullName(), err)
+			}
+			var x interface{}
+			if err := json.Unmarshal(b, &x); err != nil {
+				return pref.Value{}, fmt.Errorf("invalid JSON for field %v: %w", fd.FullName(), err)
+			}
+			if x == nil && mt.Descriptor().FullName() != valueMessageName {
+				return pref.ValueOfMessage(mt.Zero()), nil
+			}
+			m := mt.New()
+			if err := setJSON(m, x); err != nil {
+				return pref.Value{}, fmt.Errorf("field %v: %w", fd.FullName(), err)
+			}
+			return pref.ValueOfMessage(m), nil
+		},
+		toGo: func(v pref.Value) (reflect.Value, error) {
+			var x interface{}
+			if m := v.Message(); m.IsValid() {
+				x = jsonOf(m)
+			}
+			b, err := json.Marshal(x)
+			if err != nil {
+				return reflect.Value{}, fmt.Errorf("cannot marshal field %v: %w", fd.FullName(), err)
+			}
+			p := reflect.New(t)
+			if err := json.Unmarshal(b, p.Interface()); err != nil {
+				return reflect.Value{}, fmt.Errorf("cannot unmarshal field %v: %w", fd.FullName(), err)
+			}
+			return p.Elem(), nil
+		},
+	}
+}
+
+var rawJSONMapType = reflect.TypeOf(map[string]json.RawMessage(nil))
+
+// NewRawJSONMapConverter returns a TryConverter between a
+// map[string]json.RawMessage and a google.protobuf.Struct field,
+// where every raw value is parsed into the Value of its key.
+// Malformed JSON is reported by TryPBValueOf along with its key,
+// checking keys in sorted order so that the reported key is deterministic.
+// A nil map converts to an absent Struct, and the reverse. Converting from
+// the field renders every Value as compact JSON.
+func NewRawJSONMapConverter(fd pref.FieldDescriptor) TryConverter {
+	mt := wellKnownMessageType(fd, structFile)
+	if mt.Descriptor().FullName() != structMessageName || fd.IsList() {
+		panic(fmt.Sprintf("invalid field %v for %v: want singular %v", fd.FullName(), rawJSONMapType, structMessageName))
+	}
+	fieldsFd := mt.Descriptor().Fields().ByNumber(structFieldsField)
+	return &funcConverter{
+		goType: rawJSONMapType,
+		pb:     newMessageConverter(reflect.TypeOf(mt.Zero().Interface()), fd),
+		toPB: func(v reflect.Value) (pref.Value, error) {
+			raw := v.Interface().(map[string]json.RawMessage)
+			if raw == nil {
+				return pref.ValueOfMessage(mt.Zero()), nil
+			}
+			keys := make([]string, 0, len(raw))
+			for k := range raw {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			m := mt.New()
+			fields := m.Mutable(fieldsFd).Map()
+			for _, k := range keys {
+				var x interface{}
+				if err := json.Unmarshal(raw[k], &x); err != nil {
+					return pref.Value{}, fmt.Errorf("invalid JSON for key %q of field %v: %w", k, fd.FullName(), err)
+				}
+				fv := fields.NewValue()
+				if err := setJSONValue(fv.Message(), x); err != nil {
+					return pref.Value{}, fmt.Errorf("key %q of field %v: %w", k, fd.FullName(), err)
+				}
+				fields.Set(pref.ValueOfString(k).MapKey(), fv)
+			}
+			return pref.ValueOfMessage(m), nil
+		},
+		toGo: func(v pref.Value) (reflect.Value, error) {
+			m := v.Message()
+			if !m.IsValid() {
+				return reflect.Zero(rawJSONMapType), nil
+			}
+			raw := make(map[string]json.RawMessage)
+			for k, x := range jsonOfStruct(m) {
+				b, err := json.Marshal(x)
+				if err != nil {
+					return reflect.Value{}, fmt.Errorf("cannot marshal key %q of field %v: %w", k, fd.FullName(), err)
+				}
+				raw[k] = b
+			}
+			return reflect.ValueOf(raw), nil
+		},
+	}
+}
+
+// NewStructMapConverter returns a TryConverter between a
+// map[string]interface{} of JSON-like values and a google.protobuf.Struct
+// field. Values may be nil, bools, strings, numbers, and nested
+// map[string]interface{} and []interface{} values, nested at most maxDepth
+// levels below the map; deeper values, which includes maps that contain
+// themselves, are reported by TryPBValueOf rather than overflowing the stack.
+// Since a Value holds numbers as doubles, integers that a float64 cannot
+// represent exactly are also reported, and numbers convert back as float64.
+// A nil map converts to an absent Struct, and the reverse.
+func NewStructMapConverter(maxDepth int, fd pref.FieldDescriptor) TryConverter {
+	mt := wellKnownMessageType(fd, structFile)
+	if mt.Descriptor().FullName() != structMessageName || fd.IsList() {
+		panic(fmt.Sprintf("invalid field %v for %v: want singular %v", fd.FullName(), anyMapType, structMessageName))
+	}
+	return &funcConverter{
+		goType: anyMapType,
+		pb:     newMessageConverter(reflect.TypeOf(mt.Zero().Interface()), fd),
+		toPB: func(v reflect.Value) (pref.Value, error) {
+			obj := v.Interface().(map[string]interface{})
+			if obj == nil {
+				return pref.ValueOfMessage(mt.Zero()), nil
+			}
+			if err := checkJSON(obj, maxDepth); err != nil {
+				return pref.Value{}, fmt.Errorf("field %v: %w", fd.FullName(), err)
+			}
+			m := mt.New()
+			if err := setJSONStruct(m, obj); err != nil {
+				return pref.Value{}, fmt.Errorf("field %v: %w", fd.FullName(), err)
+			}
+			return pref.ValueOfMessage(m), nil
+		},
+		toGo: func(v pref.Value) (reflect.Value, error) {
+			if !v.Message().IsValid() {
+				return reflect.Zero(anyMapType), nil
+			}
+			return reflect.ValueOf(jsonOfStruct(v.Message())), nil
+		},
+	}
+}
+
+// checkJSON reports values of x nested more than depth levels below it,
+// and integers that do not round-trip through a float64.
+func checkJSON(x interface{}, depth int) error {
+	switch x := x.(type) {
+	case map[string]interface{}:
+		if depth < 0 {
+			return fmt.Errorf("JSON value nested too deeply")
+		}
+		for k, v := range x {
+			if err := checkJSON(v, depth-1); err != nil {
+				return fmt.Errorf("key %q: %w", k, err)
+			}
+		}
+	case []interface{}:
+		if depth < 0 {
+			return fmt.Errorf("JSON value nested too deeply")
+		}
+		for i, v := range x {
+			if err := checkJSON(v, depth-1); err != nil {
+				return fmt.Errorf("index %d: %w", i, err)
+			}
+		}
+	default:
+		switch rv := reflect.ValueOf(x); rv.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			if n := rv.Int(); n != int64(float64(n)) || n == math.MaxInt64 {
+				return fmt.Errorf("integer %d not exactly representable as a double", n)
+			}
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			if n := rv.Uint(); n != uint64(float64(n)) || n == math.MaxUint64 {
+				return fmt.Errorf("integer %d not exactly representable as a double", n)
+			}
+		}
+	}
+	return nil
+}
+
+// setJSON sets m, which must be a Struct, Value, or ListValue,
+// to the JSON value x.
+func setJSON(m pref.Message, x interface{}) error {
+	switch name := m.Descriptor().FullName(); name {
+	case structMessageName:
+		obj, ok := x.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("invalid JSON value of type %T for %v: want object", x, name)
+		}
+		return setJSONStruct(m, obj)
+	case listValueMessageName:
+		arr, ok := x.([]interface{})
+		if !ok {
+			return fmt.Errorf("invalid JSON value of type %T for %v: want array", x, name)
+		}
+		return setJSONList(m, arr)
+	case valueMessageName:
+		return setJSONValue(m, x)
+	default:
+		panic(fmt.Sprintf("invalid message %v: want struct.proto message", name))
+	}
+}
+
+func setJSONStruct(m pref.Message, obj map[string]interface{}) error {
+	fd := m.Descriptor().Fields().ByNumber(structFieldsField)
+	fields := m.Mutable(fd).Map()
+	for k, x := range obj {
+		v := fields.NewValue()
+		if err := setJSONValue(v.Message(), x); err != nil {
+			return fmt.Errorf("key %q: %w", k, err)
+		}
+		fields.Set(pref.ValueOfString(k).MapKey(), v)
+	}
+	return nil
+}
+
+func setJSONList(m pref.Message, arr []interface{}) error {
+	fd := m.Descriptor().Fields().ByNumber(listValueValuesField)
+	values := m.Mutable(fd).List()
+	for i, x := range arr {
+		v := values.NewElement()
+		if err := setJSONValue(v.Message(), x); err != nil {
+			return fmt.Errorf("index %d: %w", i, err)
+		}
+		values.Append(v)
+	}
+	return nil
+}
+
+func setJSONValue(m pref.Message, x interface{}) error {
+	fds := m.Descriptor().Fields()
+	switch x := x.(type) {
+	case nil:
+		m.Set(fds.ByNumber(valueNullField), pref.ValueOfEnum(0))
+	case bool:
+		m.Set(fds.ByNumber(valueBoolField), pref.ValueOfBool(x))
+	case string:
+		m.Set(fds.ByNumber(valueStringField), pref.ValueOfString(x))
+	case map[string]interface{}:
+		return setJSONStruct(m.Mutable(fds.ByNumber(valueStructField)).Message(), x)
+	case []interface{}:
+		return setJSONList(m.Mutable(fds.ByNumber(valueListField)).Message(), x)
+	default:
+		var f float64
+		switch rv := reflect.ValueOf(x); rv.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			f = float64(rv.Int())
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			f = float64(rv.Uint())
+		case reflect.Float32, reflect.Float64:
+			f = rv.Float()
+		default:
+			return fmt.Errorf("invalid JSON value of type %T", x)
+		}
+		m.Set(fds.ByNumber(valueNumberField), pref.ValueOfFloat64(f))
+	}
+	return nil
+}
+
+// jsonOf returns the JSON value of m, which must be a Struct, Value,
+// or ListValue.
+func jsonOf(m pref.Message) interface{} {
+	switch name := m.Descriptor().FullName(); name {
+	case structMessageName:
+		return jsonOfStruct(m)
+	case listValueMessageName:
+		return jsonOfList(m)
+	case valueMessageName:
+		return jsonOfValue(m)
+	default:
+		panic(fmt.Sprintf("invalid message %v: want struct.proto message", name))
+	}
+}
+
+func jsonOfStruct(m pref.Message) map[string]interface{} {
+	fields := m.Get(m.Descriptor().Fields().ByNumber(structFieldsField)).Map()
+	obj := make(map[string]interface{}, fields.Len())
+	fields.Range(func(k pref.MapKey, v pref.Value) bool {
+		obj[k.String()] = jsonOfValue(v.Message())
+		return true
+	})
+	return obj
+}
+
+func jsonOfList(m pref.Message) []interface{} {
+	values := m.Get(m.Descriptor().Fields().ByNumber(listValueValuesField)).List()
+	arr := make([]interface{}, values.Len())
+	for i := range arr {
+		arr[i] = jsonOfValue(values.Get(i).Message())
+	}
+	return arr
+}
+
+// jsonOfValue returns the JSON value of the Value m,
+// where a Value without a kind is null.
+func jsonOfValue(m pref.Message) interface{} {
+	if !m.IsValid() {
+		return nil
+	}
+	fd := m.WhichOneof(m.Descriptor().Oneofs().ByName(valueKindOneof))
+	if fd == nil {
+		return nil
+	}
+	v := m.Get(fd)
+	switch fd.Number() {
+	case valueNumberField:
+		return v.Float()
+	case valueStringField:
+		return v.String()
+	case valueBoolField:
+		return v.Bool()
+	case valueStructField:
+		return jsonOfStruct(v.Message())
+	case valueListField:
+		return jsonOfList(v.Message())
+	}
+	return nil
+}
@@ -0,0 +1,162 @@
+package protoconv_test
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/dnscrypt/dnscrypt-proxy/protoconv"
+)
+
+// jsonObject marshals as a JSON object.
+type jsonObject map[string]interface{}
+
+func (m jsonObject) MarshalJSON() ([]byte, error) { return json.Marshal(map[string]interface{}(m)) }
+
+// jsonArray marshals as a JSON array.
+type jsonArray []int
+
+func (l jsonArray) MarshalJSON() ([]byte, error) { return json.Marshal([]int(l)) }
+
+func TestJSONMarshalerConverter(t *testing.T) {
+	tests := []struct {
+		name  string
+		field string
+		in    json.Marshaler
+	}{
+		{"object to Struct", "st", jsonObject{"a": 1.5, "b": []interface{}{"x", true, nil}, "c": map[string]interface{}{"d": "e"}}},
+		{"empty object to Struct", "st", jsonObject{}},
+		{"array to ListValue", "sl", jsonArray{1, 2, 3}},
+		{"array to Value", "sv", jsonArray{4}},
+		{"object to Value", "sv", jsonObject{"k": "v"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := protoconv.NewJSONMarshalerConverter(reflect.TypeOf(tt.in), field(tt.field))
+			v, err := c.TryPBValueOf(reflect.ValueOf(tt.in))
+			if err != nil {
+				t.Fatalf("TryPBValueOf(%v) error: %v", tt.in, err)
+			}
+			got, err := c.TryGoValueOf(v)
+			if err != nil || !reflect.DeepEqual(got.Interface(), tt.in) {
+				t.Errorf("TryGoValueOf = %v, %v, want %v", got, err, tt.in)
+			}
+		})
+	}
+}
+
+func TestJSONMarshalerConverterMismatch(t *testing.T) {
+	tests := []struct {
+		name  string
+		field string
+		in    json.Marshaler
+	}{
+		{"array to Struct", "st", jsonArray{1}},
+		{"object to ListValue", "sl", jsonObject{"a": 1.0}},
+	}
+	for _, tt := range tests {
+		c := protoconv.NewJSONMarshalerConverter(reflect.TypeOf(tt.in), field(tt.field))
+		if _, err := c.TryPBValueOf(reflect.ValueOf(tt.in)); err == nil {
+			t.Errorf("%s: TryPBValueOf succeeded, want error", tt.name)
+		}
+	}
+
+	c := protoconv.NewJSONMarshalerConverter(reflect.TypeOf(jsonObject(nil)), field("st"))
+	v, err := c.TryPBValueOf(reflect.ValueOf(jsonObject(nil)))
+	if err != nil || v.Message().IsValid() {
+		t.Errorf("TryPBValueOf(null) = %v, %v, want an absent Struct", v, err)
+	}
+}
+
+func TestRawJSONMapConverter(t *testing.T) {
+	c := protoconv.NewRawJSONMapConverter(field("st"))
+	tests := []struct {
+		name string
+		in   map[string]json.RawMessage
+		want map[string]json.RawMessage // as rendered back
+	}{
+		{
+			"mixed",
+			map[string]json.RawMessage{"n": json.RawMessage(`1.5`), "s": json.RawMessage(` "x" `), "o": json.RawMessage(`{"a": [true, null]}`)},
+			map[string]json.RawMessage{"n": json.RawMessage(`1.5`), "s": json.RawMessage(`"x"`), "o": json.RawMessage(`{"a":[true,null]}`)},
+		},
+		{"empty", map[string]json.RawMessage{}, map[string]json.RawMessage{}},
+		{"nil", nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v, err := c.TryPBValueOf(reflect.ValueOf(tt.in))
+			if err != nil {
+				t.Fatalf("TryPBValueOf error: %v", err)
+			}
+			if v.Message().IsValid() != (tt.in != nil) {
+				t.Errorf("TryPBValueOf present = %v, want %v", v.Message().IsValid(), tt.in != nil)
+			}
+			got, err := c.TryGoValueOf(v)
+			if err != nil || !reflect.DeepEqual(got.Interface(), tt.want) {
+				t.Errorf("TryGoValueOf = %s, %v, want %s", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestRawJSONMapConverterMalformed(t *testing.T) {
+	c := protoconv.NewRawJSONMapConverter(field("st"))
+	in := map[string]json.RawMessage{"ok": json.RawMessage(`1`), "bad": json.RawMessage(`{`), "worse": json.RawMessage(`]`)}
+	_, err := c.TryPBValueOf(reflect.ValueOf(in))
+	if err == nil || !strings.Contains(err.Error(), `"bad"`) {
+		t.Errorf("TryPBValueOf error = %v, want error naming key %q", err, "bad")
+	}
+}
+
+func TestStructMapConverter(t *testing.T) {
+	c := protoconv.NewStructMapConverter(3, field("st"))
+	tests := []struct {
+		name string
+		in   map[string]interface{}
+		want map[string]interface{} // numbers come back as float64
+	}{
+		{
+			"nested",
+			map[string]interface{}{"a": map[string]interface{}{"b": []interface{}{map[string]interface{}{"c": 1}, "x", nil, true}}, "n": 2.5},
+			map[string]interface{}{"a": map[string]interface{}{"b": []interface{}{map[string]interface{}{"c": 1.0}, "x", nil, true}}, "n": 2.5},
+		},
+		{"empty", map[string]interface{}{}, map[string]interface{}{}},
+		{"nil", nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v, err := c.TryPBValueOf(reflect.ValueOf(tt.in))
+			if err != nil {
+				t.Fatalf("TryPBValueOf error: %v", err)
+			}
+			if v.Message().IsValid() != (tt.in != nil) {
+				t.Errorf("TryPBValueOf present = %v, want %v", v.Message().IsValid(), tt.in != nil)
+			}
+			got, err := c.TryGoValueOf(v)
+			if err != nil || !reflect.DeepEqual(got.Interface(), tt.want) {
+				t.Errorf("TryGoValueOf = %v, %v, want %v", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestStructMapConverterInvalid(t *testing.T) {
+	cyclic := map[string]interface{}{}
+	cyclic["self"] = cyclic
+	c := protoconv.NewStructMapConverter(3, field("st"))
+	tests := []struct {
+		name string
+		in   map[string]interface{}
+	}{
+		{"too deep", map[string]interface{}{"a": map[string]interface{}{"b": []interface{}{map[string]interface{}{"c": []interface{}{1}}}}}},
+		{"cyclic", cyclic},
+		{"inexact integer", map[string]interface{}{"big": int64(1<<53 + 1)}},
+	}
+	for _, tt := range tests {
+		if _, err := c.TryPBValueOf(reflect.ValueOf(tt.in)); err == nil {
+			t.Errorf("%s: TryPBValueOf succeeded, want error", tt.name)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package protoconv
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+
+	pref "google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// NewLazyMessageConverter returns a Converter between a Go function type t
+// of the form func() *Foo, which lazily builds a message, and a message field.
+// PBValueOf calls the builder, and GoValueOf wraps the message in a builder
+// that returns it. A nil builder converts to an absent message,
+// and an absent message converts to a nil builder.
+func NewLazyMessageConverter(t reflect.Type, fd pref.FieldDescriptor) Converter {
+	if t.Kind() != reflect.Func || t.NumIn() != 0 || t.NumOut() != 1 || t.IsVariadic() {
+		panic(fmt.Sprintf("invalid Go type %v for field %v: want func() returning a message", t, fd.FullName()))
+	}
+	if fd.Message() == nil || fd.IsList() || fd.IsMap() {
+		panic(fmt.Sprintf("invalid field %v for lazy message: want singular message", fd.FullName()))
+	}
+	pb := newMessageConverter(t.Out(0), fd)
+	return &funcConverter{
+		goType: t,
+		pb:     pb,
+		toPB: func(v reflect.Value) (pref.Value, error) {
+			if v.IsNil() {
+				return pb.Zero(), nil
+			}
+			return pb.PBValueOf(v.Call(nil)[0]), nil
+		},
+		toGo: func(v pref.Value) (reflect.Value, error) {
+			if !v.Message().IsValid() {
+				return reflect.Zero(t), nil
+			}
+			out := []reflect.Value{pb.GoValueOf(v)}
+			return reflect.MakeFunc(t, func([]reflect.Value) []reflect.Value {
+				return out
+			}), nil
+		},
+	}
+}
+
+// OnceMessage is a message that is computed at most once, on first use.
+// It is safe for concurrent use.
+type OnceMessage struct {
+	once    sync.Once
+	compute func() pref.ProtoMessage
+	m       pref.ProtoMessage
+}
+
+// NewOnceMessage returns a OnceMessage computed by calling compute.
+func NewOnceMessage(compute func() pref.ProtoMessage) *OnceMessage {
+	return &OnceMessage{compute: compute}
+}
+
+// Get returns the message, computing it if this is the first call.
+// Concurrent callers wait for the computation to finish.
+func (m *OnceMessage) Get() pref.ProtoMessage {
+	m.once.Do(func() {
+		m.m = m.compute()
+		m.compute = nil
+	})
+	return m.m
+}
+
+var onceMessageType = reflect.TypeOf((*OnceMessage)(nil))
+
+// NewOnceMessageConverter returns a TryConverter between *OnceMessage and
+// a message field whose Go message type is t, such as *Foo. PBValueOf calls
+// Get, so the message is computed at most once however often, and however
+// concurrently, it is converted. GoValueOf results in a OnceMessage that has
+// already been computed. A nil OnceMessage or a nil message converts to
+// an absent message, and an absent message converts to a nil OnceMessage.
+// Messages of a type other than t are reported by TryPBValueOf.
+func NewOnceMessageConverter(t reflect.Type, fd pref.FieldDescriptor) TryConverter {
+	if fd.Message() == nil || fd.IsList() || fd.IsMap() {
+		panic(fmt.Sprintf("invalid field %v for once message: want singular message", fd.FullName()))
+	}
+	pb := newMessageConverter(t, fd)
+	return &funcConverter{
+		goType: onceMessageType,
+		pb:     pb,
+		toPB: func(v reflect.Value) (pref.Value, error) {
+			if v.IsNil() {
+				return pb.Zero(), nil
+			}
+			m := v.Interface().(*OnceMessage).Get()
+			if m == nil {
+				return pb.Zero(), nil
+			}
+			rv := reflect.ValueOf(m)
+			if rv.Type() != t {
+				return pref.Value{}, fmt.Errorf("invalid message of Go type %v for field %v: want %v", rv.Type(), fd.FullName(), t)
+			}
+			return pb.PBValueOf(rv), nil
+		},
+		toGo: func(v pref.Value) (reflect.Value, error) {
+			if !v.Message().IsValid() {
+				return reflect.Zero(onceMessageType), nil
+			}
+			m := &OnceMessage{m: pb.GoValueOf(v).Interface().(pref.ProtoMessage)}
+			m.once.Do(func() {})
+			return reflect.ValueOf(m), nil
+		},
+	}
+}
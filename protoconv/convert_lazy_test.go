@@ -0,0 +1,111 @@
+package protoconv_test
+
+import (
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/dnscrypt/dnscrypt-proxy/protoconv"
+	pref "google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+type lazyDuration func() *durationpb.Duration
+
+func TestLazyMessageConverter(t *testing.T) {
+	c := protoconv.NewLazyMessageConverter(reflect.TypeOf(lazyDuration(nil)), field("dur"))
+	calls := 0
+	build := lazyDuration(func() *durationpb.Duration {
+		calls++
+		return &durationpb.Duration{Seconds: 3}
+	})
+	tests := []struct {
+		name      string
+		in        lazyDuration
+		wantValid bool
+	}{
+		{"builder", build, true},
+		{"nil builder", nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := c.PBValueOf(reflect.ValueOf(tt.in))
+			if got := v.Message().IsValid(); got != tt.wantValid {
+				t.Fatalf("PBValueOf message valid = %v, want %v", got, tt.wantValid)
+			}
+			back := c.GoValueOf(v).Interface().(lazyDuration)
+			if !tt.wantValid {
+				if back != nil {
+					t.Error("GoValueOf(absent) = non-nil builder, want nil")
+				}
+				return
+			}
+			if d := back(); d.Seconds != 3 {
+				t.Errorf("GoValueOf builder returned %v, want 3s", d)
+			}
+		})
+	}
+	if calls != 1 {
+		t.Errorf("builder called %d times, want 1", calls)
+	}
+}
+
+func TestOnceMessageConverter(t *testing.T) {
+	c := protoconv.NewOnceMessageConverter(reflect.TypeOf((*Event)(nil)), blobField("event"))
+	tests := []struct {
+		name     string
+		in       *protoconv.OnceMessage
+		wantZone string // or "" for an absent message
+		wantErr  bool
+	}{
+		{"computed", protoconv.NewOnceMessage(func() pref.ProtoMessage { return &Event{Zone: "z"} }), "z", false},
+		{"nil result", protoconv.NewOnceMessage(func() pref.ProtoMessage { return nil }), "", false},
+		{"nil", nil, "", false},
+		{"wrong type", protoconv.NewOnceMessage(func() pref.ProtoMessage { return &Chunk{} }), "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v, err := c.TryPBValueOf(reflect.ValueOf(tt.in))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("TryPBValueOf error = %v, want error %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			back := c.GoValueOf(v).Interface().(*protoconv.OnceMessage)
+			if tt.wantZone == "" {
+				if v.Message().IsValid() || back != nil {
+					t.Errorf("converted to %v and back to %v, want absent and nil", v, back)
+				}
+				return
+			}
+			if zone := back.Get().(*Event).Zone; zone != tt.wantZone {
+				t.Errorf("GoValueOf has zone %q, want %q", zone, tt.wantZone)
+			}
+		})
+	}
+}
+
+func TestOnceMessageConverterConcurrent(t *testing.T) {
+	c := protoconv.NewOnceMessageConverter(reflect.TypeOf((*Event)(nil)), blobField("event"))
+	var calls int32
+	m := protoconv.NewOnceMessage(func() pref.ProtoMessage {
+		atomic.AddInt32(&calls, 1)
+		return &Event{Zone: "z"}
+	})
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if zone := c.PBValueOf(reflect.ValueOf(m)).Message().Interface().(*Event).Zone; zone != "z" {
+				t.Errorf("PBValueOf has zone %q, want %q", zone, "z")
+			}
+		}()
+	}
+	wg.Wait()
+	if calls != 1 {
+		t.Errorf("message computed %d times, want 1", calls)
+	}
+}
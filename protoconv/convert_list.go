@@ -0,0 +1,457 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package protoconv
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"golang.org/x/exp/constraints"
+	"google.golang.org/protobuf/encoding/protowire"
+	pref "google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// newListConverter returns a Converter for a repeated field backed by
+// a slice, or pointer to a slice, whose elements are matched with the field
+// as by newSingularConverter. Since rune is an alias for int32, a []rune
+// backs a repeated int32 field of code points without any conversion.
+// Defined slice types, such as a named []uint64 backing a repeated fixed64
+// field, are matched likewise, and values round-trip over the full range
+// of the element type.
+func (o ConverterOptions) newListConverter(t reflect.Type, fd pref.FieldDescriptor) Converter {
+	switch {
+	case t.Kind() == reflect.Ptr && t.Elem().Kind() == reflect.Slice:
+		return &listPtrConverter{t, o.newSingularConverter(t.Elem().Elem(), fd)}
+	case t.Kind() == reflect.Slice:
+		return &listConverter{
+			goType:    t,
+			c:         o.newSingularConverter(t.Elem(), fd),
+			nilAbsent: o.NilListAbsent,
+			intern:    o.InternStrings && t.Elem().Kind() == reflect.String && fd.Kind() == pref.StringKind,
+		}
+	}
+	panic(fmt.Sprintf("invalid Go type %v for field %v", t, fd.FullName()))
+}
+
+// MappingListConverter returns a Converter for a repeated field backed by
+// the Go slice type t, whose elements are converted by elem after applying
+// toPB, and by fromPB after being converted by elem. The transforms must
+// preserve the Go type of the element, such as trimming whitespace from
+// every string in the list.
+func MappingListConverter(t reflect.Type, elem Converter, toPB, fromPB func(reflect.Value) reflect.Value) Converter {
+	if t.Kind() != reflect.Slice {
+		panic(fmt.Sprintf("invalid Go type %v for mapping list: want slice", t))
+	}
+	return &listConverter{goType: t, c: &mappingConverter{elem, toPB, fromPB}}
+}
+
+// NewElementListConverter returns a Converter for a repeated field backed by
+// the Go slice type t, whose elements are converted by elem. Unlike the
+// Converters returned by NewConverter, elem may be any Converter, such as
+// a TryConverter whose conversions can fail; see TryPBValuesOf.
+func NewElementListConverter(t reflect.Type, elem Converter) Converter {
+	if t.Kind() != reflect.Slice {
+		panic(fmt.Sprintf("invalid Go type %v for element list: want slice", t))
+	}
+	return &listConverter{goType: t, c: elem}
+}
+
+// NewIntegerSliceConverter returns a TryConverter between a []T of any
+// integer type T, such as []int8 or []uint, and a repeated integer field of
+// any kind, such as repeated sint32 or repeated fixed64. The elements are
+// converted without reflection. Elements that do not fit in the field kind
+// are reported by TryPBValueOf, and elements that do not fit in T are
+// reported by TryGoValueOf, as ListErrors.
+func NewIntegerSliceConverter[T constraints.Integer](fd pref.FieldDescriptor) TryConverter {
+	t := reflect.TypeOf([]T(nil))
+	if !fd.IsList() {
+		panic(fmt.Sprintf("invalid field %v for integer slice: want repeated field", fd.FullName()))
+	}
+	var signed, is32 bool
+	switch fd.Kind() {
+	case pref.Int32Kind, pref.Sint32Kind, pref.Sfixed32Kind:
+		signed, is32 = true, true
+	case pref.Int64Kind, pref.Sint64Kind, pref.Sfixed64Kind:
+		signed = true
+	case pref.Uint32Kind, pref.Fixed32Kind:
+		is32 = true
+	case pref.Uint64Kind, pref.Fixed64Kind:
+	default:
+		panic(fmt.Sprintf("invalid field %v for integer slice: got %v, want integer kind", fd.FullName(), fd.Kind()))
+	}
+	nativeType := reflect.SliceOf(scalarGoType(fd.Kind()))
+	pb := ConverterOptions{}.newListConverter(nativeType, fd)
+
+	// toPB and toGo convert a single element, reporting values that
+	// do not round-trip or whose sign changes.
+	toPB := func(x T) (pref.Value, error) {
+		if signed {
+			n := int64(x)
+			if T(n) != x || (n < 0) != (x < 0) || is32 && int64(int32(n)) != n {
+				return pref.Value{}, fmt.Errorf("value %d overflows %v", x, fd.Kind())
+			}
+			if is32 {
+				return pref.ValueOfInt32(int32(n)), nil
+			}
+			return pref.ValueOfInt64(n), nil
+		}
+		u := uint64(x)
+		if x < 0 || is32 && uint64(uint32(u)) != u {
+			return pref.Value{}, fmt.Errorf("value %d overflows %v", x, fd.Kind())
+		}
+		if is32 {
+			return pref.ValueOfUint32(uint32(u)), nil
+		}
+		return pref.ValueOfUint64(u), nil
+	}
+	toGo := func(v pref.Value) (T, error) {
+		if signed {
+			n := v.Int()
+			if x := T(n); int64(x) == n && (x < 0) == (n < 0) {
+				return x, nil
+			}
+			return 0, fmt.Errorf("value %d overflows %v", n, t.Elem())
+		}
+		u := v.Uint()
+		if x := T(u); uint64(x) == u && x >= 0 {
+			return x, nil
+		}
+		return 0, fmt.Errorf("value %d overflows %v", u, t.Elem())
+	}
+
+	return &funcConverter{
+		goType: t,
+		pb:     pb,
+		toPB: func(v reflect.Value) (pref.Value, error) {
+			s := v.Interface().([]T)
+			if s == nil {
+				return pb.PBValueOf(reflect.Zero(nativeType)), nil
+			}
+			pv := pb.New()
+			list := pv.List()
+			var errs ListErrors
+			for i, x := range s {
+				ev, err := toPB(x)
+				if err != nil {
+					errs = append(errs, &ListError{i, err})
+					continue
+				}
+				list.Append(ev)
+			}
+			if len(errs) > 0 {
+				return pref.Value{}, errs
+			}
+			return pv, nil
+		},
+		toGo: func(v pref.Value) (reflect.Value, error) {
+			if pb.GoValueOf(v).IsNil() {
+				return reflect.Zero(t), nil
+			}
+			list := v.List()
+			s := make([]T, list.Len())
+			var errs ListErrors
+			for i := range s {
+				x, err := toGo(list.Get(i))
+				if err != nil {
+					errs = append(errs, &ListError{i, err})
+					continue
+				}
+				s[i] = x
+			}
+			if len(errs) > 0 {
+				return reflect.Value{}, errs
+			}
+			return reflect.ValueOf(s), nil
+		},
+	}
+}
+
+// ListErrors is the error returned by TryPBValuesOf,
+// holding the errors of every element that failed to convert.
+type ListErrors []*ListError
+
+// ListError is the error of converting the element at Index of a list.
+type ListError struct {
+	Index int
+	Err   error
+}
+
+func (e *ListError) Error() string {
+	return fmt.Sprintf("element %d: %v", e.Index, e.Err)
+}
+
+func (e *ListError) Unwrap() error {
+	return e.Err
+}
+
+func (e ListErrors) Error() string {
+	ss := make([]string, len(e))
+	for i, err := range e {
+		ss[i] = err.Error()
+	}
+	return strings.Join(ss, "; ")
+}
+
+// ComposeConverter returns a Converter that applies the Go-side transforms
+// pre before base.PBValueOf and post after base.GoValueOf, such as trimming
+// or normalizing a string. A nil transform leaves values unchanged.
+// As for MappingListConverter, the transforms must preserve the Go type
+// of the value. The returned Converter is a TryConverter whose Try methods
+// call those of base if it is a TryConverter, so errors that base reports
+// are not turned into panics.
+func ComposeConverter(base Converter, pre, post func(reflect.Value) reflect.Value) Converter {
+	identity := func(v reflect.Value) reflect.Value { return v }
+	if pre == nil {
+		pre = identity
+	}
+	if post == nil {
+		post = identity
+	}
+	return &mappingConverter{base, pre, post}
+}
+
+// mappingConverter applies Go-side transforms around another Converter.
+type mappingConverter struct {
+	Converter
+	toPB, fromPB func(reflect.Value) reflect.Value
+}
+
+func (c *mappingConverter) TryPBValueOf(v reflect.Value) (pref.Value, error) {
+	return tryPBValueOf(c.Converter, c.toPB(v))
+}
+
+func (c *mappingConverter) TryGoValueOf(v pref.Value) (reflect.Value, error) {
+	rv, err := tryGoValueOf(c.Converter, v)
+	if err != nil {
+		return reflect.Value{}, err
+	}
+	return c.fromPB(rv), nil
+}
+
+func (c *mappingConverter) PBValueOf(v reflect.Value) pref.Value {
+	return c.Converter.PBValueOf(c.toPB(v))
+}
+
+func (c *mappingConverter) GoValueOf(v pref.Value) reflect.Value {
+	return c.fromPB(c.Converter.GoValueOf(v))
+}
+
+type listConverter struct {
+	goType    reflect.Type // []T
+	c         Converter
+	nilAbsent bool // a nil slice converts to an invalid list
+	intern    bool // ConvertSliceFromPB deduplicates string elements
+}
+
+func (c *listConverter) PBValueOf(v reflect.Value) pref.Value {
+	if v.Type() != c.goType {
+		panic(fmt.Sprintf("invalid type: got %v, want %v", v.Type(), c.goType))
+	}
+	if c.nilAbsent && v.IsNil() {
+		return c.Zero()
+	}
+	pv := reflect.New(c.goType)
+	pv.Elem().Set(v)
+	return pref.ValueOfList(&listReflect{pv, c.c})
+}
+
+// TryPBValuesOf converts every element of the slice v, which may fail if
+// the element Converter is a TryConverter. Rather than stopping at the first
+// failure, it converts all elements and reports every failure in ListErrors,
+// in which case the values of the failed elements are invalid.
+func (c *listConverter) TryPBValuesOf(v reflect.Value) ([]pref.Value, error) {
+	if v.Type() != c.goType {
+		panic(fmt.Sprintf("invalid type: got %v, want %v", v.Type(), c.goType))
+	}
+	return tryPBValuesOf(c.c, v)
+}
+
+func (c *listConverter) GoValueOf(v pref.Value) reflect.Value {
+	rv := v.List().(*listReflect).v
+	if rv.IsNil() {
+		return reflect.Zero(c.goType)
+	}
+	return rv.Elem()
+}
+
+// GoValueOfPresence is like GoValueOf, but also reports whether the list
+// is considered set. A nil slice is reported as not set, as distinct from
+// an empty one, only if ConverterOptions.NilListAbsent was specified.
+func (c *listConverter) GoValueOfPresence(v pref.Value) (reflect.Value, bool) {
+	rv := c.GoValueOf(v)
+	return rv, !c.nilAbsent || !rv.IsNil()
+}
+
+func (c *listConverter) IsValidPB(v pref.Value) bool {
+	list, ok := v.Interface().(*listReflect)
+	if !ok {
+		return false
+	}
+	return list.v.Type().Elem() == c.goType
+}
+
+func (c *listConverter) IsValidGo(v reflect.Value) bool {
+	return v.IsValid() && v.Type() == c.goType
+}
+
+func (c *listConverter) New() pref.Value {
+	return pref.ValueOfList(&listReflect{reflect.New(c.goType), c.c})
+}
+
+// NewFromGo returns a new mutable list populated with a deep copy of v.
+func (c *listConverter) NewFromGo(v reflect.Value) pref.Value {
+	nv := c.New()
+	appendClone(nv.List(), c.PBValueOf(v).List())
+	return nv
+}
+
+func (c *listConverter) Zero() pref.Value {
+	return pref.ValueOfList(&listReflect{reflect.Zero(reflect.PtrTo(c.goType)), c.c})
+}
+
+func (c *listConverter) IsZeroValue(v pref.Value) bool {
+	return v.List().Len() == 0
+}
+
+func (c *listConverter) Pretty(v pref.Value) string {
+	return prettyList(v.List(), c.c.Pretty)
+}
+
+func (c *listConverter) FieldNumber() protowire.Number {
+	return c.c.FieldNumber()
+}
+
+type listPtrConverter struct {
+	goType reflect.Type // *[]T
+	c      Converter
+}
+
+func (c *listPtrConverter) PBValueOf(v reflect.Value) pref.Value {
+	if v.Type() != c.goType {
+		panic(fmt.Sprintf("invalid type: got %v, want %v", v.Type(), c.goType))
+	}
+	return pref.ValueOfList(&listReflect{v, c.c})
+}
+
+// TryPBValuesOf is like listConverter.TryPBValuesOf,
+// where a nil pointer has no elements.
+func (c *listPtrConverter) TryPBValuesOf(v reflect.Value) ([]pref.Value, error) {
+	if v.Type() != c.goType {
+		panic(fmt.Sprintf("invalid type: got %v, want %v", v.Type(), c.goType))
+	}
+	if v.IsNil() {
+		return nil, nil
+	}
+	return tryPBValuesOf(c.c, v.Elem())
+}
+
+func (c *listPtrConverter) GoValueOf(v pref.Value) reflect.Value {
+	return v.List().(*listReflect).v
+}
+
+func (c *listPtrConverter) IsValidPB(v pref.Value) bool {
+	list, ok := v.Interface().(*listReflect)
+	if !ok {
+		return false
+	}
+	return list.v.Type() == c.goType
+}
+
+func (c *listPtrConverter) IsValidGo(v reflect.Value) bool {
+	return v.IsValid() && v.Type() == c.goType
+}
+
+func (c *listPtrConverter) New() pref.Value {
+	return c.PBValueOf(reflect.New(c.goType.Elem()))
+}
+
+// NewFromGo returns a new mutable list populated with a deep copy of v.
+func (c *listPtrConverter) NewFromGo(v reflect.Value) pref.Value {
+	nv := c.New()
+	appendClone(nv.List(), c.PBValueOf(v).List())
+	return nv
+}
+
+func (c *listPtrConverter) Zero() pref.Value {
+	return c.PBValueOf(reflect.Zero(c.goType))
+}
+
+func (c *listPtrConverter) IsZeroValue(v pref.Value) bool {
+	return v.List().Len() == 0
+}
+
+func (c *listPtrConverter) Pretty(v pref.Value) string {
+	return prettyList(v.List(), c.c.Pretty)
+}
+
+func (c *listPtrConverter) FieldNumber() protowire.Number {
+	return c.c.FieldNumber()
+}
+
+func tryPBValuesOf(c Converter, v reflect.Value) ([]pref.Value, error) {
+	vs := make([]pref.Value, v.Len())
+	var errs ListErrors
+	for i := range vs {
+		pv, err := tryPBValueOf(c, v.Index(i))
+		if err != nil {
+			errs = append(errs, &ListError{i, err})
+			continue
+		}
+		vs[i] = pv
+	}
+	if len(errs) > 0 {
+		return vs, errs
+	}
+	return vs, nil
+}
+
+// appendClone appends a deep copy of every element of src to dst.
+func appendClone(dst, src pref.List) {
+	for i := 0; i < src.Len(); i++ {
+		dst.Append(cloneValue(src.Get(i)))
+	}
+}
+
+type listReflect struct {
+	v    reflect.Value // *[]T
+	conv Converter
+}
+
+func (ls *listReflect) Len() int {
+	if ls.v.IsNil() {
+		return 0
+	}
+	return ls.v.Elem().Len()
+}
+func (ls *listReflect) Get(i int) pref.Value {
+	return ls.conv.PBValueOf(ls.v.Elem().Index(i))
+}
+func (ls *listReflect) Set(i int, v pref.Value) {
+	ls.v.Elem().Index(i).Set(ls.conv.GoValueOf(v))
+}
+func (ls *listReflect) Append(v pref.Value) {
+	ls.v.Elem().Set(reflect.Append(ls.v.Elem(), ls.conv.GoValueOf(v)))
+}
+func (ls *listReflect) AppendMutable() pref.Value {
+	if _, ok := ls.conv.(*messageConverter); !ok {
+		panic("invalid AppendMutable on list with non-message type")
+	}
+	v := ls.NewElement()
+	ls.Append(v)
+	return v
+}
+func (ls *listReflect) Truncate(i int) {
+	ls.v.Elem().Set(ls.v.Elem().Slice(0, i))
+}
+func (ls *listReflect) NewElement() pref.Value {
+	return ls.conv.New()
+}
+func (ls *listReflect) IsValid() bool {
+	return !ls.v.IsNil()
+}
+func (ls *listReflect) protoUnwrap() interface{} {
+	return ls.v.Interface()
+}
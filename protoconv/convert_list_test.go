@@ -0,0 +1,280 @@
+package protoconv_test
+
+import (
+	"errors"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dnscrypt/dnscrypt-proxy/protoconv"
+	pref "google.golang.org/protobuf/reflect/protoreflect"
+)
+
+func TestMappingListConverter(t *testing.T) {
+	trim := func(v reflect.Value) reflect.Value { return reflect.ValueOf(strings.TrimSpace(v.String())) }
+	upper := func(v reflect.Value) reflect.Value { return reflect.ValueOf(strings.ToUpper(v.String())) }
+	c := protoconv.MappingListConverter(reflect.TypeOf([]string(nil)), protoconv.NewConverter(stringType, field("s")), trim, upper)
+
+	list := c.PBValueOf(reflect.ValueOf([]string{" a ", "b\n", "\tc"})).List()
+	var got []string
+	for i := 0; i < list.Len(); i++ {
+		got = append(got, list.Get(i).String())
+	}
+	if want := []string{"a", "b", "c"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("PBValueOf elements = %q, want %q", got, want)
+	}
+
+	list.Set(0, pref.ValueOfString("x"))
+	if back := c.GoValueOf(pref.ValueOfList(list)).Interface().([]string); back[0] != "X" {
+		t.Errorf("element set to %q = %q, want %q", "x", back[0], "X")
+	}
+}
+
+// codePoints is a named slice of runes.
+type codePoints []rune
+
+func TestRuneListConverter(t *testing.T) {
+	tests := []struct {
+		name string
+		in   interface{}
+		want []rune
+	}{
+		{"ascii", []rune("dns"), []rune("dns")},
+		{"multibyte", []rune("héllo, 世界 🌍"), []rune("héllo, 世界 🌍")},
+		{"empty", []rune{}, nil},
+		{"named", codePoints("ß→∞"), []rune("ß→∞")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := protoconv.NewConverter(reflect.TypeOf(tt.in), field("ri32"))
+			list := c.PBValueOf(reflect.ValueOf(tt.in)).List()
+			var got []rune
+			for i := 0; i < list.Len(); i++ {
+				got = append(got, rune(list.Get(i).Int()))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("PBValueOf(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+
+			dst := c.New().List()
+			for _, r := range tt.want {
+				dst.Append(pref.ValueOfInt32(r))
+			}
+			back := c.GoValueOf(pref.ValueOfList(dst))
+			if back.Type() != reflect.TypeOf(tt.in) || back.Len() != len(tt.want) {
+				t.Fatalf("GoValueOf = %#v, want %q", back.Interface(), tt.want)
+			}
+			for i, r := range tt.want {
+				if got := rune(back.Index(i).Int()); got != r {
+					t.Errorf("GoValueOf element %d = %q, want %q", i, got, r)
+				}
+			}
+		})
+	}
+}
+
+func TestElementListConverterErrors(t *testing.T) {
+	elem := protoconv.EnumNameOptions{}.New(stringType, field("re"))
+	c := protoconv.NewElementListConverter(reflect.TypeOf([]string(nil)), elem).(valuesConverter)
+	tests := []struct {
+		name        string
+		in          []string
+		wantIndexes []int
+	}{
+		{"valid", []string{"MY_VALUE", "OTHER"}, nil},
+		{"empty", []string{}, nil},
+		{"several invalid", []string{"MY_VALUE", "bad", "OTHER", "worse", ""}, []int{1, 3, 4}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vs, err := c.TryPBValuesOf(reflect.ValueOf(tt.in))
+			if len(vs) != len(tt.in) {
+				t.Fatalf("TryPBValuesOf returned %d values, want %d", len(vs), len(tt.in))
+			}
+			var got []int
+			if err != nil {
+				errs, ok := err.(protoconv.ListErrors)
+				if !ok {
+					t.Fatalf("TryPBValuesOf error = %T, want ListErrors", err)
+				}
+				for _, e := range errs {
+					got = append(got, e.Index)
+				}
+			}
+			if !reflect.DeepEqual(got, tt.wantIndexes) {
+				t.Errorf("failed indexes = %v, want %v", got, tt.wantIndexes)
+			}
+			for i, v := range vs {
+				failed := false
+				for _, j := range tt.wantIndexes {
+					failed = failed || i == j
+				}
+				if v.IsValid() == failed {
+					t.Errorf("value %d valid = %v, want %v", i, v.IsValid(), !failed)
+				}
+			}
+		})
+	}
+}
+
+// fixedIDs is a defined slice type over uint64.
+type fixedIDs []uint64
+
+func TestUint64ListConverter(t *testing.T) {
+	tests := []struct {
+		name string
+		in   interface{}
+	}{
+		{"slice", []uint64{0, 1, 1 << 63, math.MaxUint64}},
+		{"defined slice", fixedIDs{math.MaxUint64, 42}},
+		{"pointer to slice", &[]uint64{math.MaxUint64 - 1}},
+		{"empty", []uint64{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := protoconv.NewConverter(reflect.TypeOf(tt.in), field("rf64"))
+			want := reflect.Indirect(reflect.ValueOf(tt.in))
+			list := c.PBValueOf(reflect.ValueOf(tt.in)).List()
+			if list.Len() != want.Len() {
+				t.Fatalf("PBValueOf has %d elements, want %d", list.Len(), want.Len())
+			}
+			for i := 0; i < list.Len(); i++ {
+				if got := list.Get(i).Uint(); got != want.Index(i).Uint() {
+					t.Errorf("element %d = %d, want %d", i, got, want.Index(i).Uint())
+				}
+			}
+			if got := c.GoValueOf(pref.ValueOfList(list)).Interface(); !reflect.DeepEqual(got, tt.in) {
+				t.Errorf("GoValueOf = %v, want %v", got, tt.in)
+			}
+		})
+	}
+}
+
+func TestIntegerSliceConverter(t *testing.T) {
+	tests := []struct {
+		name    string
+		c       protoconv.TryConverter
+		in      interface{}
+		want    []uint64 // the field elements, as by Uint or Int
+		wantErr bool
+	}{
+		{"int8 to int32", protoconv.NewIntegerSliceConverter[int8](field("ri32")), []int8{math.MinInt8, 5, math.MaxInt8}, []uint64{1<<64 - 128, 5, 127}, false},
+		{"uint32 to fixed64", protoconv.NewIntegerSliceConverter[uint32](field("rf64")), []uint32{1, 1 << 31}, []uint64{1, 1 << 31}, false},
+		{"uint64 to fixed64", protoconv.NewIntegerSliceConverter[uint64](field("rf64")), []uint64{math.MaxUint64}, []uint64{math.MaxUint64}, false},
+		{"nil", protoconv.NewIntegerSliceConverter[int8](field("ri32")), []int8(nil), nil, false},
+		{"int64 too large for uint32", protoconv.NewIntegerSliceConverter[int64](field("ru32")), []int64{1, 1 << 40}, nil, true},
+		{"negative to uint32", protoconv.NewIntegerSliceConverter[int64](field("ru32")), []int64{-1}, nil, true},
+		{"uint64 too large for int32", protoconv.NewIntegerSliceConverter[uint64](field("ri32")), []uint64{math.MaxUint64}, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v, err := tt.c.TryPBValueOf(reflect.ValueOf(tt.in))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("TryPBValueOf(%v) error = %v, want error %v", tt.in, err, tt.wantErr)
+			}
+			if err != nil {
+				var errs protoconv.ListErrors
+				if !errors.As(err, &errs) {
+					t.Errorf("TryPBValueOf(%v) error = %T, want ListErrors", tt.in, err)
+				}
+				return
+			}
+			var got []uint64
+			for i := 0; i < v.List().Len(); i++ {
+				switch x := v.List().Get(i).Interface().(type) {
+				case int32:
+					got = append(got, uint64(x))
+				default:
+					got = append(got, v.List().Get(i).Uint())
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("TryPBValueOf(%v) = %v, want %v", tt.in, got, tt.want)
+			}
+			back, err := tt.c.TryGoValueOf(v)
+			if err != nil || !reflect.DeepEqual(back.Interface(), tt.in) {
+				t.Errorf("TryGoValueOf = %v, %v, want %v", back, err, tt.in)
+			}
+		})
+	}
+}
+
+func TestIntegerSliceConverterInbound(t *testing.T) {
+	c := protoconv.NewIntegerSliceConverter[int8](field("ri32"))
+	list := c.New().List()
+	for _, n := range []int32{1, 300, -2, -129} {
+		list.Append(pref.ValueOfInt32(n))
+	}
+	_, err := c.TryGoValueOf(pref.ValueOfList(list))
+	var errs protoconv.ListErrors
+	if !errors.As(err, &errs) || len(errs) != 2 || errs[0].Index != 1 || errs[1].Index != 3 {
+		t.Errorf("TryGoValueOf error = %v, want errors for elements 1 and 3", err)
+	}
+
+	u := protoconv.NewIntegerSliceConverter[uint8](field("ri32"))
+	list = u.New().List()
+	list.Append(pref.ValueOfInt32(-1))
+	if _, err := u.TryGoValueOf(pref.ValueOfList(list)); err == nil {
+		t.Error("TryGoValueOf of -1 into uint8 succeeded, want error")
+	}
+}
+
+func TestComposeConverter(t *testing.T) {
+	norm := func(v reflect.Value) reflect.Value {
+		return reflect.ValueOf(strings.ToLower(strings.TrimSpace(v.String())))
+	}
+	base := protoconv.NewConverter(stringType, field("s"))
+	tests := []struct {
+		name      string
+		pre, post func(reflect.Value) reflect.Value
+		in        string // converted to the field
+		wantPB    string
+		out       string // converted from the field
+		wantGo    string
+	}{
+		{"both", norm, norm, "  HeLLo ", "hello", " WORLD", "world"},
+		{"pre only", norm, nil, " A ", "a", " B ", " B "},
+		{"post only", nil, norm, " A ", " A ", " B ", "b"},
+		{"neither", nil, nil, " A ", " A ", " B ", " B "},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := protoconv.ComposeConverter(base, tt.pre, tt.post)
+			if got := c.PBValueOf(reflect.ValueOf(tt.in)).String(); got != tt.wantPB {
+				t.Errorf("PBValueOf(%q) = %q, want %q", tt.in, got, tt.wantPB)
+			}
+			if got := c.GoValueOf(pref.ValueOfString(tt.out)).String(); got != tt.wantGo {
+				t.Errorf("GoValueOf(%q) = %q, want %q", tt.out, got, tt.wantGo)
+			}
+		})
+	}
+}
+
+func TestComposeConverterTry(t *testing.T) {
+	day := func(v reflect.Value) reflect.Value {
+		return reflect.ValueOf(v.Interface().(time.Time).Truncate(24 * time.Hour))
+	}
+	c := protoconv.ComposeConverter(protoconv.NewUnixStringConverter(field("s")), nil, day).(protoconv.TryConverter)
+	tests := []struct {
+		name    string
+		in      string
+		want    time.Time
+		wantErr bool
+	}{
+		{"valid", "1625142600", time.Date(2021, 7, 1, 0, 0, 0, 0, time.UTC), false},
+		{"invalid", "soon", time.Time{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := c.TryGoValueOf(pref.ValueOfString(tt.in))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("TryGoValueOf(%q) error = %v, want error %v", tt.in, err, tt.wantErr)
+			}
+			if err == nil && !got.Interface().(time.Time).Equal(tt.want) {
+				t.Errorf("TryGoValueOf(%q) = %v, want %v", tt.in, got, tt.want)
+			}
+		})
+	}
+}
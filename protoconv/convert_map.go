@@ -0,0 +1,604 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package protoconv
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+
+	"google.golang.org/protobuf/encoding/protowire"
+	pref "google.golang.org/protobuf/reflect/protoreflect"
+)
+
+type mapConverter struct {
+	goType           reflect.Type // map[K]V
+	keyConv, valConv Converter
+	maxEntries       int // if positive, the maximum length of a converted map
+	truncate         bool
+	num              protowire.Number
+}
+
+func newMapConverter(t reflect.Type, fd pref.FieldDescriptor) *mapConverter {
+	return ConverterOptions{}.newMapConverter(t, fd)
+}
+
+// newMapConverter returns a Converter for a map field backed by a Go map,
+// whose keys and values are matched with the key and value fields of the
+// map entry as by newSingularConverter. Integer keys of any kind, such as
+// the int32 keys of a map[int32]string backing a map<sint32, string> field,
+// round-trip over the full range of the key type. Values of the Go int and
+// uint types may back any integer field, such as a map[string]int backing
+// a map<string, int32> field, as by newIntConverter.
+func (o ConverterOptions) newMapConverter(t reflect.Type, fd pref.FieldDescriptor) *mapConverter {
+	if t.Kind() != reflect.Map {
+		panic(fmt.Sprintf("invalid Go type %v for field %v", t, fd.FullName()))
+	}
+	var valConv Converter
+	if k := t.Elem().Kind(); (k == reflect.Int || k == reflect.Uint) && isIntegerKind(fd.MapValue().Kind()) {
+		valConv = newIntConverter(t.Elem(), fd.MapValue())
+	} else {
+		valConv = o.newSingularConverter(t.Elem(), fd.MapValue())
+	}
+	return &mapConverter{
+		goType:     t,
+		keyConv:    o.newSingularConverter(t.Key(), fd.MapKey()),
+		valConv:    valConv,
+		maxEntries: o.MaxMapEntries,
+		truncate:   o.TruncateMaps,
+		num:        fd.Number(),
+	}
+}
+
+// NewMapConverterWith returns a Converter between the Go map type t and
+// a map field, whose keys and values are converted by keyConv and valConv.
+// This composes custom conversions, such as of a decimal string,
+// into maps. A nil keyConv or valConv is replaced by the Converter
+// that NewConverter would use for the map.
+func NewMapConverterWith(t reflect.Type, keyConv, valConv Converter, fd pref.FieldDescriptor) Converter {
+	if t.Kind() != reflect.Map || !fd.IsMap() {
+		panic(fmt.Sprintf("invalid Go type %v for field %v", t, fd.FullName()))
+	}
+	if keyConv == nil {
+		keyConv = newSingularConverter(t.Key(), fd.MapKey())
+	}
+	if valConv == nil {
+		valConv = newSingularConverter(t.Elem(), fd.MapValue())
+	}
+	if !keyConv.IsValidGo(reflect.Zero(t.Key())) || !valConv.IsValidGo(reflect.Zero(t.Elem())) {
+		panic(fmt.Sprintf("invalid key or value Converter for Go type %v of field %v", t, fd.FullName()))
+	}
+	return &mapConverter{goType: t, keyConv: keyConv, valConv: valConv, num: fd.Number()}
+}
+
+func (c *mapConverter) PBValueOf(v reflect.Value) pref.Value {
+	pv, err := c.limitedPBValueOf(v)
+	if err != nil {
+		panic(err)
+	}
+	return pv
+}
+
+// limitedPBValueOf wraps v, first applying the maximum number of entries.
+func (c *mapConverter) limitedPBValueOf(v reflect.Value) (pref.Value, error) {
+	if v.Type() != c.goType {
+		panic(fmt.Sprintf("invalid type: got %v, want %v", v.Type(), c.goType))
+	}
+	if c.maxEntries > 0 && v.Len() > c.maxEntries {
+		if !c.truncate {
+			return pref.Value{}, fmt.Errorf("map of Go type %v has %d entries, exceeding the maximum of %d", c.goType, v.Len(), c.maxEntries)
+		}
+		v = c.truncated(v)
+	}
+	return pref.ValueOfMap(&mapReflect{v, c.keyConv, c.valConv}), nil
+}
+
+// truncated returns a copy of v holding the maxEntries entries
+// with the smallest keys, in the order of genericKeyOrder.
+func (c *mapConverter) truncated(v reflect.Value) reflect.Value {
+	keys := v.MapKeys()
+	sort.Slice(keys, func(i, j int) bool {
+		return genericKeyOrder(c.keyConv.PBValueOf(keys[i]).MapKey(), c.keyConv.PBValueOf(keys[j]).MapKey())
+	})
+	m := reflect.MakeMapWithSize(c.goType, c.maxEntries)
+	for _, k := range keys[:c.maxEntries] {
+		m.SetMapIndex(k, v.MapIndex(k))
+	}
+	return m
+}
+
+// TryPBValueOf is like PBValueOf, but reports maps exceeding the maximum
+// number of entries rather than panicking. It also reports nil pointer values
+// of a message-valued map, such as map[string]*Foo, since a map field cannot
+// hold an absent message. The error names every such key, in sorted order.
+// Values that a TryConverter for the values fails to convert, such as
+// an int overflowing an int32 field, are reported with the smallest
+// offending key. PBValueOf does not check the values, since it converts
+// them lazily.
+func (c *mapConverter) TryPBValueOf(v reflect.Value) (pref.Value, error) {
+	pv, err := c.limitedPBValueOf(v)
+	if err != nil {
+		return pref.Value{}, err
+	}
+	if err := c.checkValues(pv.Map().(*mapReflect).v); err != nil {
+		return pref.Value{}, err
+	}
+	if _, ok := c.valConv.(*messageConverter); !ok || c.goType.Elem().Kind() != reflect.Ptr {
+		return pv, nil
+	}
+	var keys []string
+	for iter := pv.Map().(*mapReflect).v.MapRange(); iter.Next(); {
+		if iter.Value().IsNil() {
+			keys = append(keys, fmt.Sprint(iter.Key().Interface()))
+		}
+	}
+	if len(keys) > 0 {
+		sort.Strings(keys)
+		return pref.Value{}, fmt.Errorf("invalid nil value for keys %q of Go type %v: map values must be present", keys, c.goType)
+	}
+	return pv, nil
+}
+
+// checkValues reports the first value of v, in order of its keys, that
+// the Converter for the values reports an error for, if it is a TryConverter.
+func (c *mapConverter) checkValues(v reflect.Value) error {
+	tc, ok := c.valConv.(TryConverter)
+	if !ok {
+		return nil
+	}
+	var key pref.MapKey
+	var firstErr error
+	for iter := v.MapRange(); iter.Next(); {
+		if _, err := tc.TryPBValueOf(iter.Value()); err != nil {
+			k := c.keyConv.PBValueOf(iter.Key()).MapKey()
+			if firstErr == nil || genericKeyOrder(k, key) {
+				key, firstErr = k, err
+			}
+		}
+	}
+	if firstErr != nil {
+		return fmt.Errorf("invalid value for key %v of Go type %v: %w", key, c.goType, firstErr)
+	}
+	return nil
+}
+
+func (c *mapConverter) TryGoValueOf(v pref.Value) (reflect.Value, error) {
+	return c.GoValueOf(v), nil
+}
+
+func (c *mapConverter) GoValueOf(v pref.Value) reflect.Value {
+	return v.Map().(*mapReflect).v
+}
+
+func (c *mapConverter) IsValidPB(v pref.Value) bool {
+	mapv, ok := v.Interface().(*mapReflect)
+	if !ok {
+		return false
+	}
+	return mapv.v.Type() == c.goType
+}
+
+func (c *mapConverter) IsValidGo(v reflect.Value) bool {
+	return v.IsValid() && v.Type() == c.goType
+}
+
+func (c *mapConverter) New() pref.Value {
+	return c.PBValueOf(reflect.MakeMap(c.goType))
+}
+
+// NewFromGo returns a new mutable map populated with a deep copy of v.
+func (c *mapConverter) NewFromGo(v reflect.Value) pref.Value {
+	nv := c.New()
+	dst := nv.Map()
+	c.PBValueOf(v).Map().Range(func(k pref.MapKey, v pref.Value) bool {
+		dst.Set(k, cloneValue(v))
+		return true
+	})
+	return nv
+}
+
+func (c *mapConverter) Zero() pref.Value {
+	return c.PBValueOf(reflect.Zero(c.goType))
+}
+
+func (c *mapConverter) IsZeroValue(v pref.Value) bool {
+	return v.Map().Len() == 0
+}
+
+func (c *mapConverter) Pretty(v pref.Value) string {
+	return prettyMap(v.Map(), c.valConv.Pretty)
+}
+
+func (c *mapConverter) FieldNumber() protowire.Number {
+	return c.num
+}
+
+type mapReflect struct {
+	v       reflect.Value // map[K]V
+	keyConv Converter
+	valConv Converter
+}
+
+func (ms *mapReflect) Len() int {
+	return ms.v.Len()
+}
+func (ms *mapReflect) Has(k pref.MapKey) bool {
+	rk := ms.keyConv.GoValueOf(k.Value())
+	rv := ms.v.MapIndex(rk)
+	return rv.IsValid()
+}
+func (ms *mapReflect) Get(k pref.MapKey) pref.Value {
+	rk := ms.keyConv.GoValueOf(k.Value())
+	rv := ms.v.MapIndex(rk)
+	if !rv.IsValid() {
+		return pref.Value{}
+	}
+	return ms.valueOf(rv)
+}
+func (ms *mapReflect) Set(k pref.MapKey, v pref.Value) {
+	rk := ms.keyConv.GoValueOf(k.Value())
+	rv := ms.valConv.GoValueOf(v)
+	ms.v.SetMapIndex(rk, rv)
+}
+func (ms *mapReflect) Clear(k pref.MapKey) {
+	rk := ms.keyConv.GoValueOf(k.Value())
+	ms.v.SetMapIndex(rk, reflect.Value{})
+}
+
+// Mutable panics for non-pointer message values, such as those of
+// a map[string]Foo, since mutations to the copy it would return
+// cannot be written back to the map.
+func (ms *mapReflect) Mutable(k pref.MapKey) pref.Value {
+	mc, ok := ms.valConv.(*messageConverter)
+	if !ok {
+		panic("invalid Mutable on map with non-message value type")
+	}
+	if mc.isNonPointer() && !mc.byValue {
+		panic(fmt.Sprintf("invalid Mutable on map of Go type %v: non-pointer message values are not addressable", ms.v.Type()))
+	}
+	v := ms.Get(k)
+	if !v.IsValid() {
+		v = ms.NewValue()
+		ms.Set(k, v)
+	}
+	return v
+}
+func (ms *mapReflect) Range(f func(pref.MapKey, pref.Value) bool) {
+	iter := ms.v.MapRange()
+	for iter.Next() {
+		k := ms.keyConv.PBValueOf(iter.Key()).MapKey()
+		v := ms.valueOf(iter.Value())
+		if !f(k, v) {
+			return
+		}
+	}
+}
+
+// valueOf converts a map value obtained from MapIndex or a map iterator.
+// Such values are not addressable, so non-pointer message values are
+// copied once into addressable storage that the resulting message wraps.
+// Mutations to that message are not reflected in the map; use Set to
+// store a modified message.
+func (ms *mapReflect) valueOf(rv reflect.Value) pref.Value {
+	if mc, ok := ms.valConv.(*messageConverter); ok && mc.isNonPointer() && !mc.byValue {
+		pv := reflect.New(rv.Type())
+		pv.Elem().Set(rv)
+		return pref.ValueOfMessage(mc.messageOf(pv))
+	}
+	return ms.valConv.PBValueOf(rv)
+}
+func (ms *mapReflect) NewValue() pref.Value {
+	return ms.valConv.New()
+}
+func (ms *mapReflect) IsValid() bool {
+	return !ms.v.IsNil()
+}
+func (ms *mapReflect) protoUnwrap() interface{} {
+	return ms.v.Interface()
+}
+
+var syncMapType = reflect.TypeOf((*sync.Map)(nil))
+
+// NewSyncMapConverter returns a TryConverter between a *sync.Map and
+// a map field. Since sync.Map is untyped, the Go types of its keys and
+// values must be specified, and entries of any other type are reported
+// by TryPBValueOf. Converting from a map field stores its entries in
+// a new sync.Map.
+func NewSyncMapConverter(keyType, valType reflect.Type, fd pref.FieldDescriptor) TryConverter {
+	pb := ConverterOptions{}.newMapConverter(reflect.MapOf(keyType, valType), fd)
+	return &funcConverter{
+		goType: syncMapType,
+		pb:     pb,
+		toPB: func(v reflect.Value) (pref.Value, error) {
+			m := reflect.MakeMap(pb.goType)
+			var err error
+			if sm := v.Interface().(*sync.Map); sm != nil {
+				sm.Range(func(k, v interface{}) bool {
+					rk, rv := reflect.ValueOf(k), reflect.ValueOf(v)
+					if !rk.IsValid() || rk.Type() != keyType || !rv.IsValid() || rv.Type() != valType {
+						err = fmt.Errorf("invalid entry of type (%T, %T) for field %v: want (%v, %v)", k, v, fd.FullName(), keyType, valType)
+						return false
+					}
+					m.SetMapIndex(rk, rv)
+					return true
+				})
+			}
+			if err != nil {
+				return pref.Value{}, err
+			}
+			return pb.PBValueOf(m), nil
+		},
+		toGo: func(v pref.Value) (reflect.Value, error) {
+			sm := new(sync.Map)
+			v.Map().Range(func(k pref.MapKey, v pref.Value) bool {
+				sm.Store(pb.keyConv.GoValueOf(k.Value()).Interface(), pb.valConv.GoValueOf(v).Interface())
+				return true
+			})
+			return reflect.ValueOf(sm), nil
+		},
+	}
+}
+
+var anyMapType = reflect.TypeOf(map[string]interface{}(nil))
+
+// NewAnyMapConverter returns a TryConverter between a map[string]interface{}
+// and a map field with string keys and scalar values, such as
+// map<string, int64>. Every value must have exactly the Go type natively
+// used for the value kind, such as int64 for an int64 field; values of any
+// other type are reported by TryPBValueOf along with their key.
+// Keys are checked in sorted order so that the reported key is deterministic.
+func NewAnyMapConverter(fd pref.FieldDescriptor) TryConverter {
+	if !fd.IsMap() || fd.MapKey().Kind() != pref.StringKind {
+		panic(fmt.Sprintf("invalid field %v for %v: want map with string keys", fd.FullName(), anyMapType))
+	}
+	valType := scalarGoType(fd.MapValue().Kind())
+	if valType == nil {
+		panic(fmt.Sprintf("invalid field %v for %v: got %v values, want scalar", fd.FullName(), anyMapType, fd.MapValue().Kind()))
+	}
+	pb := ConverterOptions{}.newMapConverter(reflect.MapOf(stringType, valType), fd)
+	return &funcConverter{
+		goType: anyMapType,
+		pb:     pb,
+		toPB: func(v reflect.Value) (pref.Value, error) {
+			src := v.Interface().(map[string]interface{})
+			keys := make([]string, 0, len(src))
+			for k := range src {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			m := reflect.MakeMapWithSize(pb.goType, len(src))
+			for _, k := range keys {
+				rv := reflect.ValueOf(src[k])
+				if !rv.IsValid() || rv.Type() != valType {
+					return pref.Value{}, fmt.Errorf("invalid value of type %T for key %q of field %v: want %v", src[k], k, fd.FullName(), valType)
+				}
+				m.SetMapIndex(reflect.ValueOf(k), rv)
+			}
+			return pb.PBValueOf(m), nil
+		},
+		toGo: func(v pref.Value) (reflect.Value, error) {
+			m := make(map[string]interface{}, v.Map().Len())
+			v.Map().Range(func(k pref.MapKey, v pref.Value) bool {
+				m[k.String()] = pb.valConv.GoValueOf(v).Interface()
+				return true
+			})
+			return reflect.ValueOf(m), nil
+		},
+	}
+}
+
+// NewIndexMapConverter returns a TryConverter between a Go map keyed by
+// a signed integer, such as map[int32]string, and a repeated field whose
+// elements are the values of the map in order of their keys. The keys must
+// be the indexes of the list, from zero up to one less than the length of
+// the map; maps with gaps between their keys are reported by TryPBValueOf.
+// Converting from a repeated field keys every element by its index;
+// lists with more elements than the key type can index are reported
+// by TryGoValueOf.
+func NewIndexMapConverter(t reflect.Type, fd pref.FieldDescriptor) TryConverter {
+	if t.Kind() != reflect.Map {
+		panic(fmt.Sprintf("invalid Go type %v for field %v: want map", t, fd.FullName()))
+	}
+	switch t.Key().Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+	default:
+		panic(fmt.Sprintf("invalid Go type %v for field %v: want signed integer keys", t, fd.FullName()))
+	}
+	if !fd.IsList() {
+		panic(fmt.Sprintf("invalid field %v for index map: want repeated field", fd.FullName()))
+	}
+	pb := ConverterOptions{}.newListConverter(reflect.SliceOf(t.Elem()), fd)
+	return &funcConverter{
+		goType: t,
+		pb:     pb,
+		toPB: func(v reflect.Value) (pref.Value, error) {
+			n := v.Len()
+			s := reflect.MakeSlice(reflect.SliceOf(t.Elem()), n, n)
+			for iter := v.MapRange(); iter.Next(); {
+				i := iter.Key().Int()
+				if i < 0 || i >= int64(n) {
+					return pref.Value{}, fmt.Errorf("invalid key %d for field %v: want index in [0, %d)", i, fd.FullName(), n)
+				}
+				s.Index(int(i)).Set(iter.Value())
+			}
+			return pb.PBValueOf(s), nil
+		},
+		toGo: func(v pref.Value) (reflect.Value, error) {
+			s := pb.GoValueOf(v)
+			rv := reflect.MakeMapWithSize(t, s.Len())
+			k := reflect.New(t.Key()).Elem()
+			for i := 0; i < s.Len(); i++ {
+				if k.OverflowInt(int64(i)) {
+					return reflect.Value{}, fmt.Errorf("index %d of field %v overflows key type %v", i, fd.FullName(), t.Key())
+				}
+				k.SetInt(int64(i))
+				rv.SetMapIndex(k, s.Index(i))
+			}
+			return rv, nil
+		},
+	}
+}
+
+// NewEnumKeyMapConverter returns a TryConverter between a Go map keyed by
+// an enum type, such as map[MyEnum]string, and a map field keyed by the
+// number of the enum value, such as map<int32, string> or map<sint32, string>,
+// since map fields cannot have enum keys. The enum type must implement protoreflect.Enum.
+// Keys not declared by the enum are reported by TryGoValueOf, unless
+// unknownAsZero is set, in which case an unknown key converts to the zero
+// enum value, which the enum must declare. Since only one entry can have
+// that key, TryGoValueOf reports more than one unknown key, and an unknown
+// key alongside a key of zero, rather than picking one of their values.
+func NewEnumKeyMapConverter(t reflect.Type, unknownAsZero bool, fd pref.FieldDescriptor) TryConverter {
+	if t.Kind() != reflect.Map {
+		panic(fmt.Sprintf("invalid Go type %v for field %v: want map", t, fd.FullName()))
+	}
+	e, ok := reflect.Zero(t.Key()).Interface().(pref.Enum)
+	if !ok {
+		panic(fmt.Sprintf("invalid Go type %v for field %v: key type does not implement protoreflect.Enum", t, fd.FullName()))
+	}
+	if !fd.IsMap() || scalarGoType(fd.MapKey().Kind()) != int32Type {
+		panic(fmt.Sprintf("invalid field %v for enum keys: want map<int32, V>", fd.FullName()))
+	}
+	ed := e.Descriptor()
+	if unknownAsZero && ed.Values().ByNumber(0) == nil {
+		panic(fmt.Sprintf("invalid Go type %v for field %v: enum %v does not declare zero for unknown keys", t, fd.FullName(), ed.FullName()))
+	}
+	key := &enumConverter{goType: t.Key(), useNumber: usesEnumNumber(t.Key())}
+	pb := ConverterOptions{}.newMapConverter(reflect.MapOf(int32Type, t.Elem()), fd)
+	return &funcConverter{
+		goType: t,
+		pb:     pb,
+		toPB: func(v reflect.Value) (pref.Value, error) {
+			m := reflect.MakeMapWithSize(pb.goType, v.Len())
+			for iter := v.MapRange(); iter.Next(); {
+				n := key.PBValueOf(iter.Key()).Enum()
+				m.SetMapIndex(reflect.ValueOf(int32(n)), iter.Value())
+			}
+			return pb.PBValueOf(m), nil
+		},
+		toGo: func(v pref.Value) (reflect.Value, error) {
+			m := pb.GoValueOf(v)
+			rv := reflect.MakeMapWithSize(t, m.Len())
+			var unknown []int
+			for iter := m.MapRange(); iter.Next(); {
+				n := pref.EnumNumber(iter.Key().Int())
+				if ed.Values().ByNumber(n) == nil {
+					if !unknownAsZero {
+						return reflect.Value{}, fmt.Errorf("invalid key %d for field %v: not a value of enum %v", n, fd.FullName(), ed.FullName())
+					}
+					unknown = append(unknown, int(n))
+					continue
+				}
+				rv.SetMapIndex(key.GoValueOf(pref.ValueOfEnum(n)), iter.Value())
+			}
+			if len(unknown) == 0 {
+				return rv, nil
+			}
+			sort.Ints(unknown)
+			zero := key.GoValueOf(pref.ValueOfEnum(0))
+			if len(unknown) > 1 || rv.MapIndex(zero).IsValid() {
+				return reflect.Value{}, fmt.Errorf("invalid keys %v for field %v: unknown keys collide at the zero value of enum %v", unknown, fd.FullName(), ed.FullName())
+			}
+			rv.SetMapIndex(zero, m.MapIndex(reflect.ValueOf(int32(unknown[0]))))
+			return rv, nil
+		},
+	}
+}
+
+// NewKeyedMessageMapConverter returns a TryConverter between a Go map of
+// messages, such as map[string]*Foo, and a repeated message field, where
+// each message carries its own map key in its singular field named key.
+// The key field must be of a kind valid for map keys.
+// Converting to a repeated field emits the values of the map in order of
+// their keys; TryPBValueOf reports nil values and values whose key field
+// differs from their map key. Converting from a repeated field keys every
+// message by its key field, with later messages replacing earlier ones
+// of the same key.
+func NewKeyedMessageMapConverter(t reflect.Type, key pref.Name, fd pref.FieldDescriptor) TryConverter {
+	if t.Kind() != reflect.Map {
+		panic(fmt.Sprintf("invalid Go type %v for field %v: want map", t, fd.FullName()))
+	}
+	if !fd.IsList() || fd.Message() == nil {
+		panic(fmt.Sprintf("invalid field %v for keyed message map: want repeated message field", fd.FullName()))
+	}
+	kfd := fd.Message().Fields().ByName(key)
+	if kfd == nil || kfd.Cardinality() == pref.Repeated {
+		panic(fmt.Sprintf("invalid key field %q for field %v: want singular field of %v", key, fd.FullName(), fd.Message().FullName()))
+	}
+	switch kfd.Kind() {
+	case pref.BytesKind, pref.FloatKind, pref.DoubleKind, pref.EnumKind, pref.MessageKind, pref.GroupKind:
+		panic(fmt.Sprintf("invalid key field %v for field %v: %v is not a valid map key kind", kfd.FullName(), fd.FullName(), kfd.Kind()))
+	}
+	keyConv := newSingularConverter(t.Key(), kfd)
+	pb := ConverterOptions{}.newListConverter(reflect.SliceOf(t.Elem()), fd)
+	return &funcConverter{
+		goType: t,
+		pb:     pb,
+		toPB: func(v reflect.Value) (pref.Value, error) {
+			keys := v.MapKeys()
+			sort.Slice(keys, func(i, j int) bool {
+				return genericKeyOrder(keyConv.PBValueOf(keys[i]).MapKey(), keyConv.PBValueOf(keys[j]).MapKey())
+			})
+			s := reflect.MakeSlice(reflect.SliceOf(t.Elem()), len(keys), len(keys))
+			for i, k := range keys {
+				mv := v.MapIndex(k)
+				if mv.Kind() == reflect.Ptr && mv.IsNil() {
+					return pref.Value{}, fmt.Errorf("invalid nil value for key %v of field %v", k, fd.FullName())
+				}
+				s.Index(i).Set(mv)
+			}
+			lv := pb.PBValueOf(s)
+			for i, k := range keys {
+				got := lv.List().Get(i).Message().Get(kfd)
+				if want := keyConv.PBValueOf(k); got.Interface() != want.Interface() {
+					return pref.Value{}, fmt.Errorf("invalid value for key %v of field %v: key field %v is %v", k, fd.FullName(), key, got)
+				}
+			}
+			return lv, nil
+		},
+		toGo: func(v pref.Value) (reflect.Value, error) {
+			s := pb.GoValueOf(v)
+			rv := reflect.MakeMapWithSize(t, s.Len())
+			for i := 0; i < s.Len(); i++ {
+				k := v.List().Get(i).Message().Get(kfd)
+				rv.SetMapIndex(keyConv.GoValueOf(k), s.Index(i))
+			}
+			return rv, nil
+		},
+	}
+}
+
+// newIntConverter returns a TryConverter between the Go int or uint type t
+// and the integer field fd, whose values are converted as by convertScalar.
+// Values that overflow the field are reported by TryPBValueOf, and values
+// that overflow t are reported by TryGoValueOf.
+func newIntConverter(t reflect.Type, fd pref.FieldDescriptor) TryConverter {
+	pbType := scalarGoType(fd.Kind())
+	pb := newSingularConverter(pbType, fd)
+	return &funcConverter{
+		goType: t,
+		pb:     pb,
+		toPB: func(v reflect.Value) (pref.Value, error) {
+			rv, err := convertScalar(v, pbType)
+			if err != nil {
+				return pref.Value{}, err
+			}
+			return pb.PBValueOf(rv), nil
+		},
+		toGo: func(v pref.Value) (reflect.Value, error) {
+			return convertScalar(pb.GoValueOf(v), t)
+		},
+	}
+}
+
+// isIntegerKind reports whether k is a signed or unsigned integer kind.
+func isIntegerKind(k pref.Kind) bool {
+	switch scalarGoType(k) {
+	case int32Type, int64Type, uint32Type, uint64Type:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,500 @@
+package protoconv_test
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/dnscrypt/dnscrypt-proxy/protoconv"
+	pref "google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+var durationMapType = reflect.TypeOf(map[string]durationpb.Duration(nil))
+
+func TestMapConverterNonPointerMessages(t *testing.T) {
+	c := protoconv.NewConverter(durationMapType, field("mdur"))
+	m := map[string]durationpb.Duration{
+		"a": {Seconds: 1},
+		"b": {Seconds: 2, Nanos: 3},
+	}
+	pm := c.PBValueOf(reflect.ValueOf(m)).Map()
+
+	tests := []struct {
+		key         string
+		wantSeconds int64
+		wantNanos   int32
+	}{
+		{"a", 1, 0},
+		{"b", 2, 3},
+	}
+	for _, tt := range tests {
+		d := pm.Get(pref.ValueOfString(tt.key).MapKey()).Message().Interface().(*durationpb.Duration)
+		if d.Seconds != tt.wantSeconds || d.Nanos != tt.wantNanos {
+			t.Errorf("Get(%q) = %v, want %vs %vns", tt.key, d, tt.wantSeconds, tt.wantNanos)
+		}
+	}
+
+	got := make(map[string]int64)
+	pm.Range(func(k pref.MapKey, v pref.Value) bool {
+		got[k.String()] = v.Message().Interface().(*durationpb.Duration).Seconds
+		return true
+	})
+	if want := map[string]int64{"a": 1, "b": 2}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Range = %v, want %v", got, want)
+	}
+
+	pm.Set(pref.ValueOfString("c").MapKey(), pref.ValueOfMessage((&durationpb.Duration{Seconds: 4}).ProtoReflect()))
+	if got := reflect.ValueOf(m).MapIndex(reflect.ValueOf("c")).FieldByName("Seconds").Int(); got != 4 {
+		t.Errorf("Set stored %d seconds, want 4", got)
+	}
+}
+
+func TestMapConverterNonPointerMutable(t *testing.T) {
+	c := protoconv.NewConverter(durationMapType, field("mdur"))
+	pm := c.PBValueOf(reflect.ValueOf(map[string]durationpb.Duration{"a": {Seconds: 1}})).Map()
+	for _, key := range []string{"a", "missing"} {
+		t.Run(key, func(t *testing.T) {
+			defer func() {
+				if r := recover(); r == nil {
+					t.Errorf("Mutable(%q) did not panic", key)
+				}
+			}()
+			pm.Mutable(pref.ValueOfString(key).MapKey())
+		})
+	}
+}
+
+func BenchmarkMapConverterNonPointerMessages(b *testing.B) {
+	c := protoconv.NewConverter(durationMapType, field("mdur"))
+	m := make(map[string]durationpb.Duration, 10000)
+	for i := 0; i < 10000; i++ {
+		m[fmt.Sprint(i)] = durationpb.Duration{Seconds: int64(i)}
+	}
+	rv := reflect.ValueOf(m)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var sum int64
+		c.PBValueOf(rv).Map().Range(func(_ pref.MapKey, v pref.Value) bool {
+			sum += v.Message().Interface().(*durationpb.Duration).Seconds
+			return true
+		})
+	}
+}
+
+func TestSyncMapConverter(t *testing.T) {
+	c := protoconv.NewSyncMapConverter(stringType, reflect.TypeOf(int64(0)), field("msi"))
+	tests := []struct {
+		name string
+		in   map[string]int64
+	}{
+		{"empty", map[string]int64{}},
+		{"populated", map[string]int64{"a": 1, "b": -2, "": 3}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sm := new(sync.Map)
+			for k, v := range tt.in {
+				sm.Store(k, v)
+			}
+			v, err := c.TryPBValueOf(reflect.ValueOf(sm))
+			if err != nil {
+				t.Fatalf("TryPBValueOf error: %v", err)
+			}
+			if got := v.Map().Len(); got != len(tt.in) {
+				t.Errorf("TryPBValueOf has %d entries, want %d", got, len(tt.in))
+			}
+			rv, err := c.TryGoValueOf(v)
+			if err != nil {
+				t.Fatalf("TryGoValueOf error: %v", err)
+			}
+			got := make(map[string]int64)
+			rv.Interface().(*sync.Map).Range(func(k, v interface{}) bool {
+				got[k.(string)] = v.(int64)
+				return true
+			})
+			if !reflect.DeepEqual(got, tt.in) {
+				t.Errorf("round trip = %v, want %v", got, tt.in)
+			}
+		})
+	}
+
+	sm := new(sync.Map)
+	sm.Store("a", 1)
+	if _, err := c.TryPBValueOf(reflect.ValueOf(sm)); err == nil {
+		t.Error("TryPBValueOf with an int value succeeded, want error")
+	}
+}
+
+func TestAnyMapConverter(t *testing.T) {
+	c := protoconv.NewAnyMapConverter(field("msi"))
+	tests := []struct {
+		name    string
+		in      map[string]interface{}
+		wantErr string
+	}{
+		{"empty", map[string]interface{}{}, ""},
+		{"well typed", map[string]interface{}{"a": int64(1), "b": int64(-2)}, ""},
+		{"wrong type", map[string]interface{}{"a": int64(1), "z": "x", "zz": 3}, `key "z"`},
+		{"untyped int", map[string]interface{}{"n": 1}, `key "n"`},
+		{"nil value", map[string]interface{}{"nil": nil}, `key "nil"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v, err := c.TryPBValueOf(reflect.ValueOf(tt.in))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("TryPBValueOf(%v) error = %v, want %s", tt.in, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("TryPBValueOf(%v) error: %v", tt.in, err)
+			}
+			got, err := c.TryGoValueOf(v)
+			if err != nil || !reflect.DeepEqual(got.Interface(), tt.in) {
+				t.Errorf("TryGoValueOf = %v, %v, want %v", got, err, tt.in)
+			}
+		})
+	}
+}
+
+// shouted is rendered in upper case by its String method.
+type shouted string
+
+func (s shouted) String() string { return strings.ToUpper(string(s)) }
+
+func TestMapConverterWith(t *testing.T) {
+	mt := reflect.TypeOf(map[string]shouted(nil))
+	val := protoconv.NewStringerConverter(reflect.TypeOf(shouted("")), func(s string) (interface{}, error) {
+		return shouted(strings.ToLower(s)), nil
+	}, field("mss").MapValue())
+	c := protoconv.NewMapConverterWith(mt, nil, val, field("mss"))
+
+	in := map[string]shouted{"a": "x", "b": "dns", "C": ""}
+	m := c.PBValueOf(reflect.ValueOf(in)).Map()
+	tests := []struct {
+		key  string
+		want string
+	}{
+		{"a", "X"},
+		{"b", "DNS"},
+		{"C", ""},
+	}
+	for _, tt := range tests {
+		if got := m.Get(pref.ValueOfString(tt.key).MapKey()).String(); got != tt.want {
+			t.Errorf("entry %q = %q, want %q", tt.key, got, tt.want)
+		}
+	}
+	if got := c.GoValueOf(pref.ValueOfMap(m)).Interface(); !reflect.DeepEqual(got, in) {
+		t.Errorf("GoValueOf = %v, want %v", got, in)
+	}
+
+	defer func() {
+		if r := recover(); r == nil {
+			t.Error("NewMapConverterWith with a mismatched value Converter did not panic")
+		}
+	}()
+	protoconv.NewMapConverterWith(reflect.TypeOf(map[string]int64(nil)), nil, val, field("mss"))
+}
+
+func TestIntegerKeyMaps(t *testing.T) {
+	tests := []struct {
+		name  string
+		field string
+		in    interface{}
+	}{
+		{"int32 keys", "mi32", map[int32]string{math.MinInt32: "min", -1: "neg", 0: "zero", math.MaxInt32: "max"}},
+		{"int64 keys", "mi64", map[int64]string{math.MinInt64: "min", 7: "seven", math.MaxInt64: "max"}},
+		{"uint64 keys", "mu64", map[uint64]bool{0: false, 1 << 63: true, math.MaxUint64: true}},
+		{"empty", "mi64", map[int64]string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := protoconv.NewConverter(reflect.TypeOf(tt.in), field(tt.field))
+			in := reflect.ValueOf(tt.in)
+			m := c.PBValueOf(in).Map()
+			if m.Len() != in.Len() {
+				t.Fatalf("PBValueOf has %d entries, want %d", m.Len(), in.Len())
+			}
+			for _, k := range in.MapKeys() {
+				mk := pref.ValueOf(k.Interface()).MapKey()
+				if got, want := m.Get(mk).Interface(), in.MapIndex(k).Interface(); got != want {
+					t.Errorf("entry %v = %v, want %v", k, got, want)
+				}
+			}
+
+			dst := c.New().Map()
+			m.Range(func(k pref.MapKey, v pref.Value) bool {
+				dst.Set(k, v)
+				return true
+			})
+			if got := c.GoValueOf(pref.ValueOfMap(dst)).Interface(); !reflect.DeepEqual(got, tt.in) {
+				t.Errorf("round trip = %v, want %v", got, tt.in)
+			}
+		})
+	}
+}
+
+func TestIndexMapConverter(t *testing.T) {
+	c := protoconv.NewIndexMapConverter(reflect.TypeOf(map[int32]string(nil)), field("rs"))
+	tests := []struct {
+		name    string
+		in      map[int32]string
+		want    []string
+		wantErr bool
+	}{
+		{"empty", map[int32]string{}, []string{}, false},
+		{"contiguous", map[int32]string{0: "a", 2: "c", 1: "b"}, []string{"a", "b", "c"}, false},
+		{"gap", map[int32]string{0: "a", 2: "c"}, nil, true},
+		{"negative", map[int32]string{-1: "a"}, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v, err := c.TryPBValueOf(reflect.ValueOf(tt.in))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("TryPBValueOf(%v) error = %v, want error %v", tt.in, err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if got := listStrings(v.List()); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("TryPBValueOf(%v) = %q, want %q", tt.in, got, tt.want)
+			}
+			if got := c.GoValueOf(v).Interface(); !reflect.DeepEqual(got, tt.in) {
+				t.Errorf("round trip = %v, want %v", got, tt.in)
+			}
+		})
+	}
+}
+
+func TestIndexMapConverterOverflow(t *testing.T) {
+	c := protoconv.NewIndexMapConverter(reflect.TypeOf(map[int8]string(nil)), field("rs"))
+	for _, n := range []int{math.MaxInt8 + 1, math.MaxInt8 + 2} {
+		list := c.New().List()
+		for i := 0; i < n; i++ {
+			list.Append(pref.ValueOfString(fmt.Sprint(i)))
+		}
+		rv, err := c.TryGoValueOf(pref.ValueOfList(list))
+		if wantErr := n > math.MaxInt8+1; (err != nil) != wantErr {
+			t.Errorf("TryGoValueOf of %d elements error = %v, want error %v", n, err, wantErr)
+		} else if err == nil && rv.Len() != n {
+			t.Errorf("TryGoValueOf of %d elements has %d entries", n, rv.Len())
+		}
+	}
+}
+
+func TestMapConverterNilMessages(t *testing.T) {
+	c := protoconv.NewConverter(reflect.TypeOf(map[string]*StringList(nil)), reqField("headers")).(protoconv.TryConverter)
+	tests := []struct {
+		name     string
+		in       map[string]*StringList
+		wantKeys []string // nil keys named by the error
+	}{
+		{"empty", map[string]*StringList{}, nil},
+		{"present", map[string]*StringList{"a": {Values: []string{"x"}}, "b": {}}, nil},
+		{"nil value", map[string]*StringList{"a": {}, "b": nil}, []string{"b"}},
+		{"nil values", map[string]*StringList{"c": nil, "a": {}, "b": nil}, []string{"b", "c"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v, err := c.TryPBValueOf(reflect.ValueOf(tt.in))
+			if (err != nil) != (tt.wantKeys != nil) {
+				t.Fatalf("TryPBValueOf error = %v, want error for keys %q", err, tt.wantKeys)
+			}
+			if err != nil {
+				if want := fmt.Sprintf("%q", tt.wantKeys); !strings.Contains(err.Error(), want) {
+					t.Errorf("TryPBValueOf error = %v, want keys %s", err, want)
+				}
+				return
+			}
+			if v.Map().Len() != len(tt.in) {
+				t.Errorf("TryPBValueOf has %d entries, want %d", v.Map().Len(), len(tt.in))
+			}
+		})
+	}
+}
+
+func TestMaxMapEntries(t *testing.T) {
+	typ := reflect.TypeOf(map[string]string(nil))
+	in := map[string]string{"c": "3", "a": "1", "b": "2"}
+	tests := []struct {
+		name    string
+		opts    protoconv.ConverterOptions
+		want    map[string]string
+		wantErr bool
+	}{
+		{"unlimited", protoconv.ConverterOptions{}, in, false},
+		{"at limit", protoconv.ConverterOptions{MaxMapEntries: 3}, in, false},
+		{"over limit", protoconv.ConverterOptions{MaxMapEntries: 2}, nil, true},
+		{"truncated", protoconv.ConverterOptions{MaxMapEntries: 2, TruncateMaps: true}, map[string]string{"a": "1", "b": "2"}, false},
+		{"truncate under limit", protoconv.ConverterOptions{MaxMapEntries: 5, TruncateMaps: true}, in, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := tt.opts.New(typ, field("mss")).(protoconv.TryConverter)
+			v, err := c.TryPBValueOf(reflect.ValueOf(in))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("TryPBValueOf error = %v, want error %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if got := c.GoValueOf(v).Interface(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("round trip = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEnumKeyMapConverter(t *testing.T) {
+	typ := reflect.TypeOf(map[E]string(nil))
+	tests := []struct {
+		name          string
+		unknownAsZero bool
+		in            map[int32]string // the map field
+		want          map[E]string
+		wantErr       bool
+	}{
+		{"declared keys", false, map[int32]string{1: "a", 2: "b"}, map[E]string{1: "a", 2: "b"}, false},
+		{"empty", false, map[int32]string{}, map[E]string{}, false},
+		{"unknown key", false, map[int32]string{1: "a", 9: "x"}, nil, true},
+		{"unknown key as zero", true, map[int32]string{1: "a", 9: "x"}, map[E]string{0: "x", 1: "a"}, false},
+		{"two unknown keys", true, map[int32]string{1: "a", 8: "x", 9: "y"}, nil, true},
+		{"unknown key and zero", true, map[int32]string{0: "z", 9: "x"}, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := protoconv.NewEnumKeyMapConverter(typ, tt.unknownAsZero, field("mi32"))
+			m := c.New().Map()
+			for k, v := range tt.in {
+				m.Set(pref.ValueOfInt32(k).MapKey(), pref.ValueOfString(v))
+			}
+			got, err := c.TryGoValueOf(pref.ValueOfMap(m))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("TryGoValueOf(%v) error = %v, want error %v", tt.in, err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if !reflect.DeepEqual(got.Interface(), tt.want) {
+				t.Errorf("TryGoValueOf(%v) = %v, want %v", tt.in, got, tt.want)
+			}
+			if tt.unknownAsZero {
+				return
+			}
+			v, err := c.TryPBValueOf(got)
+			if err != nil {
+				t.Fatalf("TryPBValueOf error: %v", err)
+			}
+			back := make(map[int32]string)
+			v.Map().Range(func(k pref.MapKey, v pref.Value) bool {
+				back[int32(k.Int())] = v.String()
+				return true
+			})
+			if !reflect.DeepEqual(back, tt.in) {
+				t.Errorf("TryPBValueOf(%v) = %v, want %v", got, back, tt.in)
+			}
+		})
+	}
+}
+
+func TestKeyedMessageMapConverter(t *testing.T) {
+	tests := []struct {
+		name     string
+		in       interface{}
+		wantKeys []string
+		wantErr  bool
+	}{
+		{"pointers", map[string]*Event{"b": {Zone: "b"}, "a": {Zone: "a"}}, []string{"a", "b"}, false},
+		{"values", map[string]Event{"b": {Zone: "b"}, "a": {Zone: "a"}}, []string{"a", "b"}, false},
+		{"empty", map[string]*Event{}, nil, false},
+		{"mismatched key", map[string]*Event{"y": {Zone: "x"}}, nil, true},
+		{"nil value", map[string]*Event{"y": nil}, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			in := reflect.ValueOf(tt.in)
+			c := protoconv.NewKeyedMessageMapConverter(in.Type(), "zone", blobField("events"))
+			v, err := c.TryPBValueOf(in)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("TryPBValueOf error = %v, want error %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			var keys []string
+			for i := 0; i < v.List().Len(); i++ {
+				keys = append(keys, v.List().Get(i).Message().Get(blobField("events").Message().Fields().ByName("zone")).String())
+			}
+			if !reflect.DeepEqual(keys, tt.wantKeys) {
+				t.Errorf("TryPBValueOf keys = %q, want %q", keys, tt.wantKeys)
+			}
+			got, err := c.TryGoValueOf(v)
+			if err != nil || got.Type() != in.Type() || got.Len() != in.Len() {
+				t.Fatalf("TryGoValueOf = %v, %v, want %v", got, err, tt.in)
+			}
+			for _, k := range got.MapKeys() {
+				if zone := reflect.Indirect(got.MapIndex(k)).FieldByName("Zone").String(); zone != k.String() {
+					t.Errorf("TryGoValueOf[%q] has zone %q", k, zone)
+				}
+			}
+		})
+	}
+}
+
+func TestKeyedMessageMapConverterDuplicates(t *testing.T) {
+	c := protoconv.NewKeyedMessageMapConverter(reflect.TypeOf(map[string]*Event(nil)), "zone", blobField("events"))
+	first, last, other := &Event{Zone: "x"}, &Event{Zone: "x"}, &Event{Zone: "y"}
+	v := c.New()
+	for _, e := range []*Event{first, other, last} {
+		v.List().Append(pref.ValueOfMessage(e.ProtoReflect()))
+	}
+	got, err := c.TryGoValueOf(v)
+	if err != nil {
+		t.Fatalf("TryGoValueOf error: %v", err)
+	}
+	m := got.Interface().(map[string]*Event)
+	if len(m) != 2 || m["x"] != last || m["y"] != other {
+		t.Errorf("TryGoValueOf = %v, want the last message for each key", m)
+	}
+}
+
+func TestIntMapValues(t *testing.T) {
+	c := protoconv.NewConverter(reflect.TypeOf(map[string]int(nil)), field("msi32")).(protoconv.TryConverter)
+	tests := []struct {
+		name    string
+		in      map[string]int
+		wantKey string // naming the key in the error, or "" for success
+	}{
+		{"in range", map[string]int{"a": 1, "b": -7, "max": math.MaxInt32, "min": math.MinInt32}, ""},
+		{"empty", map[string]int{}, ""},
+		{"overflow", map[string]int{"a": 1, "z": math.MaxInt32 + 1}, "key z "},
+		{"smallest key", map[string]int{"a": 1, "z": 1 << 40, "y": -(1 << 40)}, "key y "},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v, err := c.TryPBValueOf(reflect.ValueOf(tt.in))
+			if tt.wantKey != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantKey) {
+					t.Errorf("TryPBValueOf error = %v, want error containing %q", err, tt.wantKey)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("TryPBValueOf error: %v", err)
+			}
+			for k, n := range tt.in {
+				if got := v.Map().Get(pref.ValueOfString(k).MapKey()).Int(); got != int64(n) {
+					t.Errorf("TryPBValueOf[%q] = %d, want %d", k, got, n)
+				}
+			}
+			if got := c.GoValueOf(v).Interface(); !reflect.DeepEqual(got, tt.in) {
+				t.Errorf("GoValueOf = %v, want %v", got, tt.in)
+			}
+		})
+	}
+}
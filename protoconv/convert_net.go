@@ -0,0 +1,177 @@
+package protoconv
+
+import (
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"net/netip"
+	"net/textproto"
+	"net/url"
+	"reflect"
+
+	pref "google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// NewURLValuesConverter returns a Converter between url.Values and a
+// map<string, StringList> field, where StringList is any message with
+// a single repeated string field holding the values of a key.
+// Proto maps cannot have repeated values, so every entry is wrapped
+// in such a message.
+func NewURLValuesConverter(fd pref.FieldDescriptor) Converter {
+	return newStringListMapConverter(reflect.TypeOf(url.Values(nil)), fd, nil)
+}
+
+// NewHTTPHeaderConverter returns a Converter between http.Header and a
+// map<string, StringList> field, as by NewURLValuesConverter.
+// Keys are put in canonical MIME header form in both directions, so that
+// the values of keys differing only in case are merged into a single entry;
+// since map iteration order is unspecified, so is the order of merged values.
+func NewHTTPHeaderConverter(fd pref.FieldDescriptor) Converter {
+	return newStringListMapConverter(reflect.TypeOf(http.Header(nil)), fd, textproto.CanonicalMIMEHeaderKey)
+}
+
+// newStringListMapConverter returns a Converter between the Go type t,
+// whose underlying type is map[string][]string, and a map field whose
+// values wrap a single repeated string field.
+// If canonicalKey is not nil, it is applied to every key.
+func newStringListMapConverter(t reflect.Type, fd pref.FieldDescriptor, canonicalKey func(string) string) Converter {
+	if !fd.IsMap() || fd.MapKey().Kind() != pref.StringKind || fd.MapValue().Message() == nil {
+		panic(fmt.Sprintf("invalid field %v for %v: want map<string, message>", fd.FullName(), t))
+	}
+	mt := findMessageType(fd.MapValue())
+	fds := mt.Descriptor().Fields()
+	if fds.Len() != 1 || !fds.Get(0).IsList() || fds.Get(0).Kind() != pref.StringKind {
+		panic(fmt.Sprintf("invalid message %v for field %v: want a single repeated string field", mt.Descriptor().FullName(), fd.FullName()))
+	}
+	listFd := fds.Get(0)
+	msgType := reflect.TypeOf(mt.Zero().Interface())
+	pb := ConverterOptions{}.newMapConverter(reflect.MapOf(stringType, msgType), fd)
+	return &funcConverter{
+		goType: t,
+		pb:     pb,
+		toPB: func(v reflect.Value) (pref.Value, error) {
+			m := reflect.MakeMapWithSize(pb.goType, v.Len())
+			iter := v.MapRange()
+			for iter.Next() {
+				k := iter.Key().String()
+				if canonicalKey != nil {
+					k = canonicalKey(k)
+				}
+				var msg pref.Message
+				if prev := m.MapIndex(reflect.ValueOf(k)); prev.IsValid() {
+					msg = prev.Interface().(pref.ProtoMessage).ProtoReflect() // keys merged by canonicalKey
+				} else {
+					msg = mt.New()
+				}
+				list := msg.Mutable(listFd).List()
+				for i, vs := 0, iter.Value(); i < vs.Len(); i++ {
+					list.Append(pref.ValueOfString(vs.Index(i).String()))
+				}
+				m.SetMapIndex(reflect.ValueOf(k), reflect.ValueOf(msg.Interface()))
+			}
+			return pb.PBValueOf(m), nil
+		},
+		toGo: func(v pref.Value) (reflect.Value, error) {
+			rv := reflect.MakeMapWithSize(t, v.Map().Len())
+			v.Map().Range(func(k pref.MapKey, v pref.Value) bool {
+				list := v.Message().Get(listFd).List()
+				vs := make([]string, list.Len())
+				for i := range vs {
+					vs[i] = list.Get(i).String()
+				}
+				key := reflect.ValueOf(k.String())
+				if canonicalKey != nil {
+					key = reflect.ValueOf(canonicalKey(key.String()))
+					if prev := rv.MapIndex(key); prev.IsValid() {
+						vs = append(prev.Interface().([]string), vs...) // keys merged by canonicalKey
+					}
+				}
+				rv.SetMapIndex(key, reflect.ValueOf(vs))
+				return true
+			})
+			return rv, nil
+		},
+	}
+}
+
+var addrType = reflect.TypeOf(netip.Addr{})
+
+// NewAddrConverter returns a TryConverter between a netip.Addr and either
+// a bytes field holding the 4 or 16 bytes of the address, or a string field
+// holding its textual form. The zero netip.Addr converts to an empty value,
+// and the reverse. Values that are not a valid address are reported by
+// TryGoValueOf.
+func NewAddrConverter(fd pref.FieldDescriptor) TryConverter {
+	if fd.Kind() != pref.BytesKind && fd.Kind() != pref.StringKind {
+		panic(fmt.Sprintf("invalid field %v for netip.Addr: got %v, want bytes or string", fd.FullName(), fd.Kind()))
+	}
+	isBytes := fd.Kind() == pref.BytesKind
+	return &funcConverter{
+		goType: addrType,
+		pb:     newSingularConverter(scalarGoType(fd.Kind()), fd),
+		toPB: func(v reflect.Value) (pref.Value, error) {
+			addr := v.Interface().(netip.Addr)
+			switch {
+			case isBytes:
+				return pref.ValueOfBytes(addr.AsSlice()), nil
+			case !addr.IsValid():
+				return stringZero, nil
+			default:
+				return pref.ValueOfString(addr.String()), nil
+			}
+		},
+		toGo: func(v pref.Value) (reflect.Value, error) {
+			if isBytes {
+				b := v.Bytes()
+				if len(b) == 0 {
+					return reflect.ValueOf(netip.Addr{}), nil
+				}
+				addr, ok := netip.AddrFromSlice(b)
+				if !ok {
+					return reflect.Value{}, fmt.Errorf("invalid address of length %d for field %v", len(b), fd.FullName())
+				}
+				return reflect.ValueOf(addr), nil
+			}
+			if v.String() == "" {
+				return reflect.ValueOf(netip.Addr{}), nil
+			}
+			addr, err := netip.ParseAddr(v.String())
+			if err != nil {
+				return reflect.Value{}, fmt.Errorf("invalid address for field %v: %w", fd.FullName(), err)
+			}
+			return reflect.ValueOf(addr), nil
+		},
+	}
+}
+
+var certificateType = reflect.TypeOf((*x509.Certificate)(nil))
+
+// NewCertificateConverter returns a TryConverter between a *x509.Certificate
+// and a bytes field holding its DER encoding, as in the Raw field of
+// the certificate. A nil certificate converts to empty bytes, and the reverse.
+// Bytes that are not a valid certificate are reported by TryGoValueOf.
+func NewCertificateConverter(fd pref.FieldDescriptor) TryConverter {
+	if fd.Kind() != pref.BytesKind {
+		panic(fmt.Sprintf("invalid field %v for %v: got %v, want bytes", fd.FullName(), certificateType, fd.Kind()))
+	}
+	return &funcConverter{
+		goType: certificateType,
+		pb:     newSingularConverter(bytesType, fd),
+		toPB: func(v reflect.Value) (pref.Value, error) {
+			if v.IsNil() {
+				return pref.ValueOfBytes(nil), nil
+			}
+			return pref.ValueOfBytes(v.Interface().(*x509.Certificate).Raw), nil
+		},
+		toGo: func(v pref.Value) (reflect.Value, error) {
+			if len(v.Bytes()) == 0 {
+				return reflect.Zero(certificateType), nil
+			}
+			cert, err := x509.ParseCertificate(v.Bytes())
+			if err != nil {
+				return reflect.Value{}, fmt.Errorf("invalid certificate for field %v: %w", fd.FullName(), err)
+			}
+			return reflect.ValueOf(cert), nil
+		},
+	}
+}
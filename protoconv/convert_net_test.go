@@ -0,0 +1,205 @@
+package protoconv_test
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/dnscrypt/dnscrypt-proxy/protoconv"
+	pref "google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// stringLists returns the values of a map<string, StringList> value.
+func stringLists(m pref.Map) map[string][]string {
+	got := make(map[string][]string)
+	m.Range(func(k pref.MapKey, v pref.Value) bool {
+		got[k.String()] = v.Message().Interface().(*StringList).Values
+		return true
+	})
+	return got
+}
+
+func TestURLValuesConverter(t *testing.T) {
+	c := protoconv.NewURLValuesConverter(reqField("headers"))
+	tests := []struct {
+		name string
+		in   url.Values
+	}{
+		{"empty", url.Values{}},
+		{"single key", url.Values{"q": {"a", "b"}}},
+		{"multiple keys", url.Values{"q": {"a"}, "page": {"2"}, "empty": {}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := c.PBValueOf(reflect.ValueOf(tt.in))
+			got := stringLists(v.Map())
+			if len(got) != len(tt.in) {
+				t.Fatalf("PBValueOf(%v) has %d entries, want %d", tt.in, len(got), len(tt.in))
+			}
+			for k, vs := range tt.in {
+				if len(vs) > 0 && !reflect.DeepEqual(got[k], vs) {
+					t.Errorf("PBValueOf(%v)[%q] = %q, want %q", tt.in, k, got[k], vs)
+				}
+			}
+			back := c.GoValueOf(v).Interface().(url.Values)
+			if back.Encode() != tt.in.Encode() || len(back) != len(tt.in) {
+				t.Errorf("round trip of %v = %v", tt.in, back)
+			}
+		})
+	}
+}
+
+func TestHTTPHeaderConverter(t *testing.T) {
+	c := protoconv.NewHTTPHeaderConverter(reqField("headers"))
+	tests := []struct {
+		name string
+		in   http.Header
+		want map[string][]string
+	}{
+		{"empty", http.Header{}, map[string][]string{}},
+		{"canonical", http.Header{"Content-Type": {"text/plain"}}, map[string][]string{"Content-Type": {"text/plain"}}},
+		{"lower case", http.Header{"x-request-id": {"1"}}, map[string][]string{"X-Request-Id": {"1"}}},
+		{"multiple values", http.Header{"Accept": {"a", "b"}}, map[string][]string{"Accept": {"a", "b"}}},
+		{"merged keys", http.Header{"x-multi": {"1"}, "X-MULTI": {"2", "3"}}, map[string][]string{"X-Multi": {"1", "2", "3"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := c.PBValueOf(reflect.ValueOf(tt.in))
+			got := stringLists(v.Map())
+			for _, vs := range got {
+				sort.Strings(vs) // merge order is unspecified
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("PBValueOf(%v) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHTTPHeaderConverterInbound(t *testing.T) {
+	c := protoconv.NewHTTPHeaderConverter(reqField("headers"))
+	req := &Req{Headers: map[string]*StringList{
+		"content-type": {Values: []string{"a"}},
+		"CONTENT-TYPE": {Values: []string{"b"}},
+		"X-Single":     {Values: []string{"c"}},
+	}}
+	h := c.GoValueOf(req.ProtoReflect().Get(reqField("headers"))).Interface().(http.Header)
+	sort.Strings(h["Content-Type"]) // merge order is unspecified
+	want := http.Header{"Content-Type": {"a", "b"}, "X-Single": {"c"}}
+	if !reflect.DeepEqual(h, want) {
+		t.Errorf("GoValueOf = %q, want %q", h, want)
+	}
+}
+
+func TestAddrConverter(t *testing.T) {
+	tests := []struct {
+		name      string
+		in        netip.Addr
+		wantBytes []byte
+		wantText  string
+	}{
+		{"IPv4", netip.MustParseAddr("192.0.2.1"), []byte{192, 0, 2, 1}, "192.0.2.1"},
+		{"IPv6", netip.MustParseAddr("2001:db8::1"), netip.MustParseAddr("2001:db8::1").AsSlice(), "2001:db8::1"},
+		{"zero", netip.Addr{}, nil, ""},
+	}
+	bc := protoconv.NewAddrConverter(field("b"))
+	sc := protoconv.NewAddrConverter(field("s"))
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bv := bc.PBValueOf(reflect.ValueOf(tt.in))
+			if got := bv.Bytes(); !bytes.Equal(got, tt.wantBytes) {
+				t.Errorf("bytes: PBValueOf = %v, want %v", got, tt.wantBytes)
+			}
+			sv := sc.PBValueOf(reflect.ValueOf(tt.in))
+			if got := sv.String(); got != tt.wantText {
+				t.Errorf("string: PBValueOf = %q, want %q", got, tt.wantText)
+			}
+			for _, v := range []struct {
+				c protoconv.TryConverter
+				v pref.Value
+			}{{bc, bv}, {sc, sv}} {
+				if got, err := v.c.TryGoValueOf(v.v); err != nil || got.Interface() != tt.in {
+					t.Errorf("TryGoValueOf(%v) = %v, %v, want %v", v.v, got, err, tt.in)
+				}
+			}
+		})
+	}
+
+	invalid := []struct {
+		c protoconv.TryConverter
+		v pref.Value
+	}{
+		{sc, pref.ValueOfString("1.2.3")},
+		{sc, pref.ValueOfString("example.com")},
+		{bc, pref.ValueOfBytes([]byte{1, 2, 3})},
+	}
+	for _, tt := range invalid {
+		if _, err := tt.c.TryGoValueOf(tt.v); err == nil {
+			t.Errorf("TryGoValueOf(%v) succeeded, want error", tt.v)
+		}
+	}
+}
+
+func TestCertificateConverter(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "test"},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := protoconv.NewCertificateConverter(field("b"))
+	tests := []struct {
+		name string
+		in   *x509.Certificate
+		want []byte
+	}{
+		{"self-signed", cert, der},
+		{"nil", nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v, err := c.TryPBValueOf(reflect.ValueOf(tt.in))
+			if err != nil || !bytes.Equal(v.Bytes(), tt.want) {
+				t.Fatalf("TryPBValueOf = %x, %v, want %x", v.Bytes(), err, tt.want)
+			}
+			rv, err := c.TryGoValueOf(v)
+			if err != nil {
+				t.Fatalf("TryGoValueOf error: %v", err)
+			}
+			got := rv.Interface().(*x509.Certificate)
+			if (got == nil) != (tt.in == nil) || got != nil && !got.Equal(tt.in) {
+				t.Errorf("round trip = %v, want %v", got, tt.in)
+			}
+		})
+	}
+
+	for _, b := range [][]byte{{0x30, 1, 2}, der[:len(der)-1]} {
+		if _, err := c.TryGoValueOf(pref.ValueOfBytes(b)); err == nil {
+			t.Errorf("TryGoValueOf(%x) succeeded, want error", b)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package protoconv
+
+import (
+	"fmt"
+	"reflect"
+
+	pref "google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// NewTristateConverter returns a TryConverter between a Go integer type t
+// representing a tri-state boolean and a bool field with explicit presence,
+// such as a proto3 optional bool. The values of t are 0 for unset,
+// 1 for false, and 2 for true.
+//
+// Since an unset value has no protobuf value, it converts to an invalid
+// protoreflect.Value, in which case the caller must clear the field;
+// likewise, an invalid protoreflect.Value converts to unset.
+// Other values of t are reported by TryPBValueOf.
+func NewTristateConverter(t reflect.Type, fd pref.FieldDescriptor) TryConverter {
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+	default:
+		panic(fmt.Sprintf("invalid Go type %v for tri-state bool: want signed integer", t))
+	}
+	if fd.Kind() != pref.BoolKind || !fd.HasPresence() || fd.IsList() {
+		panic(fmt.Sprintf("invalid field %v for tri-state bool: want optional bool", fd.FullName()))
+	}
+	const unset, isFalse, isTrue = 0, 1, 2
+	return &funcConverter{
+		goType: t,
+		pb:     newSingularConverter(boolType, fd),
+		toPB: func(v reflect.Value) (pref.Value, error) {
+			switch v.Int() {
+			case unset:
+				return pref.Value{}, nil
+			case isFalse:
+				return pref.ValueOfBool(false), nil
+			case isTrue:
+				return pref.ValueOfBool(true), nil
+			}
+			return pref.Value{}, fmt.Errorf("invalid tri-state value %v for field %v", v, fd.FullName())
+		},
+		toGo: func(v pref.Value) (reflect.Value, error) {
+			n := int64(unset)
+			if v.IsValid() {
+				n = isFalse
+				if v.Bool() {
+					n = isTrue
+				}
+			}
+			return reflect.ValueOf(n).Convert(t), nil
+		},
+	}
+}
@@ -0,0 +1,57 @@
+package protoconv_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/dnscrypt/dnscrypt-proxy/protoconv"
+	pref "google.golang.org/protobuf/reflect/protoreflect"
+)
+
+type tristate int
+
+const (
+	unset tristate = iota
+	isFalse
+	isTrue
+)
+
+func TestTristateConverter(t *testing.T) {
+	c := protoconv.NewTristateConverter(reflect.TypeOf(unset), field("ob"))
+	tests := []struct {
+		name string
+		in   tristate
+		want pref.Value
+	}{
+		{"unset", unset, pref.Value{}},
+		{"false", isFalse, pref.ValueOfBool(false)},
+		{"true", isTrue, pref.ValueOfBool(true)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v, err := c.TryPBValueOf(reflect.ValueOf(tt.in))
+			if err != nil {
+				t.Fatalf("TryPBValueOf(%v) error: %v", tt.in, err)
+			}
+			if v.IsValid() != tt.want.IsValid() || v.IsValid() && v.Bool() != tt.want.Bool() {
+				t.Errorf("TryPBValueOf(%v) = %v, want %v", tt.in, v, tt.want)
+			}
+			if got, err := c.TryGoValueOf(v); err != nil || got.Interface() != tt.in {
+				t.Errorf("TryGoValueOf(%v) = %v, %v, want %v", v, got, err, tt.in)
+			}
+		})
+	}
+
+	if _, err := c.TryPBValueOf(reflect.ValueOf(tristate(3))); err == nil {
+		t.Error("TryPBValueOf(3) succeeded, want error")
+	}
+}
+
+func TestTristateConverterInvalidField(t *testing.T) {
+	defer func() {
+		if r := recover(); r == nil {
+			t.Error("NewTristateConverter for a bool without presence did not panic")
+		}
+	}()
+	protoconv.NewTristateConverter(reflect.TypeOf(unset), field("rbool"))
+}
@@ -0,0 +1,116 @@
+package protoconv
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	pref "google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// prettyMaxBytes is the number of bytes rendered by Pretty
+// before the rest of a bytes value is elided.
+const prettyMaxBytes = 32
+
+// prettyValue renders v according to its Go type alone.
+// Enum values are rendered as numbers since their descriptor is unknown.
+func prettyValue(v pref.Value) string {
+	switch x := v.Interface().(type) {
+	case nil:
+		return "<invalid>"
+	case string:
+		return strconv.Quote(x)
+	case []byte:
+		return prettyBytes(x)
+	case pref.Message:
+		return prettyMessage(x)
+	case pref.List:
+		return prettyList(x, prettyValue)
+	case pref.Map:
+		return prettyMap(x, prettyValue)
+	default:
+		return fmt.Sprint(x)
+	}
+}
+
+// prettyField renders the value v of the field fd,
+// rendering enum values by name.
+func prettyField(fd pref.FieldDescriptor, v pref.Value) string {
+	switch {
+	case fd.IsList():
+		return prettyList(v.List(), func(v pref.Value) string {
+			return prettySingular(fd, v)
+		})
+	case fd.IsMap():
+		return prettyMap(v.Map(), func(v pref.Value) string {
+			return prettySingular(fd.MapValue(), v)
+		})
+	}
+	return prettySingular(fd, v)
+}
+
+func prettySingular(fd pref.FieldDescriptor, v pref.Value) string {
+	switch fd.Kind() {
+	case pref.EnumKind:
+		return prettyEnum(fd.Enum(), v.Enum())
+	case pref.MessageKind, pref.GroupKind:
+		return prettyMessage(v.Message())
+	}
+	return prettyValue(v)
+}
+
+// prettyEnum renders n by its name in ed, or as a number if undeclared.
+func prettyEnum(ed pref.EnumDescriptor, n pref.EnumNumber) string {
+	if ev := ed.Values().ByNumber(n); ev != nil {
+		return string(ev.Name())
+	}
+	return strconv.Itoa(int(n))
+}
+
+// prettyBytes renders b in hexadecimal, eliding all but its first
+// prettyMaxBytes bytes.
+func prettyBytes(b []byte) string {
+	if len(b) <= prettyMaxBytes {
+		return hex.EncodeToString(b)
+	}
+	return fmt.Sprintf("%s... (%d bytes)", hex.EncodeToString(b[:prettyMaxBytes]), len(b))
+}
+
+// prettyMessage renders the populated fields of m in index order.
+// A google.protobuf.Timestamp is rendered in RFC 3339 format.
+func prettyMessage(m pref.Message) string {
+	if !m.IsValid() {
+		return "<nil>"
+	}
+	md := m.Descriptor()
+	if md.FullName() == timestampMessageName {
+		secs := m.Get(md.Fields().ByNumber(timestampSecondsField)).Int()
+		nanos := m.Get(md.Fields().ByNumber(timestampNanosField)).Int()
+		return time.Unix(secs, nanos).UTC().Format(time.RFC3339Nano)
+	}
+	var ss []string
+	rangeFields(m, func(fd pref.FieldDescriptor, v pref.Value) bool {
+		ss = append(ss, string(fd.Name())+": "+prettyField(fd, v))
+		return true
+	})
+	return "{" + strings.Join(ss, ", ") + "}"
+}
+
+func prettyList(list pref.List, elem func(pref.Value) string) string {
+	ss := make([]string, list.Len())
+	for i := range ss {
+		ss[i] = elem(list.Get(i))
+	}
+	return "[" + strings.Join(ss, ", ") + "]"
+}
+
+func prettyMap(m pref.Map, val func(pref.Value) string) string {
+	var ss []string
+	rangeEntries(m, func(k pref.MapKey, v pref.Value) bool {
+		ss = append(ss, prettyValue(k.Value())+": "+val(v))
+		return true
+	})
+	return "{" + strings.Join(ss, ", ") + "}"
+}
@@ -0,0 +1,61 @@
+package protoconv_test
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dnscrypt/dnscrypt-proxy/protoconv"
+	pref "google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+func TestPretty(t *testing.T) {
+	ts := protoconv.NewTimestampConverter(field("ts"))
+	tests := []struct {
+		name string
+		c    protoconv.Converter
+		v    pref.Value
+		want string
+	}{
+		{"enum name", protoconv.EnumNameOptions{}.New(stringType, field("e")), pref.ValueOfEnum(1), "MY_VALUE"},
+		{"enum name undeclared", protoconv.EnumNameOptions{}.New(stringType, field("e")), pref.ValueOfEnum(9), "9"},
+		{"Go enum", protoconv.NewConverter(reflect.TypeOf(E(0)), field("e")), pref.ValueOfEnum(2), "OTHER"},
+		{"string", protoconv.NewConverter(stringType, field("s")), pref.ValueOfString("a\"b"), `"a\"b"`},
+		{"short bytes", protoconv.NewConverter(reflect.TypeOf([]byte(nil)), field("b")), pref.ValueOfBytes([]byte{0xab, 0x01}), "ab01"},
+		{
+			"long bytes",
+			protoconv.NewConverter(reflect.TypeOf([]byte(nil)), field("b")),
+			pref.ValueOfBytes(bytes.Repeat([]byte{0xab}, 40)),
+			strings.Repeat("ab", 32) + "... (40 bytes)",
+		},
+		{"timestamp", ts, ts.PBValueOf(reflect.ValueOf(time.Date(2020, 1, 2, 3, 4, 5, 6, time.UTC))), "2020-01-02T03:04:05.000000006Z"},
+		{
+			"message",
+			protoconv.NewConverter(reflect.TypeOf((*durationpb.Duration)(nil)), field("dur")),
+			pref.ValueOfMessage((&durationpb.Duration{Seconds: 3, Nanos: 4}).ProtoReflect()),
+			"{seconds: 3, nanos: 4}",
+		},
+		{
+			"list",
+			protoconv.NewConverter(reflect.TypeOf([]string(nil)), field("rs")),
+			protoconv.NewConverter(reflect.TypeOf([]string(nil)), field("rs")).PBValueOf(reflect.ValueOf([]string{"a", "b"})),
+			`["a", "b"]`,
+		},
+		{
+			"map",
+			protoconv.NewConverter(reflect.TypeOf(map[int64]string(nil)), field("mi64")),
+			protoconv.NewConverter(reflect.TypeOf(map[int64]string(nil)), field("mi64")).PBValueOf(reflect.ValueOf(map[int64]string{3: "c", 1: "a"})),
+			`{1: "a", 3: "c"}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.c.Pretty(tt.v); got != tt.want {
+				t.Errorf("Pretty = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
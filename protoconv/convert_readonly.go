@@ -0,0 +1,245 @@
+package protoconv
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"reflect"
+
+	"google.golang.org/protobuf/encoding/protowire"
+	pref "google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// The converters in this file are read-only: they derive a protobuf value
+// from a Go value, but the Go value cannot be reconstructed from it.
+// Their TryGoValueOf method reports an error wrapping ErrReadOnly, while
+// GoValueOf is a no-op that returns the zero value of the Go type, so that
+// callers populating Go values from a message can skip read-only fields
+// by checking TryGoValueOf.
+
+// ErrReadOnly is reported when converting a protobuf value
+// with a read-only Converter.
+var ErrReadOnly = errors.New("read-only converter")
+
+// readOnlyError returns the error reported by read-only converters for fd.
+func readOnlyError(goType reflect.Type, fd pref.FieldDescriptor) error {
+	return fmt.Errorf("cannot convert field %v to Go type %v: %w", fd.FullName(), goType, ErrReadOnly)
+}
+
+// methodConverter converts the result of calling a method on a Go value.
+type methodConverter struct {
+	goType reflect.Type
+	method reflect.Method
+	c      Converter // converts the method result
+	err    error     // reported by TryGoValueOf
+}
+
+// NewMethodConverter returns a read-only Converter that populates the field
+// with the result of calling the named method on values of type t.
+// The method must take no arguments and return a single value
+// suitable for the field, such as a bool for a bool field.
+// Since computed values cannot be set, TryGoValueOf always reports
+// an error wrapping ErrReadOnly.
+func NewMethodConverter(t reflect.Type, name string, fd pref.FieldDescriptor) TryConverter {
+	m, ok := t.MethodByName(name)
+	if !ok {
+		panic(fmt.Sprintf("invalid Go type %v for field %v: no method %v", t, fd.FullName(), name))
+	}
+	if m.Type.NumIn() != 1 || m.Type.NumOut() != 1 {
+		panic(fmt.Sprintf("invalid method %v.%v for field %v: must take no arguments and return one value", t, name, fd.FullName()))
+	}
+	return &methodConverter{t, m, newSingularConverter(m.Type.Out(0), fd), readOnlyError(t, fd)}
+}
+
+func (c *methodConverter) TryPBValueOf(v reflect.Value) (pref.Value, error) {
+	return c.PBValueOf(v), nil
+}
+
+func (c *methodConverter) TryGoValueOf(v pref.Value) (reflect.Value, error) {
+	return reflect.Value{}, c.err
+}
+
+func (c *methodConverter) PBValueOf(v reflect.Value) pref.Value {
+	if v.Type() != c.goType {
+		panic(fmt.Sprintf("invalid type: got %v, want %v", v.Type(), c.goType))
+	}
+	return c.c.PBValueOf(c.method.Func.Call([]reflect.Value{v})[0])
+}
+
+// GoValueOf returns the zero value of the Go type,
+// since computed values cannot be set.
+func (c *methodConverter) GoValueOf(v pref.Value) reflect.Value {
+	return reflect.Zero(c.goType)
+}
+
+func (c *methodConverter) IsValidPB(v pref.Value) bool {
+	return c.c.IsValidPB(v)
+}
+
+func (c *methodConverter) IsValidGo(v reflect.Value) bool {
+	return v.IsValid() && v.Type() == c.goType
+}
+
+func (c *methodConverter) New() pref.Value {
+	return c.c.New()
+}
+
+func (c *methodConverter) Zero() pref.Value {
+	return c.c.Zero()
+}
+
+func (c *methodConverter) IsZeroValue(v pref.Value) bool {
+	return c.c.IsZeroValue(v)
+}
+
+func (c *methodConverter) Pretty(v pref.Value) string {
+	return c.c.Pretty(v)
+}
+
+func (c *methodConverter) FieldNumber() protowire.Number {
+	return c.c.FieldNumber()
+}
+
+// readOnlyConverter is a funcConverter whose Go values cannot be
+// reconstructed. Its toGo function reports ErrReadOnly,
+// and its GoValueOf method is a no-op.
+type readOnlyConverter struct {
+	*funcConverter
+}
+
+func newReadOnlyConverter(c *funcConverter, fd pref.FieldDescriptor) *readOnlyConverter {
+	err := readOnlyError(c.goType, fd)
+	c.toGo = func(pref.Value) (reflect.Value, error) {
+		return reflect.Value{}, err
+	}
+	return &readOnlyConverter{c}
+}
+
+// GoValueOf returns the zero value of the Go type.
+func (c *readOnlyConverter) GoValueOf(v pref.Value) reflect.Value {
+	return reflect.Zero(c.goType)
+}
+
+var contextType = reflect.TypeOf((*context.Context)(nil)).Elem()
+
+// NewContextConverter returns a read-only Converter that populates
+// a map<string, string> field with values carried by a context.Context.
+// Each entry of keys maps a map key to the context key it is extracted from.
+// Values are formatted with fmt.Sprint, and keys without a value are omitted.
+func NewContextConverter(keys map[string]interface{}, fd pref.FieldDescriptor) TryConverter {
+	if !fd.IsMap() || fd.MapKey().Kind() != pref.StringKind || fd.MapValue().Kind() != pref.StringKind {
+		panic(fmt.Sprintf("invalid field %v for context values: want map<string, string>", fd.FullName()))
+	}
+	pb := ConverterOptions{}.newMapConverter(reflect.TypeOf(map[string]string(nil)), fd)
+	// A context cannot be reconstructed from its values.
+	return newReadOnlyConverter(&funcConverter{
+		goType: contextType,
+		pb:     pb,
+		toPB: func(v reflect.Value) (pref.Value, error) {
+			m := make(map[string]string)
+			if ctx, _ := v.Interface().(context.Context); ctx != nil {
+				for name, key := range keys {
+					if x := ctx.Value(key); x != nil {
+						m[name] = fmt.Sprint(x)
+					}
+				}
+			}
+			return pb.PBValueOf(reflect.ValueOf(m)), nil
+		},
+	}, fd)
+}
+
+// NewClosedChanConverter returns a read-only TryConverter that populates
+// a bool field with whether a channel of type t, such as a chan struct{}
+// used as a done signal, is closed. A nil or open channel converts to false.
+//
+// Values buffered on the channel are left in place, and the channel converts
+// to false until they are received, as a receiver would not yet observe the
+// close. Otherwise the channel is polled with a non-blocking receive, which
+// can only take a value from a blocked sender of an unbuffered channel;
+// since t should be a channel that is only ever closed, that is reported
+// by TryPBValueOf.
+func NewClosedChanConverter(t reflect.Type, fd pref.FieldDescriptor) TryConverter {
+	if t.Kind() != reflect.Chan || t.ChanDir()&reflect.RecvDir == 0 || t.Elem().Kind() != reflect.Struct || t.Elem().NumField() != 0 {
+		panic(fmt.Sprintf("invalid Go type %v for field %v: want chan struct{}", t, fd.FullName()))
+	}
+	if fd.Kind() != pref.BoolKind || fd.IsList() {
+		panic(fmt.Sprintf("invalid field %v for closed channel: got %v, want bool", fd.FullName(), fd.Kind()))
+	}
+	// A channel cannot be closed by setting the field.
+	return newReadOnlyConverter(&funcConverter{
+		goType: t,
+		pb:     newSingularConverter(boolType, fd),
+		toPB: func(v reflect.Value) (pref.Value, error) {
+			if v.IsNil() || v.Len() > 0 {
+				return pref.ValueOfBool(false), nil
+			}
+			x, ok := v.TryRecv()
+			if ok {
+				return pref.Value{}, fmt.Errorf("received a value from channel for field %v: want a channel that is only closed", fd.FullName())
+			}
+			return pref.ValueOfBool(x.IsValid()), nil
+		},
+	}, fd)
+}
+
+// NewComputedListConverter returns a read-only TryConverter that populates
+// a repeated field with the slice returned by a Go function type t of the
+// form func() []T, such as a derived collection computed when the message
+// is serialized. The function is called on every conversion, and a nil
+// function converts to an empty list.
+func NewComputedListConverter(t reflect.Type, fd pref.FieldDescriptor) TryConverter {
+	if t.Kind() != reflect.Func || t.NumIn() != 0 || t.NumOut() != 1 || t.IsVariadic() || t.Out(0).Kind() != reflect.Slice {
+		panic(fmt.Sprintf("invalid Go type %v for field %v: want func() returning a slice", t, fd.FullName()))
+	}
+	if !fd.IsList() {
+		panic(fmt.Sprintf("invalid field %v for computed list: want repeated field", fd.FullName()))
+	}
+	pb := ConverterOptions{}.newListConverter(t.Out(0), fd)
+	// A computed list cannot be set.
+	return newReadOnlyConverter(&funcConverter{
+		goType: t,
+		pb:     pb,
+		toPB: func(v reflect.Value) (pref.Value, error) {
+			if v.IsNil() {
+				return pb.Zero(), nil
+			}
+			return pb.PBValueOf(v.Call(nil)[0]), nil
+		},
+	}, fd)
+}
+
+var osFileType = reflect.TypeOf((*os.File)(nil))
+
+// NewFileDescriptorConverter returns a read-only TryConverter that populates
+// an int32 field with the file descriptor of an *os.File, for diagnostics
+// such as process snapshots. A nil or closed file converts to -1,
+// and descriptors that overflow an int32, such as Windows handles,
+// are reported by TryPBValueOf. As documented by os.File.Fd, obtaining
+// the descriptor may put the file into blocking mode.
+func NewFileDescriptorConverter(fd pref.FieldDescriptor) TryConverter {
+	if scalarGoType(fd.Kind()) != int32Type || fd.IsList() {
+		panic(fmt.Sprintf("invalid field %v for file descriptor: got %v, want int32", fd.FullName(), fd.Kind()))
+	}
+	// A file cannot be reopened from its descriptor.
+	return newReadOnlyConverter(&funcConverter{
+		goType: osFileType,
+		pb:     newSingularConverter(int32Type, fd),
+		toPB: func(v reflect.Value) (pref.Value, error) {
+			f := v.Interface().(*os.File)
+			if f == nil {
+				return pref.ValueOfInt32(-1), nil
+			}
+			n := f.Fd()
+			if n == ^uintptr(0) {
+				return pref.ValueOfInt32(-1), nil // closed
+			}
+			if n > math.MaxInt32 {
+				return pref.Value{}, fmt.Errorf("file descriptor %d for field %v overflows int32", n, fd.FullName())
+			}
+			return pref.ValueOfInt32(int32(n)), nil
+		},
+	}, fd)
+}
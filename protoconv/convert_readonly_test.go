@@ -0,0 +1,240 @@
+package protoconv_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"reflect"
+	"runtime"
+	"testing"
+
+	"github.com/dnscrypt/dnscrypt-proxy/protoconv"
+	pref "google.golang.org/protobuf/reflect/protoreflect"
+)
+
+type account struct {
+	balance int
+}
+
+func (a account) Overdrawn() bool { return a.balance < 0 }
+
+func TestMethodConverter(t *testing.T) {
+	c := protoconv.NewMethodConverter(reflect.TypeOf(account{}), "Overdrawn", field("ob"))
+	tests := []struct {
+		in   account
+		want bool
+	}{
+		{account{balance: 10}, false},
+		{account{balance: 0}, false},
+		{account{balance: -1}, true},
+	}
+	for _, tt := range tests {
+		if got := c.PBValueOf(reflect.ValueOf(tt.in)).Bool(); got != tt.want {
+			t.Errorf("PBValueOf(%+v) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+	if got, want := c.FieldNumber(), field("ob").Number(); got != want {
+		t.Errorf("FieldNumber() = %v, want %v", got, want)
+	}
+}
+
+func TestMethodConverterReadOnly(t *testing.T) {
+	c := protoconv.NewMethodConverter(reflect.TypeOf(account{}), "Overdrawn", field("ob"))
+	if _, err := c.TryGoValueOf(pref.ValueOfBool(true)); !errors.Is(err, protoconv.ErrReadOnly) {
+		t.Errorf("TryGoValueOf error = %v, want %v", err, protoconv.ErrReadOnly)
+	}
+	if got := c.GoValueOf(pref.ValueOfBool(true)).Interface(); got != (account{}) {
+		t.Errorf("GoValueOf = %+v, want the zero value", got)
+	}
+}
+
+func TestMethodConverterInvalid(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+	}{
+		{"missing method", "Balance"},
+		{"method with arguments", "Deposit"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				if r := recover(); r == nil {
+					t.Errorf("NewMethodConverter(%q) did not panic", tt.method)
+				}
+			}()
+			protoconv.NewMethodConverter(reflect.TypeOf(&wallet{}), tt.method, field("ob"))
+		})
+	}
+}
+
+type wallet struct{ n int }
+
+func (w *wallet) Deposit(n int) bool { w.n += n; return true }
+
+type contextKey string
+
+func TestContextConverter(t *testing.T) {
+	c := protoconv.NewContextConverter(map[string]interface{}{
+		"request_id": contextKey("request-id"),
+		"user":       contextKey("user"),
+		"attempt":    contextKey("attempt"),
+	}, field("mss"))
+
+	ctx := context.WithValue(context.Background(), contextKey("request-id"), "abc")
+	ctx = context.WithValue(ctx, contextKey("attempt"), 2)
+	m := c.PBValueOf(reflect.ValueOf(&ctx).Elem()).Map()
+	got := make(map[string]string)
+	m.Range(func(k pref.MapKey, v pref.Value) bool {
+		got[k.String()] = v.String()
+		return true
+	})
+	if want := map[string]string{"request_id": "abc", "attempt": "2"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("PBValueOf = %v, want %v", got, want)
+	}
+
+	if _, err := c.TryGoValueOf(pref.ValueOfMap(m)); !errors.Is(err, protoconv.ErrReadOnly) {
+		t.Errorf("TryGoValueOf error = %v, want %v", err, protoconv.ErrReadOnly)
+	}
+	if got := c.GoValueOf(pref.ValueOfMap(m)); !got.IsNil() {
+		t.Errorf("GoValueOf = %v, want nil", got)
+	}
+}
+
+func TestClosedChanConverter(t *testing.T) {
+	open := make(chan struct{})
+	closed := make(chan struct{})
+	close(closed)
+	pending := make(chan struct{}, 1)
+	pending <- struct{}{}
+	close(pending)
+	var nilChan chan struct{}
+	tests := []struct {
+		name string
+		in   chan struct{}
+		want bool
+	}{
+		{"nil", nilChan, false},
+		{"open", open, false},
+		{"closed", closed, true},
+		{"closed with pending value", pending, false},
+	}
+	c := protoconv.NewClosedChanConverter(reflect.TypeOf(open), field("ob"))
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v, err := c.TryPBValueOf(reflect.ValueOf(tt.in))
+			if err != nil || v.Bool() != tt.want {
+				t.Errorf("TryPBValueOf = %v, %v, want %v", v, err, tt.want)
+			}
+		})
+	}
+	if len(pending) != 1 {
+		t.Errorf("pending value was consumed")
+	}
+
+	if _, err := c.TryGoValueOf(pref.ValueOfBool(true)); !errors.Is(err, protoconv.ErrReadOnly) {
+		t.Errorf("TryGoValueOf error = %v, want %v", err, protoconv.ErrReadOnly)
+	}
+	if got := c.GoValueOf(pref.ValueOfBool(true)); !got.IsNil() {
+		t.Errorf("GoValueOf = %v, want nil", got)
+	}
+}
+
+func TestClosedChanConverterSender(t *testing.T) {
+	ch := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		ch <- struct{}{}
+		close(done)
+	}()
+	c := protoconv.NewClosedChanConverter(reflect.TypeOf(ch), field("ob"))
+	for {
+		_, err := c.TryPBValueOf(reflect.ValueOf(ch))
+		if err != nil {
+			break
+		}
+		runtime.Gosched()
+	}
+	<-done
+}
+
+func TestComputedListConverter(t *testing.T) {
+	calls := 0
+	compute := func() []string {
+		calls++
+		return []string{"a", "b"}
+	}
+	empty := func() []string { return nil }
+	typ := reflect.TypeOf(compute)
+	tests := []struct {
+		name string
+		in   func() []string
+		want []string
+	}{
+		{"computed", compute, []string{"a", "b"}},
+		{"nil result", empty, []string{}},
+		{"nil function", nil, []string{}},
+	}
+	c := protoconv.NewComputedListConverter(typ, field("rs"))
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v, err := c.TryPBValueOf(reflect.ValueOf(tt.in))
+			if err != nil {
+				t.Fatalf("TryPBValueOf error: %v", err)
+			}
+			if got := listStrings(v.List()); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("TryPBValueOf = %q, want %q", got, tt.want)
+			}
+		})
+	}
+	if calls != 1 {
+		t.Errorf("function called %d times, want 1", calls)
+	}
+
+	v := c.PBValueOf(reflect.ValueOf(compute))
+	if _, err := c.TryGoValueOf(v); !errors.Is(err, protoconv.ErrReadOnly) {
+		t.Errorf("TryGoValueOf error = %v, want %v", err, protoconv.ErrReadOnly)
+	}
+	if got := c.GoValueOf(v); !got.IsNil() {
+		t.Errorf("GoValueOf = %v, want nil", got)
+	}
+}
+
+func TestFileDescriptorConverter(t *testing.T) {
+	f, err := os.CreateTemp(t.TempDir(), "fd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fd := int64(f.Fd())
+	closed, err := os.CreateTemp(t.TempDir(), "closed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	closed.Close()
+	defer f.Close()
+
+	c := protoconv.NewFileDescriptorConverter(field("i32"))
+	tests := []struct {
+		name string
+		in   *os.File
+		want int64
+	}{
+		{"open", f, fd},
+		{"closed", closed, -1},
+		{"nil", nil, -1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v, err := c.TryPBValueOf(reflect.ValueOf(tt.in))
+			if err != nil || v.Int() != tt.want {
+				t.Errorf("TryPBValueOf = %v, %v, want %d", v, err, tt.want)
+			}
+			if _, err := c.TryGoValueOf(v); !errors.Is(err, protoconv.ErrReadOnly) {
+				t.Errorf("TryGoValueOf error = %v, want %v", err, protoconv.ErrReadOnly)
+			}
+			if got := c.GoValueOf(v); !got.IsNil() {
+				t.Errorf("GoValueOf = %v, want nil", got)
+			}
+		})
+	}
+}
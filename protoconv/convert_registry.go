@@ -0,0 +1,158 @@
+package protoconv
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+
+	pref "google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// converterKey identifies a registered Converter.
+type converterKey struct {
+	goType reflect.Type
+	field  pref.FullName
+}
+
+var (
+	converterRegistry sync.Map // map[converterKey]Converter
+	converterCache    sync.Map // map[converterKey]Converter
+)
+
+// RegisterConverter registers c as the Converter that NewConverter returns
+// for the Go type t and the field with the given full name.
+// It panics if a Converter is already registered for that pair.
+//
+// Registered converters allow Go types that protoc-gen-go never generates
+// to back a field, at the cost of the caller guaranteeing that c is valid
+// for the field's kind and cardinality.
+func RegisterConverter(t reflect.Type, field pref.FullName, c Converter) {
+	if _, loaded := converterRegistry.LoadOrStore(converterKey{t, field}, c); loaded {
+		panic(fmt.Sprintf("converter for Go type %v and field %v already registered", t, field))
+	}
+}
+
+// registeredConverter returns the Converter registered for t and fd, if any.
+func registeredConverter(t reflect.Type, fd pref.FieldDescriptor) Converter {
+	if c, ok := converterRegistry.Load(converterKey{t, fd.FullName()}); ok {
+		return c.(Converter)
+	}
+	return nil
+}
+
+// cachedConverter returns the Converter cached for t and fd by WarmConverters.
+func cachedConverter(t reflect.Type, fd pref.FieldDescriptor) Converter {
+	if c, ok := converterCache.Load(converterKey{t, fd.FullName()}); ok {
+		return c.(Converter)
+	}
+	return nil
+}
+
+// WarmConverters builds and caches the Converter for every field of md
+// backed by the struct that goType points to, so that later calls to
+// NewConverter for those fields do not construct them lazily.
+// Converters must be registered before warming the fields they apply to.
+// Fields that cannot be matched with a struct field or a Go type that
+// NewConverter accepts are skipped and reported in the returned errors.
+func WarmConverters(md pref.MessageDescriptor, goType reflect.Type) []error {
+	if goType.Kind() != reflect.Ptr || goType.Elem().Kind() != reflect.Struct {
+		return []error{fmt.Errorf("invalid Go type %v for message %v: want pointer to struct", goType, md.FullName())}
+	}
+	fieldTypes := structFieldTypes(md, goType)
+
+	var errs []error
+	fds := md.Fields()
+	for i := 0; i < fds.Len(); i++ {
+		fd := fds.Get(i)
+		ft := fieldTypes[fd.Number()]
+		if ft != nil && fd.HasPresence() && fd.Message() == nil && ft.Kind() == reflect.Ptr {
+			ft = ft.Elem() // proto2 and proto3 optional scalars
+		}
+		if ft == nil {
+			errs = append(errs, fmt.Errorf("field %v has no matching field in Go type %v", fd.FullName(), goType))
+			continue
+		}
+		c, err := tryNewConverter(ft, fd)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		converterCache.Store(converterKey{ft, fd.FullName()}, c)
+	}
+	return errs
+}
+
+// tryNewConverter is like NewConverter, but reports unmatched types as errors.
+func tryNewConverter(t reflect.Type, fd pref.FieldDescriptor) (c Converter, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r)
+		}
+	}()
+	return NewConverter(t, fd), nil
+}
+
+// structFieldTypes returns the Go type of the struct field backing every
+// field of md in the struct that goType points to, as matched by the field
+// number in its protobuf struct tag. Fields of a oneof are backed by the
+// only field of their oneof wrapper type, which is found by populating
+// each such field of a new message.
+func structFieldTypes(md pref.MessageDescriptor, goType reflect.Type) map[pref.FieldNumber]reflect.Type {
+	types := make(map[pref.FieldNumber]reflect.Type)
+	st := goType.Elem()
+	for i := 0; i < st.NumField(); i++ {
+		f := st.Field(i)
+		tag := f.Tag.Get("protobuf")
+		if tag == "" {
+			continue
+		}
+		parts := strings.Split(tag, ",")
+		if len(parts) < 2 {
+			continue
+		}
+		if n, err := strconv.ParseInt(parts[1], 10, 32); err == nil {
+			types[pref.FieldNumber(n)] = f.Type
+		}
+	}
+	m, ok := reflect.New(st).Interface().(pref.ProtoMessage)
+	if !ok {
+		return types
+	}
+	oneofs := md.Oneofs()
+	for i := 0; i < oneofs.Len(); i++ {
+		od := oneofs.Get(i)
+		if od.IsSynthetic() {
+			continue
+		}
+		for j := 0; j < od.Fields().Len(); j++ {
+			fd := od.Fields().Get(j)
+			types[fd.Number()] = oneofWrapperFieldType(m, od, fd)
+		}
+	}
+	return types
+}
+
+// oneofWrapperFieldType returns the type of the field of the oneof wrapper
+// that backs fd, or nil if it cannot be determined.
+func oneofWrapperFieldType(m pref.ProtoMessage, od pref.OneofDescriptor, fd pref.FieldDescriptor) (t reflect.Type) {
+	defer func() {
+		if recover() != nil {
+			t = nil
+		}
+	}()
+	mr := m.ProtoReflect()
+	mr.Set(fd, mr.NewField(fd))
+	defer mr.Clear(fd)
+	rv := reflect.ValueOf(m).Elem()
+	for i := 0; i < rv.NumField(); i++ {
+		if rv.Type().Field(i).Tag.Get("protobuf_oneof") != string(od.Name()) {
+			continue
+		}
+		if w := rv.Field(i); !w.IsNil() && w.Elem().Kind() == reflect.Ptr {
+			return w.Elem().Elem().Field(0).Type()
+		}
+	}
+	return nil
+}
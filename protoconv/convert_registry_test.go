@@ -0,0 +1,72 @@
+package protoconv_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/dnscrypt/dnscrypt-proxy/protoconv"
+	pref "google.golang.org/protobuf/reflect/protoreflect"
+)
+
+func TestWarmConverters(t *testing.T) {
+	tests := []struct {
+		name   string
+		md     pref.MessageDescriptor
+		goType reflect.Type
+		fields map[pref.Name]reflect.Type
+	}{{
+		name:   "Blob",
+		md:     (&Blob{}).ProtoReflect().Descriptor(),
+		goType: reflect.TypeOf((*Blob)(nil)),
+		fields: map[pref.Name]reflect.Type{
+			"chunks": reflect.TypeOf([]*Chunk(nil)),
+			"zone":   stringType,
+			"event":  reflect.TypeOf((*Event)(nil)),
+			"events": reflect.TypeOf([]*Event(nil)),
+		},
+	}, {
+		name:   "Value oneof",
+		md:     (&Value{}).ProtoReflect().Descriptor(),
+		goType: reflect.TypeOf((*Value)(nil)),
+		fields: map[pref.Name]reflect.Type{
+			"null_value":   reflect.TypeOf(NullValue(0)),
+			"number_value": reflect.TypeOf(float64(0)),
+			"string_value": stringType,
+			"bool_value":   reflect.TypeOf(false),
+			"struct_value": reflect.TypeOf((*Struct)(nil)),
+			"list_value":   reflect.TypeOf((*ListValue)(nil)),
+		},
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if errs := protoconv.WarmConverters(tt.md, tt.goType); len(errs) > 0 {
+				t.Fatalf("WarmConverters errors: %v", errs)
+			}
+			for name, ft := range tt.fields {
+				fd := tt.md.Fields().ByName(name)
+				if c1, c2 := protoconv.NewConverter(ft, fd), protoconv.NewConverter(ft, fd); c1 != c2 {
+					t.Errorf("NewConverter(%v, %v) is not cached", ft, name)
+				}
+			}
+		})
+	}
+}
+
+func TestWarmConvertersUnmatched(t *testing.T) {
+	type partialBlob struct {
+		Zone string `protobuf:"bytes,2,opt,name=zone,proto3"`
+	}
+	md := (&Blob{}).ProtoReflect().Descriptor()
+	errs := protoconv.WarmConverters(md, reflect.TypeOf((*partialBlob)(nil)))
+	if len(errs) != 4 { // chunks, event, events and body
+		t.Errorf("WarmConverters reported %d errors, want 4: %v", len(errs), errs)
+	}
+	fd := md.Fields().ByName("zone")
+	if c1, c2 := protoconv.NewConverter(stringType, fd), protoconv.NewConverter(stringType, fd); c1 != c2 {
+		t.Errorf("NewConverter(%v, zone) is not cached", stringType)
+	}
+
+	if errs := protoconv.WarmConverters(md, reflect.TypeOf(partialBlob{})); len(errs) != 1 {
+		t.Errorf("WarmConverters of a non-pointer type reported %d errors, want 1", len(errs))
+	}
+}
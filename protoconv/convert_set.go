@@ -0,0 +1,84 @@
+package protoconv
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+
+	pref "google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// NewStringSetConverter returns a Converter between a Go set of strings,
+// such as map[string]struct{}, and a repeated string field holding its keys.
+// Since map iteration order is unspecified, the keys are sorted if sorted
+// is true so that the output is deterministic.
+// Duplicate elements of the list are merged into a single key.
+func NewStringSetConverter(t reflect.Type, sorted bool, fd pref.FieldDescriptor) Converter {
+	if t.Kind() != reflect.Map || t.Key().Kind() != reflect.String || t.Elem().Kind() != reflect.Struct || t.Elem().NumField() != 0 {
+		panic(fmt.Sprintf("invalid Go type %v for field %v: want map[string]struct{}", t, fd.FullName()))
+	}
+	if !fd.IsList() || fd.Kind() != pref.StringKind {
+		panic(fmt.Sprintf("invalid field %v for string set: want repeated string", fd.FullName()))
+	}
+	pb := ConverterOptions{}.newListConverter(reflect.TypeOf([]string(nil)), fd)
+	return &funcConverter{
+		goType: t,
+		pb:     pb,
+		toPB: func(v reflect.Value) (pref.Value, error) {
+			keys := make([]string, 0, v.Len())
+			for iter := v.MapRange(); iter.Next(); {
+				keys = append(keys, iter.Key().String())
+			}
+			if sorted {
+				sort.Strings(keys)
+			}
+			return pb.PBValueOf(reflect.ValueOf(keys)), nil
+		},
+		toGo: func(v pref.Value) (reflect.Value, error) {
+			list := v.List()
+			rv := reflect.MakeMapWithSize(t, list.Len())
+			for i := 0; i < list.Len(); i++ {
+				rv.SetMapIndex(reflect.ValueOf(list.Get(i).String()).Convert(t.Key()), reflect.Zero(t.Elem()))
+			}
+			return rv, nil
+		},
+	}
+}
+
+// NewEnumSetConverter returns a Converter between a Go set of enum values,
+// such as map[MyEnum]bool, and a repeated enum field holding the values
+// in the set, which are the keys mapped to true. The values are sorted
+// by number so that the output is deterministic.
+// Every element of the list is mapped to true.
+func NewEnumSetConverter(t reflect.Type, fd pref.FieldDescriptor) Converter {
+	if t.Kind() != reflect.Map || t.Elem().Kind() != reflect.Bool {
+		panic(fmt.Sprintf("invalid Go type %v for field %v: want map[E]bool", t, fd.FullName()))
+	}
+	if !fd.IsList() || fd.Kind() != pref.EnumKind {
+		panic(fmt.Sprintf("invalid field %v for enum set: want repeated enum", fd.FullName()))
+	}
+	elem := newEnumConverter(t.Key(), fd)
+	pb := ConverterOptions{}.newListConverter(reflect.TypeOf([]pref.EnumNumber(nil)), fd)
+	return &funcConverter{
+		goType: t,
+		pb:     pb,
+		toPB: func(v reflect.Value) (pref.Value, error) {
+			nums := make([]pref.EnumNumber, 0, v.Len())
+			for iter := v.MapRange(); iter.Next(); {
+				if iter.Value().Bool() {
+					nums = append(nums, elem.PBValueOf(iter.Key()).Enum())
+				}
+			}
+			sort.Slice(nums, func(i, j int) bool { return nums[i] < nums[j] })
+			return pb.PBValueOf(reflect.ValueOf(nums)), nil
+		},
+		toGo: func(v pref.Value) (reflect.Value, error) {
+			list := v.List()
+			rv := reflect.MakeMapWithSize(t, list.Len())
+			for i := 0; i < list.Len(); i++ {
+				rv.SetMapIndex(elem.GoValueOf(list.Get(i)), reflect.ValueOf(true).Convert(t.Elem()))
+			}
+			return rv, nil
+		},
+	}
+}
@@ -0,0 +1,84 @@
+package protoconv_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/dnscrypt/dnscrypt-proxy/protoconv"
+	pref "google.golang.org/protobuf/reflect/protoreflect"
+)
+
+var stringSetType = reflect.TypeOf(map[string]struct{}(nil))
+
+// listStrings returns the elements of a list of strings.
+func listStrings(list pref.List) []string {
+	s := make([]string, list.Len())
+	for i := range s {
+		s[i] = list.Get(i).String()
+	}
+	return s
+}
+
+func TestStringSetConverter(t *testing.T) {
+	tests := []struct {
+		name string
+		in   map[string]struct{}
+		want []string
+	}{
+		{"empty", map[string]struct{}{}, []string{}},
+		{"sorted", map[string]struct{}{"c": {}, "a": {}, "b": {}}, []string{"a", "b", "c"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := protoconv.NewStringSetConverter(stringSetType, true, field("rs"))
+			v := c.PBValueOf(reflect.ValueOf(tt.in))
+			if got := listStrings(v.List()); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("PBValueOf(%v) = %q, want %q", tt.in, got, tt.want)
+			}
+			if got := c.GoValueOf(v).Interface(); !reflect.DeepEqual(got, tt.in) {
+				t.Errorf("round trip of %v = %v", tt.in, got)
+			}
+		})
+	}
+}
+
+func TestStringSetConverterDuplicates(t *testing.T) {
+	c := protoconv.NewStringSetConverter(stringSetType, false, field("rs"))
+	v := c.New()
+	for _, s := range []string{"a", "b", "a", "a"} {
+		v.List().Append(pref.ValueOfString(s))
+	}
+	want := map[string]struct{}{"a": {}, "b": {}}
+	if got := c.GoValueOf(v).Interface(); !reflect.DeepEqual(got, want) {
+		t.Errorf("GoValueOf(%q) = %v, want %v", listStrings(v.List()), got, want)
+	}
+}
+
+func TestEnumSetConverter(t *testing.T) {
+	c := protoconv.NewEnumSetConverter(reflect.TypeOf(map[E]bool(nil)), field("re"))
+	tests := []struct {
+		name   string
+		in     map[E]bool
+		want   []pref.EnumNumber
+		wantGo map[E]bool
+	}{
+		{"empty", map[E]bool{}, nil, map[E]bool{}},
+		{"false excluded", map[E]bool{1: false, 2: false}, nil, map[E]bool{}},
+		{"several", map[E]bool{2: true, 0: true, 1: false}, []pref.EnumNumber{0, 2}, map[E]bool{0: true, 2: true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			list := c.PBValueOf(reflect.ValueOf(tt.in)).List()
+			var got []pref.EnumNumber
+			for i := 0; i < list.Len(); i++ {
+				got = append(got, list.Get(i).Enum())
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("PBValueOf(%v) = %v, want %v", tt.in, got, tt.want)
+			}
+			if back := c.GoValueOf(pref.ValueOfList(list)).Interface(); !reflect.DeepEqual(back, tt.wantGo) {
+				t.Errorf("GoValueOf = %v, want %v", back, tt.wantGo)
+			}
+		})
+	}
+}
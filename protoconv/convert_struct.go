@@ -0,0 +1,252 @@
+package protoconv
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"google.golang.org/protobuf/encoding/protowire"
+	pref "google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// scalarGoType returns the Go type natively used for a scalar kind,
+// or nil if k is not a scalar kind.
+func scalarGoType(k pref.Kind) reflect.Type {
+	switch k {
+	case pref.BoolKind:
+		return boolType
+	case pref.Int32Kind, pref.Sint32Kind, pref.Sfixed32Kind:
+		return int32Type
+	case pref.Int64Kind, pref.Sint64Kind, pref.Sfixed64Kind:
+		return int64Type
+	case pref.Uint32Kind, pref.Fixed32Kind:
+		return uint32Type
+	case pref.Uint64Kind, pref.Fixed64Kind:
+		return uint64Type
+	case pref.FloatKind:
+		return float32Type
+	case pref.DoubleKind:
+		return float64Type
+	case pref.StringKind:
+		return stringType
+	case pref.BytesKind:
+		return bytesType
+	}
+	return nil
+}
+
+// scalarClass returns the class of the Go scalar type t: reflect.Int for
+// signed and unsigned integers, reflect.Float64 for floating-point numbers,
+// reflect.Bool, reflect.String, and reflect.Slice for byte slices,
+// or reflect.Invalid for any other type.
+func scalarClass(t reflect.Type) reflect.Kind {
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return reflect.Int
+	case reflect.Float32, reflect.Float64:
+		return reflect.Float64
+	case reflect.Bool, reflect.String:
+		return t.Kind()
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return reflect.Slice
+		}
+	}
+	return reflect.Invalid
+}
+
+// convertScalar converts rv to the Go type t of the same kind class,
+// reporting values that t cannot represent. Values of different classes,
+// such as an int and a string, are reported rather than converted as by
+// reflect.Value.Convert.
+func convertScalar(rv reflect.Value, t reflect.Type) (reflect.Value, error) {
+	if c := scalarClass(t); c == reflect.Invalid || c != scalarClass(rv.Type()) {
+		return reflect.Value{}, fmt.Errorf("cannot convert %v to %v", rv.Type(), t)
+	}
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		switch rv.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			if reflect.Zero(t).OverflowInt(rv.Int()) {
+				return reflect.Value{}, fmt.Errorf("value %d overflows %v", rv.Int(), t)
+			}
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+			if rv.Uint() > 1<<63-1 || reflect.Zero(t).OverflowInt(int64(rv.Uint())) {
+				return reflect.Value{}, fmt.Errorf("value %d overflows %v", rv.Uint(), t)
+			}
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		switch rv.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			if rv.Int() < 0 || reflect.Zero(t).OverflowUint(uint64(rv.Int())) {
+				return reflect.Value{}, fmt.Errorf("value %d overflows %v", rv.Int(), t)
+			}
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+			if reflect.Zero(t).OverflowUint(rv.Uint()) {
+				return reflect.Value{}, fmt.Errorf("value %d overflows %v", rv.Uint(), t)
+			}
+		}
+	case reflect.Float32:
+		if reflect.Zero(t).OverflowFloat(rv.Float()) {
+			return reflect.Value{}, fmt.Errorf("value %g overflows %v", rv.Float(), t)
+		}
+	}
+	return rv.Convert(t), nil
+}
+
+// structFieldConverter converts between a Go struct and a message
+// by matching struct fields with message fields by name.
+type structFieldConverter struct {
+	goType reflect.Type // S or *S
+	mt     pref.MessageType
+	fields []structFieldMapping
+	num    protowire.Number
+}
+
+type structFieldMapping struct {
+	index  []int
+	fd     pref.FieldDescriptor
+	goType reflect.Type // native Go type of fd
+	conv   Converter
+}
+
+// NewStructFieldConverter returns a TryConverter between the Go struct type t
+// (or pointer to struct) and the message field fd. Every exported field of
+// the struct must match exactly one scalar field of the message by name,
+// ignoring case, such as the X and Y fields of image.Point for a message
+// with x and y fields. Values that overflow the destination are reported
+// by the Try methods. It panics if a struct field is missing from the
+// message, matches more than one of its fields, or has a type of a different
+// class than its field: integers match integer fields, floating-point numbers
+// match float and double fields, and bools, strings and byte slices match
+// fields of their own kind, so an int never backs a string field.
+//
+// The message type must be registered in the global registry.
+func NewStructFieldConverter(t reflect.Type, fd pref.FieldDescriptor) TryConverter {
+	st := t
+	if st.Kind() == reflect.Ptr {
+		st = st.Elem()
+	}
+	if st.Kind() != reflect.Struct || fd.Message() == nil || fd.IsList() || fd.IsMap() {
+		panic(fmt.Sprintf("invalid Go type %v for field %v", t, fd.FullName()))
+	}
+	md := fd.Message()
+	mt := findMessageType(fd)
+	c := &structFieldConverter{goType: t, mt: mt, num: fd.Number()}
+	for i := 0; i < st.NumField(); i++ {
+		f := st.Field(i)
+		if f.PkgPath != "" {
+			continue // unexported
+		}
+		var match pref.FieldDescriptor
+		fds := md.Fields()
+		for j := 0; j < fds.Len(); j++ {
+			if strings.EqualFold(string(fds.Get(j).Name()), f.Name) {
+				if match != nil {
+					panic(fmt.Sprintf("invalid Go type %v for field %v: %v matches both %v and %v", t, fd.FullName(), f.Name, match.Name(), fds.Get(j).Name()))
+				}
+				match = fds.Get(j)
+			}
+		}
+		if match == nil {
+			panic(fmt.Sprintf("invalid Go type %v for field %v: %v has no matching field in %v", t, fd.FullName(), f.Name, md.FullName()))
+		}
+		nt := scalarGoType(match.Kind())
+		if nt == nil || match.IsList() || match.IsMap() || scalarClass(f.Type) != scalarClass(nt) {
+			panic(fmt.Sprintf("invalid Go type %v for field %v: %v of type %v cannot be converted to %v", t, fd.FullName(), f.Name, f.Type, match.FullName()))
+		}
+		c.fields = append(c.fields, structFieldMapping{f.Index, match, nt, newSingularConverter(nt, match)})
+	}
+	return c
+}
+
+func (c *structFieldConverter) TryPBValueOf(v reflect.Value) (pref.Value, error) {
+	if v.Type() != c.goType {
+		panic(fmt.Sprintf("invalid type: got %v, want %v", v.Type(), c.goType))
+	}
+	if c.goType.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return pref.ValueOfMessage(c.mt.Zero()), nil
+		}
+		v = v.Elem()
+	}
+	m := c.mt.New()
+	for _, f := range c.fields {
+		rv, err := convertScalar(v.FieldByIndex(f.index), f.goType)
+		if err != nil {
+			return pref.Value{}, fmt.Errorf("field %v: %w", f.fd.FullName(), err)
+		}
+		m.Set(f.fd, f.conv.PBValueOf(rv))
+	}
+	return pref.ValueOfMessage(m), nil
+}
+
+func (c *structFieldConverter) TryGoValueOf(v pref.Value) (reflect.Value, error) {
+	m := v.Message()
+	st := c.goType
+	if st.Kind() == reflect.Ptr {
+		if !m.IsValid() {
+			return reflect.Zero(c.goType), nil
+		}
+		st = st.Elem()
+	}
+	pv := reflect.New(st)
+	for _, f := range c.fields {
+		dst := pv.Elem().FieldByIndex(f.index)
+		rv, err := convertScalar(f.conv.GoValueOf(m.Get(f.fd)), dst.Type())
+		if err != nil {
+			return reflect.Value{}, fmt.Errorf("field %v: %w", f.fd.FullName(), err)
+		}
+		dst.Set(rv)
+	}
+	if c.goType.Kind() == reflect.Ptr {
+		return pv, nil
+	}
+	return pv.Elem(), nil
+}
+
+func (c *structFieldConverter) PBValueOf(v reflect.Value) pref.Value {
+	pv, err := c.TryPBValueOf(v)
+	if err != nil {
+		panic(err)
+	}
+	return pv
+}
+
+func (c *structFieldConverter) GoValueOf(v pref.Value) reflect.Value {
+	rv, err := c.TryGoValueOf(v)
+	if err != nil {
+		panic(err)
+	}
+	return rv
+}
+
+func (c *structFieldConverter) IsValidPB(v pref.Value) bool {
+	m, ok := v.Interface().(pref.Message)
+	return ok && m.Descriptor().FullName() == c.mt.Descriptor().FullName()
+}
+
+func (c *structFieldConverter) IsValidGo(v reflect.Value) bool {
+	return v.IsValid() && v.Type() == c.goType
+}
+
+func (c *structFieldConverter) New() pref.Value {
+	return pref.ValueOfMessage(c.mt.New())
+}
+
+func (c *structFieldConverter) Zero() pref.Value {
+	return pref.ValueOfMessage(c.mt.Zero())
+}
+
+func (c *structFieldConverter) IsZeroValue(v pref.Value) bool {
+	return !v.Message().IsValid()
+}
+
+func (c *structFieldConverter) Pretty(v pref.Value) string {
+	return prettyMessage(v.Message())
+}
+
+func (c *structFieldConverter) FieldNumber() protowire.Number {
+	return c.num
+}
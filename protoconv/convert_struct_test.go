@@ -0,0 +1,80 @@
+package protoconv_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/dnscrypt/dnscrypt-proxy/protoconv"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// interval is an image.Point-style struct matching google.protobuf.Duration.
+type interval struct {
+	Seconds int
+	Nanos   int
+	unit    string // unexported, so not converted
+}
+
+func TestStructFieldConverter(t *testing.T) {
+	tests := []struct {
+		name string
+		t    reflect.Type
+		in   interface{}
+	}{
+		{"value", reflect.TypeOf(interval{}), interval{Seconds: 3, Nanos: 500}},
+		{"pointer", reflect.TypeOf((*interval)(nil)), &interval{Seconds: -1, Nanos: -2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := protoconv.NewStructFieldConverter(tt.t, field("dur"))
+			v, err := c.TryPBValueOf(reflect.ValueOf(tt.in))
+			if err != nil {
+				t.Fatalf("TryPBValueOf(%v) error: %v", tt.in, err)
+			}
+			d := v.Message().Interface().(*durationpb.Duration)
+			want := reflect.Indirect(reflect.ValueOf(tt.in)).Interface().(interval)
+			if d.Seconds != int64(want.Seconds) || d.Nanos != int32(want.Nanos) {
+				t.Errorf("TryPBValueOf(%v) = %v", tt.in, d)
+			}
+			got, err := c.TryGoValueOf(v)
+			if err != nil {
+				t.Fatalf("TryGoValueOf error: %v", err)
+			}
+			if !reflect.DeepEqual(got.Interface(), tt.in) {
+				t.Errorf("round trip = %v, want %v", got, tt.in)
+			}
+		})
+	}
+}
+
+func TestStructFieldConverterOverflow(t *testing.T) {
+	c := protoconv.NewStructFieldConverter(reflect.TypeOf(interval{}), field("dur"))
+	if _, err := c.TryPBValueOf(reflect.ValueOf(interval{Nanos: 1 << 40})); err == nil {
+		t.Error("TryPBValueOf with overflowing nanos succeeded, want error")
+	}
+}
+
+func TestStructFieldConverterInvalid(t *testing.T) {
+	tests := []struct {
+		name  string
+		field string
+		t     reflect.Type
+	}{
+		{"name mismatch", "dur", reflect.TypeOf(struct{ Seconds, Millis int }{})},
+		{"wrong type", "dur", reflect.TypeOf(struct{ Seconds []int }{})},
+		{"int to string", "sw", reflect.TypeOf(struct{ Value int }{})},
+		{"string to int", "dur", reflect.TypeOf(struct{ Seconds string }{})},
+		{"float to int", "dur", reflect.TypeOf(struct{ Seconds float64 }{})},
+		{"not a struct", "dur", reflect.TypeOf(0)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				if r := recover(); r == nil {
+					t.Errorf("NewStructFieldConverter(%v) did not panic", tt.t)
+				}
+			}()
+			protoconv.NewStructFieldConverter(tt.t, field(tt.field))
+		})
+	}
+}
@@ -0,0 +1,380 @@
+package protoconv_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/dnscrypt/dnscrypt-proxy/protoconv"
+	pref "google.golang.org/protobuf/reflect/protoreflect"
+)
+
+var stringType = reflect.TypeOf("")
+
+// presenceConverter is implemented by converters that distinguish
+// values that are considered set.
+type presenceConverter interface {
+	GoValueOfPresence(pref.Value) (reflect.Value, bool)
+}
+
+func TestEmptyStringAbsent(t *testing.T) {
+	tests := []struct {
+		name        string
+		opts        protoconv.ConverterOptions
+		in          string
+		wantValid   bool
+		wantPresent bool
+	}{
+		{"default empty", protoconv.ConverterOptions{}, "", true, true},
+		{"default non-empty", protoconv.ConverterOptions{}, "x", true, true},
+		{"absent empty", protoconv.ConverterOptions{EmptyStringAbsent: true}, "", false, false},
+		{"absent non-empty", protoconv.ConverterOptions{EmptyStringAbsent: true}, "x", true, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := tt.opts.New(stringType, field("s"))
+			v := pref.ValueOfString(tt.in)
+			if got := c.IsValidPB(v); got != tt.wantValid {
+				t.Errorf("IsValidPB(%q) = %v, want %v", tt.in, got, tt.wantValid)
+			}
+			rv, present := c.(presenceConverter).GoValueOfPresence(v)
+			if rv.String() != tt.in || present != tt.wantPresent {
+				t.Errorf("GoValueOfPresence(%q) = %q, %v, want %q, %v", tt.in, rv.String(), present, tt.in, tt.wantPresent)
+			}
+		})
+	}
+}
+
+func TestUint64Bytes(t *testing.T) {
+	c := protoconv.NewConverter(reflect.TypeOf(uint64(0)), field("u64")).(interface {
+		Bytes(pref.Value) []byte
+	})
+	for _, x := range []uint64{0, 1, 0x0102030405060708, math.MaxUint64} {
+		want := make([]byte, 8)
+		binary.LittleEndian.PutUint64(want, x)
+		if got := c.Bytes(pref.ValueOfUint64(x)); !bytes.Equal(got, want) {
+			t.Errorf("Bytes(%#x) = %x, want %x", x, got, want)
+		}
+	}
+}
+
+type wrappedEvent struct {
+	*Event
+	extra int
+}
+
+func TestEmbeddedMessageConverter(t *testing.T) {
+	wt := reflect.TypeOf((*wrappedEvent)(nil))
+	c := protoconv.NewConverter(wt, blobField("event"))
+
+	e := &Event{Zone: "UTC"}
+	v := c.PBValueOf(reflect.ValueOf(&wrappedEvent{Event: e, extra: 1}))
+	if got := v.Message().Interface(); got != e {
+		t.Errorf("PBValueOf message = %v, want the embedded %v", got, e)
+	}
+	if !c.IsValidPB(v) {
+		t.Errorf("IsValidPB(%v) = false, want true", v)
+	}
+
+	rv := c.GoValueOf(v)
+	if rv.Type() != wt {
+		t.Fatalf("GoValueOf type = %v, want %v", rv.Type(), wt)
+	}
+	if w := rv.Interface().(*wrappedEvent); w.Event != e || w.extra != 0 {
+		t.Errorf("GoValueOf = %+v, want a new wrapper of %v", w, e)
+	}
+
+	if _, ok := c.GoValueOf(c.New()).Interface().(*wrappedEvent); !ok {
+		t.Errorf("New did not unwrap to %v", wt)
+	}
+}
+
+func TestLenientFloat64List(t *testing.T) {
+	ft := reflect.TypeOf([]float64(nil))
+	c := protoconv.ConverterOptions{Lenient: true}.New(ft, field("rf"))
+	in := []float64{0, 1.5, 0.1, math.MaxFloat32}
+	list := c.PBValueOf(reflect.ValueOf(in)).List()
+	for i, x := range in {
+		if got, want := list.Get(i).Float(), float64(float32(x)); got != want {
+			t.Errorf("element %d = %v, want %v", i, got, want)
+		}
+	}
+	if got := list.Get(2).Float(); got == 0.1 {
+		t.Errorf("element 2 = %v, want it rounded to float32", got)
+	}
+
+	dst := c.New().List()
+	for i := 0; i < list.Len(); i++ {
+		dst.Append(list.Get(i))
+	}
+	got := c.GoValueOf(pref.ValueOfList(dst)).Interface().([]float64)
+	want := []float64{0, 1.5, float64(float32(0.1)), math.MaxFloat32}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("round trip = %v, want %v", got, want)
+	}
+
+	defer func() {
+		if r := recover(); r == nil {
+			t.Errorf("NewConverter(%v) without Lenient did not panic", ft)
+		}
+	}()
+	protoconv.NewConverter(ft, field("rf"))
+}
+
+// labelEnum is an enum type that is not of an integer kind,
+// so it can only be converted by its Number method.
+type labelEnum string
+
+func (e labelEnum) Number() pref.EnumNumber {
+	switch e {
+	case "my":
+		return 1
+	case "other":
+		return 2
+	}
+	return 0
+}
+
+// countingEnum is an enum type of an integer kind that records calls
+// to its Number method, which the converter does not need.
+type countingEnum int32
+
+var countingEnumCalls int
+
+func (e countingEnum) Number() pref.EnumNumber {
+	countingEnumCalls++
+	return pref.EnumNumber(e)
+}
+
+func TestEnumNumberMethod(t *testing.T) {
+	tests := []struct {
+		in   interface{}
+		want pref.EnumNumber
+	}{
+		{labelEnum("my"), 1},
+		{labelEnum("other"), 2},
+		{labelEnum("unknown"), 0},
+		{countingEnum(2), 2},
+	}
+	for _, tt := range tests {
+		c := protoconv.NewConverter(reflect.TypeOf(tt.in), field("e"))
+		if got := c.PBValueOf(reflect.ValueOf(tt.in)).Enum(); got != tt.want {
+			t.Errorf("PBValueOf(%v) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+	if countingEnumCalls != 0 {
+		t.Errorf("Number called %d times for an int32 enum, want 0", countingEnumCalls)
+	}
+}
+
+func TestIsZeroValueDefaults(t *testing.T) {
+	tests := []struct {
+		field string
+		t     reflect.Type
+		in    pref.Value
+		want  bool
+	}{
+		{"i32", reflect.TypeOf(int32(0)), pref.ValueOfInt32(7), true},
+		{"i32", reflect.TypeOf(int32(0)), pref.ValueOfInt32(0), false},
+		{"s", stringType, pref.ValueOfString("hi"), true},
+		{"s", stringType, pref.ValueOfString(""), false},
+		{"d", reflect.TypeOf(float64(0)), pref.ValueOfFloat64(math.NaN()), true},
+		{"d", reflect.TypeOf(float64(0)), pref.ValueOfFloat64(0), false},
+		{"level", reflect.TypeOf(pref.EnumNumber(0)), pref.ValueOfEnum(2), true},
+		{"level", reflect.TypeOf(pref.EnumNumber(0)), pref.ValueOfEnum(1), false},
+		{"b", reflect.TypeOf([]byte(nil)), pref.ValueOfBytes([]byte("ab")), true},
+		{"b", reflect.TypeOf([]byte(nil)), pref.ValueOfBytes(nil), false},
+	}
+	for _, tt := range tests {
+		c := protoconv.NewConverter(tt.t, defaultField(tt.field))
+		if got := c.IsZeroValue(tt.in); got != tt.want {
+			t.Errorf("IsZeroValue(%v) for field %v = %v, want %v", tt.in, tt.field, got, tt.want)
+		}
+	}
+}
+
+func TestIsZeroValueComposites(t *testing.T) {
+	list := protoconv.NewConverter(reflect.TypeOf([]string(nil)), field("rs"))
+	if !list.IsZeroValue(list.New()) {
+		t.Error("IsZeroValue(empty list) = false, want true")
+	}
+	if list.IsZeroValue(list.PBValueOf(reflect.ValueOf([]string{""}))) {
+		t.Error("IsZeroValue(non-empty list) = true, want false")
+	}
+	msg := protoconv.NewConverter(reflect.TypeOf((*Event)(nil)), blobField("event"))
+	if !msg.IsZeroValue(msg.Zero()) {
+		t.Error("IsZeroValue(nil message) = false, want true")
+	}
+	if msg.IsZeroValue(msg.New()) {
+		t.Error("IsZeroValue(empty message) = true, want false")
+	}
+}
+
+// eventRef implements the message methods with a value receiver.
+type eventRef struct{ e *Event }
+
+func (r eventRef) ProtoReflect() pref.Message { return r.e.ProtoReflect() }
+
+func TestValueReceiverMessage(t *testing.T) {
+	c := protoconv.NewConverter(reflect.TypeOf(eventRef{}), blobField("event"))
+	e := &Event{Zone: "UTC"}
+	v := reflect.ValueOf(map[string]eventRef{"a": {e}}).MapIndex(reflect.ValueOf("a"))
+	if v.CanAddr() {
+		t.Fatal("map value is addressable")
+	}
+	if got := c.PBValueOf(v).Message().Interface(); got != e {
+		t.Errorf("PBValueOf = %v, want %v", got, e)
+	}
+}
+
+// seededConverter is implemented by composite converters.
+type seededConverter interface {
+	NewFromGo(reflect.Value) pref.Value
+}
+
+func TestNewFromGoIndependent(t *testing.T) {
+	t.Run("list", func(t *testing.T) {
+		seed := []string{"a", "b"}
+		c := protoconv.NewConverter(reflect.TypeOf(seed), field("rs")).(seededConverter)
+		list := c.NewFromGo(reflect.ValueOf(seed)).List()
+		list.Set(0, pref.ValueOfString("x"))
+		seed[1] = "y"
+		if seed[0] != "a" || list.Get(1).String() != "b" {
+			t.Errorf("seed %q and copy %v are not independent", seed, list)
+		}
+	})
+	t.Run("map", func(t *testing.T) {
+		seed := map[string]string{"k": "a"}
+		c := protoconv.NewConverter(reflect.TypeOf(seed), field("mss")).(seededConverter)
+		m := c.NewFromGo(reflect.ValueOf(seed)).Map()
+		m.Set(pref.ValueOfString("k").MapKey(), pref.ValueOfString("x"))
+		seed["other"] = "y"
+		if seed["k"] != "a" || m.Len() != 1 {
+			t.Errorf("seed %v and copy with %d entries are not independent", seed, m.Len())
+		}
+	})
+	t.Run("message", func(t *testing.T) {
+		seed := &Event{Zone: "UTC"}
+		c := protoconv.NewConverter(reflect.TypeOf(seed), blobField("event")).(seededConverter)
+		e := c.NewFromGo(reflect.ValueOf(seed)).Message().Interface().(*Event)
+		e.Zone = "CET"
+		if seed.Zone != "UTC" || e == seed {
+			t.Errorf("seed %v and copy %v are not independent", seed, e)
+		}
+	})
+	t.Run("list of messages", func(t *testing.T) {
+		seed := []*Chunk{{Data: []byte("a")}}
+		c := protoconv.NewConverter(reflect.TypeOf(seed), blobField("chunks")).(seededConverter)
+		list := c.NewFromGo(reflect.ValueOf(seed)).List()
+		list.Get(0).Message().Interface().(*Chunk).Data[0] = 'x'
+		if string(seed[0].Data) != "a" {
+			t.Errorf("seed element changed to %q through the copy", seed[0].Data)
+		}
+	})
+}
+
+func TestPointerScalars(t *testing.T) {
+	b, i, s, d := true, int32(-3), "x", 2.5
+	tests := []struct {
+		name    string
+		fd      pref.FieldDescriptor
+		in      interface{}
+		wantNil interface{} // the field value of a nil pointer
+	}{
+		{"bool", field("ob"), &b, false},
+		{"int32", field("i32"), &i, int32(0)},
+		{"string", field("s"), &s, ""},
+		{"float64", field("d"), &d, float64(0)},
+		{"int32 with default", defaultField("i32"), &i, int32(7)},
+		{"string with default", defaultField("s"), &s, "hi"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			in := reflect.ValueOf(tt.in)
+			c := protoconv.NewConverter(in.Type(), tt.fd)
+			v := c.PBValueOf(in)
+			if got, want := v.Interface(), in.Elem().Interface(); got != want {
+				t.Errorf("PBValueOf(%v) = %v, want %v", in.Elem(), got, want)
+			}
+			back := c.GoValueOf(v)
+			if back.Pointer() == in.Pointer() || !reflect.DeepEqual(back.Interface(), tt.in) {
+				t.Errorf("GoValueOf(%v) = %v, want a new pointer to %v", v, back, in.Elem())
+			}
+
+			nv := c.PBValueOf(reflect.Zero(in.Type()))
+			if got := nv.Interface(); got != tt.wantNil {
+				t.Errorf("PBValueOf(nil) = %v, want %v", got, tt.wantNil)
+			}
+			if !c.IsZeroValue(nv) {
+				t.Errorf("IsZeroValue(PBValueOf(nil)) = false, want true")
+			}
+			if got := c.GoValueOf(nv); got.IsNil() || got.Elem().Interface() != tt.wantNil {
+				t.Errorf("GoValueOf(PBValueOf(nil)) = %v, want a pointer to %v", got, tt.wantNil)
+			}
+		})
+	}
+}
+
+func TestNilListAbsent(t *testing.T) {
+	typ := reflect.TypeOf([]string(nil))
+	tests := []struct {
+		name        string
+		opts        protoconv.ConverterOptions
+		in          []string
+		wantValid   bool
+		wantNil     bool
+		wantPresent bool
+	}{
+		{"nil", protoconv.ConverterOptions{NilListAbsent: true}, nil, false, true, false},
+		{"empty", protoconv.ConverterOptions{NilListAbsent: true}, []string{}, true, false, true},
+		{"non-empty", protoconv.ConverterOptions{NilListAbsent: true}, []string{"a"}, true, false, true},
+		{"nil by default", protoconv.ConverterOptions{}, nil, true, true, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := tt.opts.New(typ, field("rs"))
+			v := c.PBValueOf(reflect.ValueOf(tt.in))
+			if got := v.List().IsValid(); got != tt.wantValid {
+				t.Errorf("PBValueOf(%#v).List().IsValid() = %v, want %v", tt.in, got, tt.wantValid)
+			}
+			rv, present := c.(presenceConverter).GoValueOfPresence(v)
+			if rv.IsNil() != tt.wantNil || present != tt.wantPresent {
+				t.Errorf("GoValueOfPresence = %#v, %v, want nil %v, present %v", rv.Interface(), present, tt.wantNil, tt.wantPresent)
+			}
+			if got := c.GoValueOf(v).Interface(); !reflect.DeepEqual(got, tt.in) {
+				t.Errorf("round trip of %#v = %#v", tt.in, got)
+			}
+		})
+	}
+}
+
+func TestFieldNumber(t *testing.T) {
+	tests := []struct {
+		name string
+		c    protoconv.Converter
+		fd   pref.FieldDescriptor
+	}{
+		{"string", protoconv.NewConverter(stringType, field("s")), field("s")},
+		{"int32", protoconv.NewConverter(reflect.TypeOf(int32(0)), field("i32")), field("i32")},
+		{"pointer", protoconv.NewConverter(reflect.TypeOf(new(int32)), field("i32")), field("i32")},
+		{"enum", protoconv.NewConverter(reflect.TypeOf(E(0)), field("e")), field("e")},
+		{"enum name", protoconv.EnumNameOptions{}.New(stringType, field("e")), field("e")},
+		{"message", protoconv.NewConverter(reflect.TypeOf(new(Event)), blobField("event")), blobField("event")},
+		{"list", protoconv.NewConverter(reflect.TypeOf([]string(nil)), field("rs")), field("rs")},
+		{"message list", protoconv.NewConverter(reflect.TypeOf([]*Event(nil)), blobField("events")), blobField("events")},
+		{"map", protoconv.NewConverter(reflect.TypeOf(map[string]string(nil)), field("mss")), field("mss")},
+		{"map with", protoconv.NewMapConverterWith(reflect.TypeOf(map[string]string(nil)), nil, nil, field("mss")), field("mss")},
+		{"timestamp", protoconv.NewTimestampConverter(field("ts")), field("ts")},
+		{"timestamp list", protoconv.NewTimestampListConverter(field("rts")), field("rts")},
+		{"unix string", protoconv.NewUnixStringConverter(field("s")), field("s")},
+		{"duration unit", protoconv.NewDurationUnitConverter(time.Second, field("i64")), field("i64")},
+	}
+	for _, tt := range tests {
+		if got, want := tt.c.FieldNumber(), tt.fd.Number(); got != want || got == 0 {
+			t.Errorf("%s: FieldNumber() = %v, want %v", tt.name, got, want)
+		}
+	}
+}
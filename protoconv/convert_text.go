@@ -0,0 +1,501 @@
+package protoconv
+
+import (
+	"bytes"
+	"encoding"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"strconv"
+
+	pref "google.golang.org/protobuf/reflect/protoreflect"
+)
+
+var regexpType = reflect.TypeOf((*regexp.Regexp)(nil))
+
+// NewRegexpConverter returns a TryConverter between a *regexp.Regexp and
+// a string field holding its pattern. A nil *regexp.Regexp converts to
+// the empty string, and the empty string converts to a nil *regexp.Regexp.
+// Patterns that fail to compile are reported by TryGoValueOf.
+func NewRegexpConverter(fd pref.FieldDescriptor) TryConverter {
+	if fd.Kind() != pref.StringKind {
+		panic(fmt.Sprintf("invalid field %v for regexp: got %v, want string", fd.FullName(), fd.Kind()))
+	}
+	return &funcConverter{
+		goType: regexpType,
+		pb:     newSingularConverter(stringType, fd),
+		toPB: func(v reflect.Value) (pref.Value, error) {
+			re := v.Interface().(*regexp.Regexp)
+			if re == nil {
+				return stringZero, nil
+			}
+			return pref.ValueOfString(re.String()), nil
+		},
+		toGo: func(v pref.Value) (reflect.Value, error) {
+			if v.String() == "" {
+				return reflect.Zero(regexpType), nil
+			}
+			re, err := regexp.Compile(v.String())
+			if err != nil {
+				return reflect.Value{}, fmt.Errorf("invalid pattern for field %v: %w", fd.FullName(), err)
+			}
+			return reflect.ValueOf(re), nil
+		},
+	}
+}
+
+var stringerType = reflect.TypeOf((*fmt.Stringer)(nil)).Elem()
+
+// NewStringerConverter returns a TryConverter between a Go type t that
+// implements fmt.Stringer and a string field. Values are formatted with
+// their String method and parsed back with parse, which must return a
+// value of type t. Since String has no inverse, parse is required so that
+// conversions work in both directions. Parse errors are reported by
+// TryGoValueOf.
+func NewStringerConverter(t reflect.Type, parse func(string) (interface{}, error), fd pref.FieldDescriptor) TryConverter {
+	if !t.Implements(stringerType) || parse == nil || fd.Kind() != pref.StringKind {
+		panic(fmt.Sprintf("invalid Go type %v for field %v: want fmt.Stringer with a parse function", t, fd.FullName()))
+	}
+	return &funcConverter{
+		goType: t,
+		pb:     newSingularConverter(stringType, fd),
+		toPB: func(v reflect.Value) (pref.Value, error) {
+			return pref.ValueOfString(v.Interface().(fmt.Stringer).String()), nil
+		},
+		toGo: func(v pref.Value) (reflect.Value, error) {
+			x, err := parse(v.String())
+			if err != nil {
+				return reflect.Value{}, fmt.Errorf("invalid value %q for field %v: %w", v.String(), fd.FullName(), err)
+			}
+			rv := reflect.ValueOf(x)
+			if !rv.IsValid() || rv.Type() != t {
+				return reflect.Value{}, fmt.Errorf("invalid type %T parsed for field %v: want %v", x, fd.FullName(), t)
+			}
+			return rv, nil
+		},
+	}
+}
+
+// FloatSentinels are the strings used for special floating-point values
+// by NewFloatSentinelConverter and NewFloatSentinelOneof, such as "NaN",
+// "Inf", and "-Inf". The sentinels must be distinct, non-empty, and not
+// the representation of a finite number.
+type FloatSentinels struct {
+	NaN, PosInf, NegInf string
+}
+
+// check reports sentinels that cannot be told apart from each other
+// or from a finite number.
+func (s FloatSentinels) check() error {
+	seen := make(map[string]bool, 3)
+	for _, x := range []string{s.NaN, s.PosInf, s.NegInf} {
+		switch f, err := strconv.ParseFloat(x, 64); {
+		case x == "":
+			return fmt.Errorf("empty sentinel")
+		case seen[x]:
+			return fmt.Errorf("duplicate sentinel %q", x)
+		case err == nil && !math.IsNaN(f) && !math.IsInf(f, 0):
+			return fmt.Errorf("sentinel %q is a finite number", x)
+		}
+		seen[x] = true
+	}
+	return nil
+}
+
+// format returns the sentinel of f, if it is NaN or an infinity.
+func (s FloatSentinels) format(f float64) (string, bool) {
+	switch {
+	case math.IsNaN(f):
+		return s.NaN, true
+	case math.IsInf(f, +1):
+		return s.PosInf, true
+	case math.IsInf(f, -1):
+		return s.NegInf, true
+	}
+	return "", false
+}
+
+// parse returns the special value of the sentinel x.
+func (s FloatSentinels) parse(x string) (float64, bool) {
+	switch x {
+	case s.NaN:
+		return math.NaN(), true
+	case s.PosInf:
+		return math.Inf(+1), true
+	case s.NegInf:
+		return math.Inf(-1), true
+	}
+	return 0, false
+}
+
+// NewFloatSentinelConverter returns a TryConverter between a float64 and
+// a string field, where NaN and the infinities are represented by the given
+// sentinels and finite values by their shortest decimal representation.
+// Strings that are neither a sentinel nor a number are reported by
+// TryGoValueOf.
+func NewFloatSentinelConverter(s FloatSentinels, fd pref.FieldDescriptor) TryConverter {
+	if fd.Kind() != pref.StringKind {
+		panic(fmt.Sprintf("invalid field %v for float sentinels: got %v, want string", fd.FullName(), fd.Kind()))
+	}
+	if err := s.check(); err != nil {
+		panic(fmt.Sprintf("invalid float sentinels for field %v: %v", fd.FullName(), err))
+	}
+	return &funcConverter{
+		goType: float64Type,
+		pb:     newSingularConverter(stringType, fd),
+		toPB: func(v reflect.Value) (pref.Value, error) {
+			f := v.Float()
+			if x, ok := s.format(f); ok {
+				return pref.ValueOfString(x), nil
+			}
+			return pref.ValueOfString(strconv.FormatFloat(f, 'g', -1, 64)), nil
+		},
+		toGo: func(v pref.Value) (reflect.Value, error) {
+			if f, ok := s.parse(v.String()); ok {
+				return reflect.ValueOf(f), nil
+			}
+			f, err := strconv.ParseFloat(v.String(), 64)
+			if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+				return reflect.Value{}, fmt.Errorf("invalid number %q for field %v", v.String(), fd.FullName())
+			}
+			return reflect.ValueOf(f), nil
+		},
+	}
+}
+
+// FloatSentinelOneof stores a float64 in a oneof of a double field and
+// a string field, where finite values are held by the double field and
+// NaN and the infinities by their sentinels in the string field.
+type FloatSentinelOneof struct {
+	s        FloatSentinels
+	num, str pref.FieldDescriptor
+}
+
+// NewFloatSentinelOneof returns a FloatSentinelOneof for the double field
+// num and the string field str, which must be members of the same oneof.
+func NewFloatSentinelOneof(s FloatSentinels, num, str pref.FieldDescriptor) *FloatSentinelOneof {
+	if num.Kind() != pref.DoubleKind {
+		panic(fmt.Sprintf("invalid field %v for float sentinels: got %v, want double", num.FullName(), num.Kind()))
+	}
+	if str.Kind() != pref.StringKind {
+		panic(fmt.Sprintf("invalid field %v for float sentinels: got %v, want string", str.FullName(), str.Kind()))
+	}
+	if od := num.ContainingOneof(); od == nil || od != str.ContainingOneof() {
+		panic(fmt.Sprintf("invalid fields %v and %v for float sentinels: want members of the same oneof", num.FullName(), str.FullName()))
+	}
+	if err := s.check(); err != nil {
+		panic(fmt.Sprintf("invalid float sentinels for field %v: %v", str.FullName(), err))
+	}
+	return &FloatSentinelOneof{s: s, num: num, str: str}
+}
+
+// Set stores f in the oneof of m, replacing any other member.
+func (o *FloatSentinelOneof) Set(m pref.Message, f float64) {
+	if x, ok := o.s.format(f); ok {
+		m.Set(o.str, pref.ValueOfString(x))
+		return
+	}
+	m.Set(o.num, pref.ValueOfFloat64(f))
+}
+
+// Get returns the value stored in the oneof of m. An unset oneof is zero.
+// Strings that are not a sentinel, and other members of the oneof,
+// are reported as errors.
+func (o *FloatSentinelOneof) Get(m pref.Message) (float64, error) {
+	fd := m.WhichOneof(o.num.ContainingOneof())
+	if fd == nil {
+		return 0, nil
+	}
+	switch fd.Number() {
+	case o.num.Number():
+		return m.Get(o.num).Float(), nil
+	case o.str.Number():
+		x := m.Get(o.str).String()
+		if f, ok := o.s.parse(x); ok {
+			return f, nil
+		}
+		return 0, fmt.Errorf("invalid sentinel %q for field %v", x, o.str.FullName())
+	default:
+		return 0, fmt.Errorf("invalid field %v for float sentinels: want %v or %v", fd.FullName(), o.num.Name(), o.str.Name())
+	}
+}
+
+var (
+	textMarshalerType   = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// NewTextMarshalerConverter returns a TryConverter between a Go type t that
+// implements encoding.TextMarshaler, and whose pointer or t itself implements
+// encoding.TextUnmarshaler, and a string field holding its text.
+// Marshal and unmarshal errors are reported by TryPBValueOf and
+// TryGoValueOf respectively.
+func NewTextMarshalerConverter(t reflect.Type, fd pref.FieldDescriptor) TryConverter {
+	if !t.Implements(textMarshalerType) || fd.Kind() != pref.StringKind {
+		panic(fmt.Sprintf("invalid Go type %v for field %v: want encoding.TextMarshaler", t, fd.FullName()))
+	}
+	newValue := unmarshalTarget(t, textUnmarshalerType)
+	if newValue == nil {
+		panic(fmt.Sprintf("invalid Go type %v for field %v: want encoding.TextUnmarshaler", t, fd.FullName()))
+	}
+	return &funcConverter{
+		goType: t,
+		pb:     newSingularConverter(stringType, fd),
+		toPB: func(v reflect.Value) (pref.Value, error) {
+			b, err := v.Interface().(encoding.TextMarshaler).MarshalText()
+			if err != nil {
+				return pref.Value{}, fmt.Errorf("cannot marshal field %v: %w", fd.FullName(), err)
+			}
+			return pref.ValueOfString(string(b)), nil
+		},
+		toGo: func(v pref.Value) (reflect.Value, error) {
+			p, rv := newValue()
+			if err := p.Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(v.String())); err != nil {
+				return reflect.Value{}, fmt.Errorf("invalid value %q for field %v: %w", v.String(), fd.FullName(), err)
+			}
+			return rv, nil
+		},
+	}
+}
+
+// unmarshalTarget returns a function that allocates a new value of type t
+// to be unmarshaled into, where either *t or t itself, if it is a pointer,
+// implements the unmarshaler interface iface. It returns the pointer that
+// implements iface and the value of type t it unmarshals into.
+// It returns nil if t does not implement iface either way.
+func unmarshalTarget(t, iface reflect.Type) func() (ptr, val reflect.Value) {
+	switch {
+	case reflect.PtrTo(t).Implements(iface):
+		return func() (reflect.Value, reflect.Value) {
+			p := reflect.New(t)
+			return p, p.Elem()
+		}
+	case t.Kind() == reflect.Ptr && t.Implements(iface):
+		return func() (reflect.Value, reflect.Value) {
+			p := reflect.New(t.Elem())
+			return p, p
+		}
+	}
+	return nil
+}
+
+// NewTextMarshalerListConverter returns a TryConverter between a Go slice
+// type t, whose elements are converted as by NewTextMarshalerConverter,
+// and a repeated string field. Rather than stopping at the first failure,
+// the Try methods convert every element and report the failures
+// as ListErrors, as by the TryPBValuesOf method of NewElementListConverter.
+func NewTextMarshalerListConverter(t reflect.Type, fd pref.FieldDescriptor) TryConverter {
+	if t.Kind() != reflect.Slice || !fd.IsList() {
+		panic(fmt.Sprintf("invalid Go type %v for field %v: want slice for repeated field", t, fd.FullName()))
+	}
+	elem := NewTextMarshalerConverter(t.Elem(), fd)
+	pb := ConverterOptions{}.newListConverter(reflect.TypeOf([]string(nil)), fd)
+	return &funcConverter{
+		goType: t,
+		pb:     pb,
+		toPB: func(v reflect.Value) (pref.Value, error) {
+			vs, err := tryPBValuesOf(elem, v)
+			if err != nil {
+				return pref.Value{}, err
+			}
+			ss := make([]string, len(vs))
+			for i, s := range vs {
+				ss[i] = s.String()
+			}
+			return pb.PBValueOf(reflect.ValueOf(ss)), nil
+		},
+		toGo: func(v pref.Value) (reflect.Value, error) {
+			list := v.List()
+			rv := reflect.MakeSlice(t, list.Len(), list.Len())
+			var errs ListErrors
+			for i := 0; i < list.Len(); i++ {
+				ev, err := elem.TryGoValueOf(list.Get(i))
+				if err != nil {
+					errs = append(errs, &ListError{i, err})
+					continue
+				}
+				rv.Index(i).Set(ev)
+			}
+			if len(errs) > 0 {
+				return reflect.Value{}, errs
+			}
+			return rv, nil
+		},
+	}
+}
+
+// NewLinesConverter returns a Converter between []byte holding
+// newline-separated text and a repeated string field holding its lines.
+// Lines end with either "\n" or "\r\n", and the line endings are not
+// part of the strings. The final newline of the text terminates the last
+// line rather than starting an empty one, so "a\nb\n" and "a\r\nb"
+// both convert to ["a", "b"], while "a\n\n" converts to ["a", ""].
+// Empty text converts to an empty list. Converting from the field
+// terminates every line with "\n", so an empty list converts to empty text.
+func NewLinesConverter(fd pref.FieldDescriptor) Converter {
+	if !fd.IsList() || fd.Kind() != pref.StringKind {
+		panic(fmt.Sprintf("invalid field %v for lines: want repeated string", fd.FullName()))
+	}
+	pb := ConverterOptions{}.newListConverter(reflect.TypeOf([]string(nil)), fd)
+	return &funcConverter{
+		goType: bytesType,
+		pb:     pb,
+		toPB: func(v reflect.Value) (pref.Value, error) {
+			b := v.Bytes()
+			var ss []string
+			if len(b) > 0 {
+				b = bytes.TrimSuffix(b, []byte("\n"))
+				for _, line := range bytes.Split(b, []byte("\n")) {
+					ss = append(ss, string(bytes.TrimSuffix(line, []byte("\r"))))
+				}
+			}
+			return pb.PBValueOf(reflect.ValueOf(ss)), nil
+		},
+		toGo: func(v pref.Value) (reflect.Value, error) {
+			var b []byte
+			list := v.List()
+			for i := 0; i < list.Len(); i++ {
+				b = append(b, list.Get(i).String()...)
+				b = append(b, '\n')
+			}
+			return reflect.ValueOf(b), nil
+		},
+	}
+}
+
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+// NewErrorChainConverter returns a Converter between an error and a repeated
+// string field holding the message of every error in its chain, as followed
+// by an Unwrap method, from the outermost error inwards.
+// A nil error converts to an empty list. Converting from the field
+// reconstructs a chain of errors with the same messages, but not the
+// original error types, and an empty list converts to a nil error.
+func NewErrorChainConverter(fd pref.FieldDescriptor) Converter {
+	if !fd.IsList() || fd.Kind() != pref.StringKind {
+		panic(fmt.Sprintf("invalid field %v for error chain: want repeated string", fd.FullName()))
+	}
+	pb := ConverterOptions{}.newListConverter(reflect.TypeOf([]string(nil)), fd)
+	return &funcConverter{
+		goType: errorType,
+		pb:     pb,
+		toPB: func(v reflect.Value) (pref.Value, error) {
+			var ss []string
+			err, _ := v.Interface().(error)
+			for err != nil {
+				ss = append(ss, err.Error())
+				u, ok := err.(interface{ Unwrap() error })
+				if !ok {
+					break
+				}
+				err = u.Unwrap()
+			}
+			return pb.PBValueOf(reflect.ValueOf(ss)), nil
+		},
+		toGo: func(v pref.Value) (reflect.Value, error) {
+			var err error
+			list := v.List()
+			for i := list.Len() - 1; i >= 0; i-- {
+				err = &chainError{list.Get(i).String(), err}
+			}
+			rv := reflect.New(errorType).Elem()
+			if err != nil {
+				rv.Set(reflect.ValueOf(err))
+			}
+			return rv, nil
+		},
+	}
+}
+
+// chainError is an error reconstructed by NewErrorChainConverter.
+type chainError struct {
+	msg string
+	err error
+}
+
+func (e *chainError) Error() string {
+	return e.msg
+}
+
+func (e *chainError) Unwrap() error {
+	return e.err
+}
+
+// CodedError is an error that carries a numeric code,
+// as converted by the Converters returned by NewErrorCodeConverter.
+type CodedError interface {
+	error
+	Code() int
+}
+
+// NewErrorCodeConverter returns a TryConverter between the Go error type
+// and the enum field fd, using codes to translate the code of a CodedError,
+// or of the first CodedError it wraps, into an enum number.
+// A nil error, an error without a code, and a code missing from codes
+// all convert to the zero enum value. Converting from a non-zero number
+// results in a CodedError with the corresponding code, and the zero number
+// results in a nil error; other numbers, which have no code, are reported
+// by TryGoValueOf. The numbers of codes must be distinct and non-zero.
+func NewErrorCodeConverter(codes map[int]pref.EnumNumber, fd pref.FieldDescriptor) TryConverter {
+	if fd.Enum() == nil || fd.IsList() {
+		panic(fmt.Sprintf("invalid field %v for error code: want singular enum", fd.FullName()))
+	}
+	numbers := make(map[pref.EnumNumber]int, len(codes))
+	for code, n := range codes {
+		if _, ok := numbers[n]; ok || n == 0 {
+			panic(fmt.Sprintf("invalid error codes for field %v: enum number %d is zero or used by multiple codes", fd.FullName(), n))
+		}
+		numbers[n] = code
+	}
+	ed := fd.Enum()
+	return &funcConverter{
+		goType: errorType,
+		pb:     newEnumConverter(reflect.TypeOf(pref.EnumNumber(0)), fd),
+		toPB: func(v reflect.Value) (pref.Value, error) {
+			err, _ := v.Interface().(error)
+			for err != nil {
+				if e, ok := err.(CodedError); ok {
+					return pref.ValueOfEnum(codes[e.Code()]), nil
+				}
+				u, ok := err.(interface{ Unwrap() error })
+				if !ok {
+					break
+				}
+				err = u.Unwrap()
+			}
+			return pref.ValueOfEnum(0), nil
+		},
+		toGo: func(v pref.Value) (reflect.Value, error) {
+			n := v.Enum()
+			if n == 0 {
+				return reflect.Zero(errorType), nil
+			}
+			code, ok := numbers[n]
+			if !ok {
+				return reflect.Value{}, fmt.Errorf("invalid value %d for field %v: no error code", n, fd.FullName())
+			}
+			name := strconv.Itoa(int(n))
+			if evd := ed.Values().ByNumber(n); evd != nil {
+				name = string(evd.Name())
+			}
+			rv := reflect.New(errorType).Elem()
+			rv.Set(reflect.ValueOf(&codeError{code, name}))
+			return rv, nil
+		},
+	}
+}
+
+// codeError is an error reconstructed by NewErrorCodeConverter.
+type codeError struct {
+	code int
+	name string
+}
+
+func (e *codeError) Error() string {
+	return fmt.Sprintf("%s (code %d)", e.name, e.code)
+}
+
+func (e *codeError) Code() int {
+	return e.code
+}
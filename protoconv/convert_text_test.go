@@ -0,0 +1,425 @@
+package protoconv_test
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/dnscrypt/dnscrypt-proxy/protoconv"
+	"golang.org/x/text/unicode/norm"
+	pref "google.golang.org/protobuf/reflect/protoreflect"
+)
+
+func TestRegexpConverter(t *testing.T) {
+	c := protoconv.NewRegexpConverter(field("s"))
+	tests := []struct {
+		in   *regexp.Regexp
+		want string
+	}{
+		{nil, ""},
+		{regexp.MustCompile(`^[a-z]+\.example\.com$`), `^[a-z]+\.example\.com$`},
+	}
+	for _, tt := range tests {
+		v := c.PBValueOf(reflect.ValueOf(tt.in))
+		if got := v.String(); got != tt.want {
+			t.Errorf("PBValueOf(%v) = %q, want %q", tt.in, got, tt.want)
+		}
+		rv, err := c.TryGoValueOf(v)
+		if err != nil {
+			t.Fatalf("TryGoValueOf(%q) error: %v", tt.want, err)
+		}
+		if got := rv.Interface().(*regexp.Regexp); (got == nil) != (tt.in == nil) || (got != nil && got.String() != tt.want) {
+			t.Errorf("TryGoValueOf(%q) = %v, want %v", tt.want, got, tt.in)
+		}
+	}
+	if _, err := c.TryGoValueOf(pref.ValueOfString("a(b")); err == nil {
+		t.Error("TryGoValueOf(\"a(b\") succeeded, want compile error")
+	}
+}
+
+type version struct{ major, minor int }
+
+func (v version) String() string { return fmt.Sprintf("%d.%d", v.major, v.minor) }
+
+func parseVersion(s string) (interface{}, error) {
+	var v version
+	if _, err := fmt.Sscanf(s, "%d.%d", &v.major, &v.minor); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+func TestStringerConverter(t *testing.T) {
+	c := protoconv.NewStringerConverter(reflect.TypeOf(version{}), parseVersion, field("s"))
+	in := version{2, 13}
+	v := c.PBValueOf(reflect.ValueOf(in))
+	if got := v.String(); got != "2.13" {
+		t.Errorf("PBValueOf(%v) = %q, want %q", in, got, "2.13")
+	}
+	got, err := c.TryGoValueOf(v)
+	if err != nil || got.Interface() != in {
+		t.Errorf("TryGoValueOf(%q) = %v, %v, want %v", v.String(), got, err, in)
+	}
+	if _, err := c.TryGoValueOf(pref.ValueOfString("two")); err == nil {
+		t.Error(`TryGoValueOf("two") succeeded, want parse error`)
+	}
+
+	wrongType := func(string) (interface{}, error) { return "2.13", nil }
+	c = protoconv.NewStringerConverter(reflect.TypeOf(version{}), wrongType, field("s"))
+	if _, err := c.TryGoValueOf(v); err == nil {
+		t.Error("TryGoValueOf with a parser of the wrong type succeeded, want error")
+	}
+}
+
+var sentinels = protoconv.FloatSentinels{NaN: "NaN", PosInf: "Inf", NegInf: "-Inf"}
+
+func TestFloatSentinelConverter(t *testing.T) {
+	c := protoconv.NewFloatSentinelConverter(sentinels, field("s"))
+	tests := []struct {
+		in   float64
+		want string
+	}{
+		{math.NaN(), "NaN"},
+		{math.Inf(+1), "Inf"},
+		{math.Inf(-1), "-Inf"},
+		{1.5, "1.5"},
+		{0, "0"},
+	}
+	for _, tt := range tests {
+		v := c.PBValueOf(reflect.ValueOf(tt.in))
+		if got := v.String(); got != tt.want {
+			t.Errorf("PBValueOf(%v) = %q, want %q", tt.in, got, tt.want)
+		}
+		got, err := c.TryGoValueOf(v)
+		if f := got.Float(); err != nil || !(f == tt.in || math.IsNaN(f) && math.IsNaN(tt.in)) {
+			t.Errorf("TryGoValueOf(%q) = %v, %v, want %v", tt.want, got, err, tt.in)
+		}
+	}
+	for _, in := range []string{"", "nan", "x"} {
+		if _, err := c.TryGoValueOf(pref.ValueOfString(in)); err == nil {
+			t.Errorf("TryGoValueOf(%q) succeeded, want error", in)
+		}
+	}
+}
+
+func TestFloatSentinelsInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		s    protoconv.FloatSentinels
+	}{
+		{"zero", protoconv.FloatSentinels{}},
+		{"empty", protoconv.FloatSentinels{NaN: "NaN", PosInf: "", NegInf: "-Inf"}},
+		{"duplicate", protoconv.FloatSentinels{NaN: "NaN", PosInf: "Inf", NegInf: "Inf"}},
+		{"finite", protoconv.FloatSentinels{NaN: "0", PosInf: "Inf", NegInf: "-Inf"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				if r := recover(); r == nil {
+					t.Errorf("NewFloatSentinelConverter(%+v) did not panic", tt.s)
+				}
+			}()
+			protoconv.NewFloatSentinelConverter(tt.s, field("s"))
+		})
+	}
+}
+
+func TestFloatSentinelOneof(t *testing.T) {
+	fds := (&Value{}).ProtoReflect().Descriptor().Fields()
+	num, str := fds.ByName("number_value"), fds.ByName("string_value")
+	o := protoconv.NewFloatSentinelOneof(sentinels, num, str)
+	tests := []struct {
+		in        float64
+		wantField pref.FieldDescriptor
+		want      pref.Value
+	}{
+		{math.NaN(), str, pref.ValueOfString("NaN")},
+		{math.Inf(+1), str, pref.ValueOfString("Inf")},
+		{math.Inf(-1), str, pref.ValueOfString("-Inf")},
+		{2.25, num, pref.ValueOfFloat64(2.25)},
+	}
+	for _, tt := range tests {
+		m := (&Value{Kind: &Value_BoolValue{BoolValue: true}}).ProtoReflect()
+		o.Set(m, tt.in)
+		fd := m.WhichOneof(num.ContainingOneof())
+		if fd == nil || fd.Number() != tt.wantField.Number() {
+			t.Errorf("Set(%v) stored field %v, want %v", tt.in, fd, tt.wantField.Name())
+		} else if got := m.Get(fd); got.Interface() != tt.want.Interface() {
+			t.Errorf("Set(%v) stored %v, want %v", tt.in, got, tt.want)
+		}
+		got, err := o.Get(m)
+		if err != nil || !(got == tt.in || math.IsNaN(got) && math.IsNaN(tt.in)) {
+			t.Errorf("Get after Set(%v) = %v, %v", tt.in, got, err)
+		}
+	}
+
+	for _, m := range []*Value{
+		{Kind: &Value_StringValue{StringValue: "1.5"}},
+		{Kind: &Value_BoolValue{BoolValue: true}},
+	} {
+		if _, err := o.Get(m.ProtoReflect()); err == nil {
+			t.Errorf("Get(%v) succeeded, want error", m)
+		}
+	}
+	if got, err := o.Get((&Value{}).ProtoReflect()); got != 0 || err != nil {
+		t.Errorf("Get(unset) = %v, %v, want 0", got, err)
+	}
+
+	defer func() {
+		if r := recover(); r == nil {
+			t.Error("NewFloatSentinelOneof with fields outside a oneof did not panic")
+		}
+	}()
+	protoconv.NewFloatSentinelOneof(sentinels, field("d"), field("s"))
+}
+
+// shout marshals as upper case and unmarshals as lower case.
+type shout string
+
+func (s shout) MarshalText() ([]byte, error) {
+	if s == "" {
+		return nil, errors.New("empty shout")
+	}
+	return []byte(strings.ToUpper(string(s))), nil
+}
+
+func (s *shout) UnmarshalText(b []byte) error {
+	if string(b) == "BAD" {
+		return errors.New("bad shout")
+	}
+	*s = shout(strings.ToLower(string(b)))
+	return nil
+}
+
+func TestTextMarshalerListConverter(t *testing.T) {
+	c := protoconv.NewTextMarshalerListConverter(reflect.TypeOf([]shout(nil)), field("rs"))
+	tests := []struct {
+		name        string
+		in          []shout
+		want        []string
+		wantIndices []int // of elements that fail to convert
+	}{
+		{"empty", []shout{}, []string{}, nil},
+		{"round trip", []shout{"a", "dns"}, []string{"A", "DNS"}, nil},
+		{"marshal error", []shout{"a", "b", ""}, nil, []int{2}},
+		{"marshal errors", []shout{"", "b", ""}, nil, []int{0, 2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v, err := c.TryPBValueOf(reflect.ValueOf(tt.in))
+			if tt.wantIndices != nil {
+				if got := listErrorIndices(err); !reflect.DeepEqual(got, tt.wantIndices) {
+					t.Errorf("TryPBValueOf(%q) error = %v, want ListErrors for elements %v", tt.in, err, tt.wantIndices)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("TryPBValueOf(%q) error: %v", tt.in, err)
+			}
+			if got := listStrings(v.List()); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("TryPBValueOf(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+			got, err := c.TryGoValueOf(v)
+			if err != nil || !reflect.DeepEqual(got.Interface(), tt.in) {
+				t.Errorf("TryGoValueOf = %v, %v, want %q", got, err, tt.in)
+			}
+		})
+	}
+
+	list := c.New().List()
+	for _, s := range []string{"A", "B", "BAD"} {
+		list.Append(pref.ValueOfString(s))
+	}
+	if _, err := c.TryGoValueOf(pref.ValueOfList(list)); !reflect.DeepEqual(listErrorIndices(err), []int{2}) {
+		t.Errorf("TryGoValueOf error = %v, want ListErrors for element 2", err)
+	}
+}
+
+// listErrorIndices returns the indices of the elements reported by err,
+// or nil if err is not ListErrors.
+func listErrorIndices(err error) []int {
+	var errs protoconv.ListErrors
+	if !errors.As(err, &errs) {
+		return nil
+	}
+	var indices []int
+	for _, e := range errs {
+		indices = append(indices, e.Index)
+	}
+	return indices
+}
+
+func TestNormalizeString(t *testing.T) {
+	const (
+		precomposed = "caf\u00e9"
+		decomposed  = "cafe\u0301"
+	)
+	c := protoconv.ConverterOptions{NormalizeString: norm.NFC.String}.New(stringType, field("s"))
+	tests := []struct {
+		name string
+		c    protoconv.Converter
+		in   string
+		want string
+	}{
+		{"precomposed", c, precomposed, precomposed},
+		{"decomposed", c, decomposed, precomposed},
+		{"default", protoconv.NewConverter(stringType, field("s")), decomposed, decomposed},
+	}
+	for _, tt := range tests {
+		if got := tt.c.PBValueOf(reflect.ValueOf(tt.in)).String(); got != tt.want {
+			t.Errorf("%s: PBValueOf(%+q) = %+q, want %+q", tt.name, tt.in, got, tt.want)
+		}
+	}
+}
+
+// errorValue returns err as a reflect.Value of type error.
+func errorValue(err error) reflect.Value {
+	return reflect.ValueOf(&err).Elem()
+}
+
+func TestErrorChainConverter(t *testing.T) {
+	c := protoconv.NewErrorChainConverter(field("rs"))
+	base := errors.New("base")
+	tests := []struct {
+		name string
+		in   error
+		want []string
+	}{
+		{"nil", nil, []string{}},
+		{"single", base, []string{"base"}},
+		{
+			"wrapped",
+			fmt.Errorf("outer: %w", fmt.Errorf("mid: %w", base)),
+			[]string{"outer: mid: base", "mid: base", "base"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := c.PBValueOf(errorValue(tt.in))
+			if got := listStrings(v.List()); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("PBValueOf = %q, want %q", got, tt.want)
+			}
+			err, _ := c.GoValueOf(v).Interface().(error)
+			var got []string
+			for ; err != nil; err = errors.Unwrap(err) {
+				got = append(got, err.Error())
+			}
+			if len(got) != len(tt.want) || len(got) > 0 && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GoValueOf chain = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// codedError is an error carrying a numeric code.
+type codedError int
+
+func (e codedError) Error() string { return fmt.Sprint("code ", int(e)) }
+func (e codedError) Code() int     { return int(e) }
+
+func TestErrorCodeConverter(t *testing.T) {
+	c := protoconv.NewErrorCodeConverter(map[int]pref.EnumNumber{404: 1, 500: 2}, field("e"))
+	tests := []struct {
+		name     string
+		in       error
+		want     pref.EnumNumber
+		wantCode int // of the converted-back error, or 0 for nil
+	}{
+		{"coded", codedError(500), 2, 500},
+		{"wrapped", fmt.Errorf("wrap: %w", codedError(404)), 1, 404},
+		{"unknown code", codedError(418), 0, 0},
+		{"uncoded", errors.New("plain"), 0, 0},
+		{"nil", nil, 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			in := reflect.New(reflect.TypeOf((*error)(nil)).Elem()).Elem()
+			if tt.in != nil {
+				in.Set(reflect.ValueOf(tt.in))
+			}
+			v, err := c.TryPBValueOf(in)
+			if err != nil || v.Enum() != tt.want {
+				t.Fatalf("TryPBValueOf(%v) = %v, %v, want %v", tt.in, v, err, tt.want)
+			}
+			got, err := c.TryGoValueOf(v)
+			if err != nil {
+				t.Fatalf("TryGoValueOf error: %v", err)
+			}
+			if tt.wantCode == 0 {
+				if !got.IsNil() {
+					t.Errorf("TryGoValueOf = %v, want nil", got)
+				}
+				return
+			}
+			if ce, ok := got.Interface().(protoconv.CodedError); !ok || ce.Code() != tt.wantCode {
+				t.Errorf("TryGoValueOf = %v, want error with code %d", got, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestErrorCodeConverterInbound(t *testing.T) {
+	c := protoconv.NewErrorCodeConverter(map[int]pref.EnumNumber{404: 1, 500: 2}, field("e"))
+	tests := []struct {
+		name     string
+		in       pref.EnumNumber
+		wantCode int // or 0 for a nil error
+		wantErr  bool
+	}{
+		{"zero", 0, 0, false},
+		{"mapped", 2, 500, false},
+		{"unmapped", 3, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := c.TryGoValueOf(pref.ValueOfEnum(tt.in))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("TryGoValueOf(%d) error = %v, want error %v", tt.in, err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if tt.wantCode == 0 {
+				if !got.IsNil() {
+					t.Errorf("TryGoValueOf(%d) = %v, want nil", tt.in, got)
+				}
+				return
+			}
+			if ce, ok := got.Interface().(protoconv.CodedError); !ok || ce.Code() != tt.wantCode {
+				t.Errorf("TryGoValueOf(%d) = %v, want error with code %d", tt.in, got, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestLinesConverter(t *testing.T) {
+	c := protoconv.NewLinesConverter(field("rs"))
+	tests := []struct {
+		name string
+		in   string
+		want []string
+		back string
+	}{
+		{"CRLF", "a\r\nb\r\n", []string{"a", "b"}, "a\nb\n"},
+		{"no trailing newline", "a\r\nb", []string{"a", "b"}, "a\nb\n"},
+		{"trailing newline", "a\nb\n", []string{"a", "b"}, "a\nb\n"},
+		{"trailing empty line", "a\n\n", []string{"a", ""}, "a\n\n"},
+		{"newline only", "\n", []string{""}, "\n"},
+		{"empty", "", []string{}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := c.PBValueOf(reflect.ValueOf([]byte(tt.in)))
+			if got := listStrings(v.List()); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("PBValueOf(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+			if got := string(c.GoValueOf(v).Bytes()); got != tt.back {
+				t.Errorf("GoValueOf = %q, want %q", got, tt.back)
+			}
+		})
+	}
+}
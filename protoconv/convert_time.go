@@ -0,0 +1,159 @@
+package protoconv
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"time"
+
+	pref "google.golang.org/protobuf/reflect/protoreflect"
+)
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// NewTimeLayoutConverter returns a TryConverter between time.Time and
+// a string field holding the time formatted according to layout,
+// as understood by time.Time.Format and time.Parse.
+//
+// Layouts without time zone information are parsed as UTC,
+// so times are converted to UTC before being formatted with them
+// in order to round-trip. Layouts with a two-digit year ("06") parse
+// years 69 through 99 as 1969 through 1999 and 00 through 68 as
+// 2000 through 2068.
+func NewTimeLayoutConverter(layout string, fd pref.FieldDescriptor) TryConverter {
+	if fd.Kind() != pref.StringKind {
+		panic(fmt.Sprintf("invalid field %v for time layout: got %v, want string", fd.FullName(), fd.Kind()))
+	}
+	hasZone := layoutHasZone(layout)
+	return &funcConverter{
+		goType: timeType,
+		pb:     newSingularConverter(stringType, fd),
+		toPB: func(v reflect.Value) (pref.Value, error) {
+			t := v.Interface().(time.Time)
+			if !hasZone {
+				t = t.UTC()
+			}
+			return pref.ValueOfString(t.Format(layout)), nil
+		},
+		toGo: func(v pref.Value) (reflect.Value, error) {
+			t, err := time.Parse(layout, v.String())
+			if err != nil {
+				return reflect.Value{}, fmt.Errorf("invalid time for field %v: %w", fd.FullName(), err)
+			}
+			return reflect.ValueOf(t), nil
+		},
+	}
+}
+
+// layoutHasZone reports whether times formatted with layout
+// retain their offset from UTC when parsed with it.
+func layoutHasZone(layout string) bool {
+	ref := time.Date(2006, 1, 2, 15, 4, 5, 0, time.FixedZone("", 7*60*60))
+	t, err := time.Parse(layout, ref.Format(layout))
+	if err != nil {
+		return false
+	}
+	_, offset := t.Zone()
+	return offset != 0
+}
+
+// NewUnixStringConverter returns a TryConverter between time.Time and
+// a string field holding the number of seconds since the Unix epoch in
+// decimal, such as "1625097600". Sub-second precision is dropped, and
+// times converted from the field are in UTC. Strings that are not a decimal
+// integer are reported by TryGoValueOf.
+func NewUnixStringConverter(fd pref.FieldDescriptor) TryConverter {
+	if fd.Kind() != pref.StringKind {
+		panic(fmt.Sprintf("invalid field %v for Unix time: got %v, want string", fd.FullName(), fd.Kind()))
+	}
+	return &funcConverter{
+		goType: timeType,
+		pb:     newSingularConverter(stringType, fd),
+		toPB: func(v reflect.Value) (pref.Value, error) {
+			return pref.ValueOfString(strconv.FormatInt(v.Interface().(time.Time).Unix(), 10)), nil
+		},
+		toGo: func(v pref.Value) (reflect.Value, error) {
+			secs, err := strconv.ParseInt(v.String(), 10, 64)
+			if err != nil {
+				return reflect.Value{}, fmt.Errorf("invalid Unix time for field %v: %w", fd.FullName(), err)
+			}
+			return reflect.ValueOf(time.Unix(secs, 0).UTC()), nil
+		},
+	}
+}
+
+// NewMonthConverter returns a TryConverter between time.Month and an enum
+// field, where the enum number of a month is its Go value plus offset.
+// For example, an offset of 0 suits an enum with MONTH_UNSPECIFIED = 0 and
+// JANUARY = 1. Months outside of January through December, and enum numbers
+// that do not correspond to one, are reported as errors.
+func NewMonthConverter(offset int32, fd pref.FieldDescriptor) TryConverter {
+	return newCalendarEnumConverter(reflect.TypeOf(time.Month(0)), int64(time.January), int64(time.December), offset, fd)
+}
+
+// NewWeekdayConverter returns a TryConverter between time.Weekday and an enum
+// field, where the enum number of a weekday is its Go value plus offset.
+// For example, an offset of 1 suits an enum with DAY_UNSPECIFIED = 0 and
+// SUNDAY = 1. Weekdays outside of Sunday through Saturday, and enum numbers
+// that do not correspond to one, are reported as errors.
+func NewWeekdayConverter(offset int32, fd pref.FieldDescriptor) TryConverter {
+	return newCalendarEnumConverter(reflect.TypeOf(time.Weekday(0)), int64(time.Sunday), int64(time.Saturday), offset, fd)
+}
+
+// newCalendarEnumConverter converts the Go integer type t with values
+// in [min, max] to enum numbers offset from them.
+func newCalendarEnumConverter(t reflect.Type, min, max int64, offset int32, fd pref.FieldDescriptor) TryConverter {
+	if fd.Enum() == nil {
+		panic(fmt.Sprintf("invalid field %v for %v: want enum", fd.FullName(), t))
+	}
+	return &funcConverter{
+		goType: t,
+		pb:     newEnumConverter(reflect.TypeOf(pref.EnumNumber(0)), fd),
+		toPB: func(v reflect.Value) (pref.Value, error) {
+			if n := v.Int(); n < min || n > max {
+				return pref.Value{}, fmt.Errorf("invalid %v %d for field %v", t, n, fd.FullName())
+			}
+			return pref.ValueOfEnum(pref.EnumNumber(v.Int() + int64(offset))), nil
+		},
+		toGo: func(v pref.Value) (reflect.Value, error) {
+			n := int64(v.Enum()) - int64(offset)
+			if n < min || n > max {
+				return reflect.Value{}, fmt.Errorf("invalid enum number %d for %v in field %v", v.Enum(), t, fd.FullName())
+			}
+			return reflect.ValueOf(n).Convert(t), nil
+		},
+	}
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// NewDurationUnitConverter returns a TryConverter between time.Duration and
+// an int64 field holding a number of units, such as time.Millisecond.
+// Durations are truncated toward zero to a whole number of units,
+// so that 1500*time.Microsecond converts to 1 for time.Millisecond.
+// Numbers of units that overflow a time.Duration are reported by TryGoValueOf.
+func NewDurationUnitConverter(unit time.Duration, fd pref.FieldDescriptor) TryConverter {
+	switch unit {
+	case time.Nanosecond, time.Microsecond, time.Millisecond, time.Second:
+	default:
+		panic(fmt.Sprintf("invalid unit %v for field %v: want ns, us, ms, or s", unit, fd.FullName()))
+	}
+	if scalarGoType(fd.Kind()) != int64Type {
+		panic(fmt.Sprintf("invalid field %v for duration: got %v, want int64", fd.FullName(), fd.Kind()))
+	}
+	return &funcConverter{
+		goType: durationType,
+		pb:     newSingularConverter(int64Type, fd),
+		toPB: func(v reflect.Value) (pref.Value, error) {
+			return pref.ValueOfInt64(v.Int() / int64(unit)), nil
+		},
+		toGo: func(v pref.Value) (reflect.Value, error) {
+			n := v.Int()
+			if n > int64(math.MaxInt64/unit) || n < int64(math.MinInt64/unit) {
+				return reflect.Value{}, fmt.Errorf("duration of %d units of %v for field %v out of range", n, unit, fd.FullName())
+			}
+			return reflect.ValueOf(time.Duration(n) * unit), nil
+		},
+	}
+}
@@ -0,0 +1,155 @@
+package protoconv_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/dnscrypt/dnscrypt-proxy/protoconv"
+	pref "google.golang.org/protobuf/reflect/protoreflect"
+)
+
+func TestTimeLayoutConverter(t *testing.T) {
+	zone := time.FixedZone("CEST", 2*60*60)
+	tests := []struct {
+		name   string
+		layout string
+		in     time.Time
+		want   string
+	}{
+		{"with zone", "2006-01-02 15:04:05 -0700", time.Date(2021, 7, 1, 12, 30, 0, 0, zone), "2021-07-01 12:30:00 +0200"},
+		{"without zone", "2006-01-02 15:04:05", time.Date(2021, 7, 1, 12, 30, 0, 0, zone), "2021-07-01 10:30:00"},
+		{"two-digit year", "02/01/06 15:04", time.Date(1999, 12, 31, 23, 59, 0, 0, time.UTC), "31/12/99 23:59"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := protoconv.NewTimeLayoutConverter(tt.layout, field("s"))
+			v := c.PBValueOf(reflect.ValueOf(tt.in))
+			if got := v.String(); got != tt.want {
+				t.Errorf("PBValueOf(%v) = %q, want %q", tt.in, got, tt.want)
+			}
+			got, err := c.TryGoValueOf(v)
+			if err != nil {
+				t.Fatalf("TryGoValueOf(%q) error: %v", tt.want, err)
+			}
+			if !got.Interface().(time.Time).Equal(tt.in) {
+				t.Errorf("TryGoValueOf(%q) = %v, want %v", tt.want, got, tt.in)
+			}
+		})
+	}
+}
+
+func TestTimeLayoutConverterParseError(t *testing.T) {
+	c := protoconv.NewTimeLayoutConverter(time.RFC1123, field("s"))
+	if _, err := c.TryGoValueOf(pref.ValueOfString("2021-07-01")); err == nil {
+		t.Error("TryGoValueOf succeeded, want parse error")
+	}
+}
+
+func TestUnixStringConverter(t *testing.T) {
+	c := protoconv.NewUnixStringConverter(field("s"))
+	tests := []struct {
+		name string
+		in   time.Time
+		want string
+	}{
+		{"seconds", time.Unix(1625097600, 0).UTC(), "1625097600"},
+		{"sub-second dropped", time.Unix(1625097600, 999).UTC(), "1625097600"},
+		{"pre-epoch", time.Unix(-86400, 0).UTC(), "-86400"},
+		{"zoned", time.Unix(1625097600, 0).In(time.FixedZone("CEST", 2*60*60)), "1625097600"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := c.PBValueOf(reflect.ValueOf(tt.in))
+			if got := v.String(); got != tt.want {
+				t.Errorf("PBValueOf(%v) = %q, want %q", tt.in, got, tt.want)
+			}
+			got, err := c.TryGoValueOf(v)
+			if err != nil {
+				t.Fatalf("TryGoValueOf(%q) error: %v", tt.want, err)
+			}
+			if want := tt.in.Truncate(time.Second).UTC(); got.Interface() != want {
+				t.Errorf("TryGoValueOf(%q) = %v, want %v", tt.want, got, want)
+			}
+		})
+	}
+
+	for _, s := range []string{"", "12x", "1.5", "99999999999999999999"} {
+		if _, err := c.TryGoValueOf(pref.ValueOfString(s)); err == nil {
+			t.Errorf("TryGoValueOf(%q) succeeded, want error", s)
+		}
+	}
+}
+
+func TestCalendarEnumConverters(t *testing.T) {
+	tests := []struct {
+		name    string
+		c       protoconv.TryConverter
+		in      interface{}
+		want    pref.EnumNumber
+		wantErr bool
+	}{
+		{"January", protoconv.NewMonthConverter(0, field("e")), time.January, 1, false},
+		{"December", protoconv.NewMonthConverter(0, field("e")), time.December, 12, false},
+		{"Monday", protoconv.NewWeekdayConverter(1, field("e")), time.Monday, 2, false},
+		{"Sunday", protoconv.NewWeekdayConverter(1, field("e")), time.Sunday, 1, false},
+		{"month 13", protoconv.NewMonthConverter(0, field("e")), time.Month(13), 0, true},
+		{"month 0", protoconv.NewMonthConverter(0, field("e")), time.Month(0), 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v, err := tt.c.TryPBValueOf(reflect.ValueOf(tt.in))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("TryPBValueOf(%v) error = %v, want error %v", tt.in, err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if v.Enum() != tt.want {
+				t.Errorf("TryPBValueOf(%v) = %d, want %d", tt.in, v.Enum(), tt.want)
+			}
+			if got, err := tt.c.TryGoValueOf(v); err != nil || got.Interface() != tt.in {
+				t.Errorf("TryGoValueOf(%d) = %v, %v, want %v", v.Enum(), got, err, tt.in)
+			}
+		})
+	}
+
+	if _, err := protoconv.NewMonthConverter(0, field("e")).TryGoValueOf(pref.ValueOfEnum(0)); err == nil {
+		t.Error("TryGoValueOf(0) for a month succeeded, want error")
+	}
+}
+
+func TestDurationUnitConverter(t *testing.T) {
+	tests := []struct {
+		name   string
+		unit   time.Duration
+		in     time.Duration
+		want   int64
+		wantGo time.Duration
+	}{
+		{"ms whole", time.Millisecond, 3 * time.Millisecond, 3, 3 * time.Millisecond},
+		{"ms truncated", time.Millisecond, 1500 * time.Microsecond, 1, time.Millisecond},
+		{"ms sub-unit", time.Millisecond, 999 * time.Microsecond, 0, 0},
+		{"s truncated", time.Second, 2500 * time.Millisecond, 2, 2 * time.Second},
+		{"s negative truncated toward zero", time.Second, -2500 * time.Millisecond, -2, -2 * time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := protoconv.NewDurationUnitConverter(tt.unit, field("i64"))
+			v := c.PBValueOf(reflect.ValueOf(tt.in))
+			if got := v.Int(); got != tt.want {
+				t.Errorf("PBValueOf(%v) = %d, want %d", tt.in, got, tt.want)
+			}
+			if got, err := c.TryGoValueOf(v); err != nil || got.Interface() != tt.wantGo {
+				t.Errorf("TryGoValueOf(%d) = %v, %v, want %v", tt.want, got, err, tt.wantGo)
+			}
+		})
+	}
+
+	c := protoconv.NewDurationUnitConverter(time.Second, field("i64"))
+	for _, n := range []int64{1 << 62, -1 << 62} {
+		if _, err := c.TryGoValueOf(pref.ValueOfInt64(n)); err == nil {
+			t.Errorf("TryGoValueOf(%d) succeeded, want overflow error", n)
+		}
+	}
+}
@@ -0,0 +1,170 @@
+package protoconv
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+
+	"google.golang.org/protobuf/encoding/protowire"
+	pref "google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// MarshalAppender is implemented by the Converters of scalar fields.
+// MarshalAppend appends the tag of field number num and the wire encoding
+// of v according to the kind of the field, as produced by proto.Marshal
+// for a singular field.
+type MarshalAppender interface {
+	MarshalAppend(b []byte, num protowire.Number, v pref.Value) []byte
+}
+
+func (c *boolConverter) MarshalAppend(b []byte, num protowire.Number, v pref.Value) []byte {
+	return appendScalar(b, num, pref.BoolKind, v)
+}
+func (c *int32Converter) MarshalAppend(b []byte, num protowire.Number, v pref.Value) []byte {
+	return appendScalar(b, num, c.kind, v)
+}
+func (c *int64Converter) MarshalAppend(b []byte, num protowire.Number, v pref.Value) []byte {
+	return appendScalar(b, num, c.kind, v)
+}
+func (c *uint32Converter) MarshalAppend(b []byte, num protowire.Number, v pref.Value) []byte {
+	return appendScalar(b, num, c.kind, v)
+}
+func (c *uint64Converter) MarshalAppend(b []byte, num protowire.Number, v pref.Value) []byte {
+	return appendScalar(b, num, c.kind, v)
+}
+func (c *float32Converter) MarshalAppend(b []byte, num protowire.Number, v pref.Value) []byte {
+	return appendScalar(b, num, pref.FloatKind, v)
+}
+func (c *float64Converter) MarshalAppend(b []byte, num protowire.Number, v pref.Value) []byte {
+	return appendScalar(b, num, pref.DoubleKind, v)
+}
+func (c *stringConverter) MarshalAppend(b []byte, num protowire.Number, v pref.Value) []byte {
+	return appendScalar(b, num, pref.StringKind, v)
+}
+func (c *bytesConverter) MarshalAppend(b []byte, num protowire.Number, v pref.Value) []byte {
+	return appendScalar(b, num, pref.BytesKind, v)
+}
+func (c *enumConverter) MarshalAppend(b []byte, num protowire.Number, v pref.Value) []byte {
+	return appendScalar(b, num, pref.EnumKind, v)
+}
+
+// appendScalar appends the tag and wire encoding of the scalar value v
+// of kind k to b.
+func appendScalar(b []byte, num protowire.Number, k pref.Kind, v pref.Value) []byte {
+	switch k {
+	case pref.BoolKind:
+		b = protowire.AppendTag(b, num, protowire.VarintType)
+		return protowire.AppendVarint(b, protowire.EncodeBool(v.Bool()))
+	case pref.EnumKind:
+		b = protowire.AppendTag(b, num, protowire.VarintType)
+		return protowire.AppendVarint(b, uint64(v.Enum()))
+	case pref.Int32Kind, pref.Int64Kind:
+		b = protowire.AppendTag(b, num, protowire.VarintType)
+		return protowire.AppendVarint(b, uint64(v.Int()))
+	case pref.Sint32Kind, pref.Sint64Kind:
+		b = protowire.AppendTag(b, num, protowire.VarintType)
+		return protowire.AppendVarint(b, protowire.EncodeZigZag(v.Int()))
+	case pref.Uint32Kind, pref.Uint64Kind:
+		b = protowire.AppendTag(b, num, protowire.VarintType)
+		return protowire.AppendVarint(b, v.Uint())
+	case pref.Sfixed32Kind:
+		b = protowire.AppendTag(b, num, protowire.Fixed32Type)
+		return protowire.AppendFixed32(b, uint32(v.Int()))
+	case pref.Fixed32Kind:
+		b = protowire.AppendTag(b, num, protowire.Fixed32Type)
+		return protowire.AppendFixed32(b, uint32(v.Uint()))
+	case pref.FloatKind:
+		b = protowire.AppendTag(b, num, protowire.Fixed32Type)
+		return protowire.AppendFixed32(b, math.Float32bits(float32(v.Float())))
+	case pref.Sfixed64Kind:
+		b = protowire.AppendTag(b, num, protowire.Fixed64Type)
+		return protowire.AppendFixed64(b, uint64(v.Int()))
+	case pref.Fixed64Kind:
+		b = protowire.AppendTag(b, num, protowire.Fixed64Type)
+		return protowire.AppendFixed64(b, v.Uint())
+	case pref.DoubleKind:
+		b = protowire.AppendTag(b, num, protowire.Fixed64Type)
+		return protowire.AppendFixed64(b, math.Float64bits(v.Float()))
+	case pref.StringKind:
+		b = protowire.AppendTag(b, num, protowire.BytesType)
+		return protowire.AppendString(b, v.String())
+	case pref.BytesKind:
+		b = protowire.AppendTag(b, num, protowire.BytesType)
+		return protowire.AppendBytes(b, v.Bytes())
+	}
+	panic(fmt.Sprintf("invalid scalar kind: %v", k))
+}
+
+// FramePrefix is the encoding of the length prefix of a framed payload.
+type FramePrefix int
+
+const (
+	// VarintFrame prefixes the payload with its length as a varint.
+	VarintFrame FramePrefix = iota
+	// Fixed32Frame prefixes the payload with its length as
+	// a little-endian 32-bit integer, as in a fixed32 field.
+	Fixed32Frame
+)
+
+// NewFramedConverter returns a TryConverter between a []byte payload and
+// a string or bytes field holding the payload preceded by its length,
+// encoded according to prefix. Values whose prefix is malformed, or does
+// not match the length of the rest of the value, are reported by
+// TryGoValueOf. Since the prefix is binary, a string field may hold
+// invalid UTF-8, which proto3 rejects when marshaling.
+func NewFramedConverter(prefix FramePrefix, fd pref.FieldDescriptor) TryConverter {
+	if fd.Kind() != pref.StringKind && fd.Kind() != pref.BytesKind {
+		panic(fmt.Sprintf("invalid field %v for framing: got %v, want string or bytes", fd.FullName(), fd.Kind()))
+	}
+	if prefix != VarintFrame && prefix != Fixed32Frame {
+		panic(fmt.Sprintf("invalid frame prefix %d for field %v", prefix, fd.FullName()))
+	}
+	isBytes := fd.Kind() == pref.BytesKind
+	return &funcConverter{
+		goType: bytesType,
+		pb:     newSingularConverter(scalarGoType(fd.Kind()), fd),
+		toPB: func(v reflect.Value) (pref.Value, error) {
+			payload := v.Bytes()
+			var b []byte
+			switch prefix {
+			case VarintFrame:
+				b = protowire.AppendVarint(b, uint64(len(payload)))
+			case Fixed32Frame:
+				if uint64(len(payload)) > math.MaxUint32 {
+					return pref.Value{}, fmt.Errorf("payload of %d bytes too large for field %v", len(payload), fd.FullName())
+				}
+				b = protowire.AppendFixed32(b, uint32(len(payload)))
+			}
+			b = append(b, payload...)
+			if isBytes {
+				return pref.ValueOfBytes(b), nil
+			}
+			return pref.ValueOfString(string(b)), nil
+		},
+		toGo: func(v pref.Value) (reflect.Value, error) {
+			var b []byte
+			if isBytes {
+				b = v.Bytes()
+			} else {
+				b = []byte(v.String())
+			}
+			var size uint64
+			var n int
+			switch prefix {
+			case VarintFrame:
+				size, n = protowire.ConsumeVarint(b)
+			case Fixed32Frame:
+				var size32 uint32
+				size32, n = protowire.ConsumeFixed32(b)
+				size = uint64(size32)
+			}
+			if n < 0 {
+				return reflect.Value{}, fmt.Errorf("invalid frame prefix in field %v: %w", fd.FullName(), protowire.ParseError(n))
+			}
+			if size != uint64(len(b)-n) {
+				return reflect.Value{}, fmt.Errorf("invalid frame in field %v: prefix declares %d bytes, but %d follow", fd.FullName(), size, len(b)-n)
+			}
+			return reflect.ValueOf(append([]byte(nil), b[n:]...)), nil
+		},
+	}
+}
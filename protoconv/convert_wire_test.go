@@ -0,0 +1,111 @@
+package protoconv_test
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/dnscrypt/dnscrypt-proxy/protoconv"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	pref "google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+func TestMarshalAppend(t *testing.T) {
+	tests := []struct {
+		name  string
+		m     proto.Message
+		field pref.Name
+		goVal interface{}
+	}{
+		{"bool", &Value{Kind: &Value_BoolValue{BoolValue: true}}, "bool_value", true},
+		{"enum", &Value{Kind: &Value_NullValue{}}, "null_value", NullValue(0)},
+		{"double", &Value{Kind: &Value_NumberValue{NumberValue: -1.5}}, "number_value", -1.5},
+		{"string", &Value{Kind: &Value_StringValue{StringValue: "héllo"}}, "string_value", "héllo"},
+		{"int64", &Int64Value{Value: -3}, "value", int64(-3)},
+		{"int32", &durationpb.Duration{Nanos: -7}, "nanos", int32(-7)},
+		{"bytes", &Chunk{Data: []byte{0, 1, 255}}, "data", []byte{0, 1, 255}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want, err := proto.Marshal(tt.m)
+			if err != nil {
+				t.Fatalf("Marshal(%v) error: %v", tt.m, err)
+			}
+			fd := tt.m.ProtoReflect().Descriptor().Fields().ByName(tt.field)
+			c := protoconv.NewConverter(reflect.TypeOf(tt.goVal), fd).(protoconv.MarshalAppender)
+			got := c.MarshalAppend([]byte("prefix"), fd.Number(), tt.m.ProtoReflect().Get(fd))
+			if !bytes.Equal(got, append([]byte("prefix"), want...)) {
+				t.Errorf("MarshalAppend = %x, want prefix followed by %x", got, want)
+			}
+		})
+	}
+}
+
+func TestMarshalAppendFixed64(t *testing.T) {
+	c := protoconv.NewConverter(reflect.TypeOf(uint64(0)), field("u64")).(protoconv.MarshalAppender)
+	got := c.MarshalAppend(nil, field("u64").Number(), pref.ValueOfUint64(0x0102030405060708))
+	want := protowire.AppendTag(nil, field("u64").Number(), protowire.Fixed64Type)
+	want = append(want, 8, 7, 6, 5, 4, 3, 2, 1)
+	if !bytes.Equal(got, want) {
+		t.Errorf("MarshalAppend = %x, want %x", got, want)
+	}
+}
+
+func TestFramedConverter(t *testing.T) {
+	tests := []struct {
+		name   string
+		prefix protoconv.FramePrefix
+		field  string
+		in     []byte
+		want   []byte
+	}{
+		{"varint bytes", protoconv.VarintFrame, "b", []byte("hello"), []byte("\x05hello")},
+		{"fixed32 bytes", protoconv.Fixed32Frame, "b", []byte("hello"), []byte("\x05\x00\x00\x00hello")},
+		{"varint string", protoconv.VarintFrame, "s", []byte("hi"), []byte("\x02hi")},
+		{"empty", protoconv.VarintFrame, "b", []byte{}, []byte{0}},
+		{"long varint", protoconv.VarintFrame, "b", bytes.Repeat([]byte("x"), 200), append([]byte{0xc8, 0x01}, bytes.Repeat([]byte("x"), 200)...)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := protoconv.NewFramedConverter(tt.prefix, field(tt.field))
+			v, err := c.TryPBValueOf(reflect.ValueOf(tt.in))
+			if err != nil {
+				t.Fatalf("TryPBValueOf error: %v", err)
+			}
+			got := []byte(v.String())
+			if tt.field == "b" {
+				got = v.Bytes()
+			}
+			if !bytes.Equal(got, tt.want) {
+				t.Errorf("TryPBValueOf(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+			back, err := c.TryGoValueOf(v)
+			if err != nil || !bytes.Equal(back.Bytes(), tt.in) {
+				t.Errorf("TryGoValueOf = %q, %v, want %q", back.Bytes(), err, tt.in)
+			}
+		})
+	}
+}
+
+func TestFramedConverterMalformed(t *testing.T) {
+	tests := []struct {
+		name   string
+		prefix protoconv.FramePrefix
+		in     []byte
+	}{
+		{"truncated payload", protoconv.VarintFrame, []byte("\x05hell")},
+		{"trailing bytes", protoconv.VarintFrame, []byte("\x05hello!")},
+		{"truncated varint", protoconv.VarintFrame, []byte{0x80}},
+		{"empty", protoconv.VarintFrame, nil},
+		{"truncated fixed32", protoconv.Fixed32Frame, []byte{5, 0, 0}},
+		{"fixed32 truncated payload", protoconv.Fixed32Frame, []byte("\x05\x00\x00\x00hell")},
+	}
+	for _, tt := range tests {
+		c := protoconv.NewFramedConverter(tt.prefix, field("b"))
+		if _, err := c.TryGoValueOf(pref.ValueOfBytes(tt.in)); err == nil {
+			t.Errorf("%s: TryGoValueOf(%q) succeeded, want error", tt.name, tt.in)
+		}
+	}
+}
@@ -0,0 +1,455 @@
+package protoconv
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"reflect"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protowire"
+	pref "google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// The converters in this file convert between Go types and the well-known
+// types. Since the packages for some of the well-known types are not
+// dependencies of this module, their message types are resolved from the
+// global registry and must be linked into the program.
+
+// wellKnownMessageType returns the message type of fd,
+// which must be a message declared in the well-known file path.
+func wellKnownMessageType(fd pref.FieldDescriptor, path string) pref.MessageType {
+	md := fd.Message()
+	if md == nil || md.ParentFile() == nil || md.ParentFile().Path() != path {
+		panic(fmt.Sprintf("invalid field %v: want message declared in %v", fd.FullName(), path))
+	}
+	return findMessageType(fd)
+}
+
+// wrapperConverter converts between a pointer to a scalar and
+// one of the wrapper messages in wrappers.proto, such as
+// *string and google.protobuf.StringValue.
+type wrapperConverter struct {
+	goType   reflect.Type // *T
+	fd       pref.FieldDescriptor
+	mt       pref.MessageType
+	valueFd  pref.FieldDescriptor
+	conv     Converter // converts T
+	repeated bool
+}
+
+// NewWrapperConverter returns a TryConverter between a pointer to a scalar
+// and a wrapper message field, such as *string for google.protobuf.StringValue.
+// For a repeated field, t must be a slice of such pointers, such as []*string.
+//
+// A nil pointer converts to an absent message for a singular field.
+// Since lists cannot contain absent messages, a nil element of a repeated
+// field is reported as an error.
+func NewWrapperConverter(t reflect.Type, fd pref.FieldDescriptor) Converter {
+	mt := wellKnownMessageType(fd, wrappersFile)
+	et := t
+	if fd.IsList() {
+		if t.Kind() != reflect.Slice {
+			panic(fmt.Sprintf("invalid Go type %v for field %v", t, fd.FullName()))
+		}
+		et = t.Elem()
+	}
+	if et.Kind() != reflect.Ptr {
+		panic(fmt.Sprintf("invalid Go type %v for field %v", t, fd.FullName()))
+	}
+	valueFd := mt.Descriptor().Fields().ByNumber(wrapperValueField)
+	c := &wrapperConverter{
+		goType:   et,
+		fd:       fd,
+		mt:       mt,
+		valueFd:  valueFd,
+		conv:     newSingularConverter(et.Elem(), valueFd),
+		repeated: fd.IsList(),
+	}
+	if fd.IsList() {
+		return &listConverter{goType: t, c: c}
+	}
+	return c
+}
+
+func (c *wrapperConverter) TryPBValueOf(v reflect.Value) (pref.Value, error) {
+	if v.Type() != c.goType {
+		panic(fmt.Sprintf("invalid type: got %v, want %v", v.Type(), c.goType))
+	}
+	if v.IsNil() {
+		if c.repeated {
+			return pref.Value{}, fmt.Errorf("invalid nil element in repeated field %v", c.fd.FullName())
+		}
+		return pref.ValueOfMessage(c.mt.Zero()), nil
+	}
+	m := c.mt.New()
+	m.Set(c.valueFd, c.conv.PBValueOf(v.Elem()))
+	return pref.ValueOfMessage(m), nil
+}
+
+func (c *wrapperConverter) TryGoValueOf(v pref.Value) (reflect.Value, error) {
+	m := v.Message()
+	if !m.IsValid() {
+		if c.repeated {
+			return reflect.Value{}, fmt.Errorf("invalid nil element in repeated field %v", c.fd.FullName())
+		}
+		return reflect.Zero(c.goType), nil
+	}
+	pv := reflect.New(c.goType.Elem())
+	pv.Elem().Set(c.conv.GoValueOf(m.Get(c.valueFd)))
+	return pv, nil
+}
+
+func (c *wrapperConverter) PBValueOf(v reflect.Value) pref.Value {
+	pv, err := c.TryPBValueOf(v)
+	if err != nil {
+		panic(err)
+	}
+	return pv
+}
+
+func (c *wrapperConverter) GoValueOf(v pref.Value) reflect.Value {
+	rv, err := c.TryGoValueOf(v)
+	if err != nil {
+		panic(err)
+	}
+	return rv
+}
+
+func (c *wrapperConverter) IsValidPB(v pref.Value) bool {
+	m, ok := v.Interface().(pref.Message)
+	return ok && m.Descriptor() == c.mt.Descriptor()
+}
+
+func (c *wrapperConverter) IsValidGo(v reflect.Value) bool {
+	return v.IsValid() && v.Type() == c.goType
+}
+
+func (c *wrapperConverter) New() pref.Value {
+	return pref.ValueOfMessage(c.mt.New())
+}
+
+func (c *wrapperConverter) Zero() pref.Value {
+	return pref.ValueOfMessage(c.mt.Zero())
+}
+
+func (c *wrapperConverter) IsZeroValue(v pref.Value) bool {
+	return !v.Message().IsValid()
+}
+
+func (c *wrapperConverter) Pretty(v pref.Value) string {
+	return prettyMessage(v.Message())
+}
+
+func (c *wrapperConverter) FieldNumber() protowire.Number {
+	return c.fd.Number()
+}
+
+// Bounds of google.protobuf.Timestamp, which is restricted to
+// years 0001 through 9999.
+const (
+	minTimestampSeconds = -62135596800
+	maxTimestampSeconds = 253402300799
+)
+
+// StripMonotonic returns t without its monotonic clock reading,
+// as is the case for every time converted from a Timestamp.
+func StripMonotonic(t time.Time) time.Time {
+	return t.Round(0)
+}
+
+// timestampConverter converts between time.Time and google.protobuf.Timestamp.
+type timestampConverter struct {
+	fd       pref.FieldDescriptor
+	mt       pref.MessageType
+	secondFd pref.FieldDescriptor
+	nanoFd   pref.FieldDescriptor
+
+	// zeroAbsent specifies that the zero time.Time converts to and from
+	// an absent message.
+	zeroAbsent bool
+}
+
+// NewTimestampConverter returns a TryConverter between time.Time and
+// a google.protobuf.Timestamp field. Times outside of the range of
+// Timestamp are reported by TryPBValueOf, and messages whose seconds
+// or nanos are out of range are reported by TryGoValueOf.
+// Times converted from a message are in UTC, and an absent message
+// converts to the Unix epoch. See NewOptionalTimestampConverter for
+// a Converter that represents the zero time.Time as an absent message.
+//
+// Times converted from a message have no monotonic clock reading, so
+// a time from time.Now does not compare equal with == to itself after
+// a round trip. Compare with time.Time.Equal, or compare the round-tripped
+// time with StripMonotonic(t).UTC().
+func NewTimestampConverter(fd pref.FieldDescriptor) TryConverter {
+	return newTimestampConverter(fd, false)
+}
+
+// NewOptionalTimestampConverter is like NewTimestampConverter, but converts
+// the zero time.Time, for which time.Time.IsZero reports true, to an absent
+// message, and an absent message back to the zero time.Time. This suits
+// fields whose presence is meaningful, where the zero time would otherwise
+// be set as the minimum Timestamp, and an absent message read as the
+// Unix epoch. A present message holding the Unix epoch converts to the
+// Unix epoch.
+func NewOptionalTimestampConverter(fd pref.FieldDescriptor) TryConverter {
+	return newTimestampConverter(fd, true)
+}
+
+func newTimestampConverter(fd pref.FieldDescriptor, zeroAbsent bool) *timestampConverter {
+	mt := wellKnownMessageType(fd, timestampFile)
+	fds := mt.Descriptor().Fields()
+	return &timestampConverter{
+		fd:         fd,
+		mt:         mt,
+		secondFd:   fds.ByNumber(timestampSecondsField),
+		nanoFd:     fds.ByNumber(timestampNanosField),
+		zeroAbsent: zeroAbsent,
+	}
+}
+
+func (c *timestampConverter) TryPBValueOf(v reflect.Value) (pref.Value, error) {
+	if v.Type() != timeType {
+		panic(fmt.Sprintf("invalid type: got %v, want %v", v.Type(), timeType))
+	}
+	t := v.Interface().(time.Time)
+	if c.zeroAbsent && t.IsZero() {
+		return c.Zero(), nil
+	}
+	secs, nanos := t.Unix(), int32(t.Nanosecond())
+	if secs < minTimestampSeconds || secs > maxTimestampSeconds {
+		return pref.Value{}, fmt.Errorf("time %v out of range for field %v", t, c.fd.FullName())
+	}
+	m := c.mt.New()
+	m.Set(c.secondFd, pref.ValueOfInt64(secs))
+	m.Set(c.nanoFd, pref.ValueOfInt32(nanos))
+	return pref.ValueOfMessage(m), nil
+}
+
+func (c *timestampConverter) TryGoValueOf(v pref.Value) (reflect.Value, error) {
+	m := v.Message()
+	if c.zeroAbsent && !m.IsValid() {
+		return reflect.ValueOf(time.Time{}), nil
+	}
+	secs, nanos := m.Get(c.secondFd).Int(), m.Get(c.nanoFd).Int()
+	switch {
+	case secs < minTimestampSeconds || secs > maxTimestampSeconds:
+		return reflect.Value{}, fmt.Errorf("seconds %d out of range for field %v", secs, c.fd.FullName())
+	case nanos < 0 || nanos >= 1e9:
+		return reflect.Value{}, fmt.Errorf("nanos %d out of range for field %v", nanos, c.fd.FullName())
+	}
+	return reflect.ValueOf(time.Unix(secs, nanos).UTC()), nil
+}
+
+func (c *timestampConverter) PBValueOf(v reflect.Value) pref.Value {
+	pv, err := c.TryPBValueOf(v)
+	if err != nil {
+		panic(err)
+	}
+	return pv
+}
+
+func (c *timestampConverter) GoValueOf(v pref.Value) reflect.Value {
+	rv, err := c.TryGoValueOf(v)
+	if err != nil {
+		panic(err)
+	}
+	return rv
+}
+
+func (c *timestampConverter) IsValidPB(v pref.Value) bool {
+	m, ok := v.Interface().(pref.Message)
+	return ok && m.Descriptor() == c.mt.Descriptor()
+}
+
+func (c *timestampConverter) IsValidGo(v reflect.Value) bool {
+	return v.IsValid() && v.Type() == timeType
+}
+
+func (c *timestampConverter) New() pref.Value {
+	return pref.ValueOfMessage(c.mt.New())
+}
+
+func (c *timestampConverter) Zero() pref.Value {
+	return pref.ValueOfMessage(c.mt.Zero())
+}
+
+func (c *timestampConverter) IsZeroValue(v pref.Value) bool {
+	return !v.Message().IsValid()
+}
+
+func (c *timestampConverter) Pretty(v pref.Value) string {
+	return prettyMessage(v.Message())
+}
+
+func (c *timestampConverter) FieldNumber() protowire.Number {
+	return c.fd.Number()
+}
+
+// Bound of google.protobuf.Duration, which is restricted to
+// approximately 10,000 years in either direction.
+const maxDurationSeconds = 315576000000
+
+// NewDurationConverter returns a TryConverter between time.Duration and
+// a google.protobuf.Duration field. Every time.Duration is in range for
+// a Duration, while messages whose seconds or nanos are out of range, whose
+// seconds and nanos differ in sign, or that overflow a time.Duration,
+// which spans about 292 years, are reported by TryGoValueOf.
+// An absent message converts to zero.
+func NewDurationConverter(fd pref.FieldDescriptor) TryConverter {
+	mt := wellKnownMessageType(fd, durationFile)
+	secondFd := mt.Descriptor().Fields().ByNumber(durationSecondsField)
+	nanoFd := mt.Descriptor().Fields().ByNumber(durationNanosField)
+	return &funcConverter{
+		goType: durationType,
+		pb:     newMessageConverter(reflect.TypeOf(mt.Zero().Interface()), fd),
+		toPB: func(v reflect.Value) (pref.Value, error) {
+			d := time.Duration(v.Int())
+			m := mt.New()
+			m.Set(secondFd, pref.ValueOfInt64(int64(d/time.Second)))
+			m.Set(nanoFd, pref.ValueOfInt32(int32(d%time.Second)))
+			return pref.ValueOfMessage(m), nil
+		},
+		toGo: func(v pref.Value) (reflect.Value, error) {
+			m := v.Message()
+			secs, nanos := m.Get(secondFd).Int(), m.Get(nanoFd).Int()
+			switch {
+			case secs < -maxDurationSeconds || secs > maxDurationSeconds:
+				return reflect.Value{}, fmt.Errorf("seconds %d out of range for field %v", secs, fd.FullName())
+			case nanos <= -1e9 || nanos >= 1e9 || (secs < 0 && nanos > 0) || (secs > 0 && nanos < 0):
+				return reflect.Value{}, fmt.Errorf("nanos %d out of range for seconds %d of field %v", nanos, secs, fd.FullName())
+			case secs > math.MaxInt64/int64(time.Second) || secs < math.MinInt64/int64(time.Second):
+				return reflect.Value{}, fmt.Errorf("duration of %d seconds for field %v overflows %v", secs, fd.FullName(), durationType)
+			}
+			d := time.Duration(secs) * time.Second
+			n := d + time.Duration(nanos)
+			if (nanos > 0 && n < d) || (nanos < 0 && n > d) {
+				return reflect.Value{}, fmt.Errorf("duration of %d seconds and %d nanos for field %v overflows %v", secs, nanos, fd.FullName(), durationType)
+			}
+			return reflect.ValueOf(n), nil
+		},
+	}
+}
+
+// NewDurationMapConverter returns a Converter between a Go map type t with
+// time.Duration values, such as map[string]time.Duration, and a map field
+// with google.protobuf.Duration values, such as map<string, Duration>.
+// Every value is converted as by NewDurationConverter, and the keys are
+// converted as by NewConverter. Negative durations round-trip, with
+// seconds and nanos of the same sign. Setting an entry whose Duration
+// overflows a time.Duration panics with the error of TryGoValueOf.
+func NewDurationMapConverter(t reflect.Type, fd pref.FieldDescriptor) Converter {
+	if t.Kind() != reflect.Map || t.Elem() != durationType || !fd.IsMap() {
+		panic(fmt.Sprintf("invalid Go type %v for field %v: want map with time.Duration values", t, fd.FullName()))
+	}
+	return NewMapConverterWith(t, nil, NewDurationConverter(fd.MapValue()), fd)
+}
+
+var timeSliceType = reflect.TypeOf([]time.Time(nil))
+
+// NewTimestampListConverter returns a Converter between []time.Time and
+// a repeated google.protobuf.Timestamp field, whose elements are converted
+// as by NewTimestampConverter. This includes the zero time.Time, which is
+// the minimum Timestamp, and times before the Unix epoch. Elements that
+// are out of range are reported by TryPBValuesOf.
+func NewTimestampListConverter(fd pref.FieldDescriptor) Converter {
+	if !fd.IsList() {
+		panic(fmt.Sprintf("invalid field %v for %v: want repeated field", fd.FullName(), timeSliceType))
+	}
+	return &listConverter{goType: timeSliceType, c: NewTimestampConverter(fd)}
+}
+
+// NewZonedTimeConverter returns a TryConverter between time.Time and
+// a message field whose message holds both the instant, in the
+// google.protobuf.Timestamp field named instant, and the name of its
+// location, in the sibling string field named zone. The instant is converted
+// as by NewTimestampConverter, and the location is loaded by name with
+// time.LoadLocation, falling back to UTC for zones that are unknown
+// to the system. Other fields of the message are left unset.
+func NewZonedTimeConverter(fd pref.FieldDescriptor, instant, zone pref.Name) TryConverter {
+	if fd.IsList() || fd.IsMap() {
+		panic(fmt.Sprintf("invalid field %v for zoned time: want singular message", fd.FullName()))
+	}
+	mt := findMessageType(fd)
+	instantFd := mt.Descriptor().Fields().ByName(instant)
+	zoneFd := mt.Descriptor().Fields().ByName(zone)
+	if instantFd == nil || zoneFd == nil || zoneFd.Kind() != pref.StringKind || zoneFd.IsList() {
+		panic(fmt.Sprintf("invalid message %v for field %v: want Timestamp field %v and string field %v", mt.Descriptor().FullName(), fd.FullName(), instant, zone))
+	}
+	ts := NewTimestampConverter(instantFd)
+	return &funcConverter{
+		goType: timeType,
+		pb:     newMessageConverter(reflect.TypeOf(mt.Zero().Interface()), fd),
+		toPB: func(v reflect.Value) (pref.Value, error) {
+			pv, err := ts.TryPBValueOf(v)
+			if err != nil {
+				return pref.Value{}, err
+			}
+			m := mt.New()
+			m.Set(instantFd, pv)
+			m.Set(zoneFd, pref.ValueOfString(v.Interface().(time.Time).Location().String()))
+			return pref.ValueOfMessage(m), nil
+		},
+		toGo: func(v pref.Value) (reflect.Value, error) {
+			m := v.Message()
+			rv, err := ts.TryGoValueOf(m.Get(instantFd))
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			loc, err := time.LoadLocation(m.Get(zoneFd).String())
+			if err != nil {
+				loc = time.UTC
+			}
+			return reflect.ValueOf(rv.Interface().(time.Time).In(loc)), nil
+		},
+	}
+}
+
+var nullTimeType = reflect.TypeOf(sql.NullTime{})
+
+// NewNullTimeConverter returns a TryConverter between t, which is either
+// sql.NullTime or *sql.NullTime, and a google.protobuf.Timestamp field.
+// A NullTime that is not Valid, or a nil pointer, converts to an absent
+// message, and an absent message converts back to the zero NullTime,
+// or to a nil pointer. Valid times are converted as by NewTimestampConverter.
+func NewNullTimeConverter(t reflect.Type, fd pref.FieldDescriptor) TryConverter {
+	if t != nullTimeType && t != reflect.PtrTo(nullTimeType) {
+		panic(fmt.Sprintf("invalid Go type %v for field %v: want sql.NullTime or *sql.NullTime", t, fd.FullName()))
+	}
+	ts := NewTimestampConverter(fd)
+	isPtr := t.Kind() == reflect.Ptr
+	return &funcConverter{
+		goType: t,
+		pb:     ts,
+		toPB: func(v reflect.Value) (pref.Value, error) {
+			if isPtr {
+				if v.IsNil() {
+					return ts.Zero(), nil
+				}
+				v = v.Elem()
+			}
+			nt := v.Interface().(sql.NullTime)
+			if !nt.Valid {
+				return ts.Zero(), nil
+			}
+			return ts.TryPBValueOf(reflect.ValueOf(nt.Time))
+		},
+		toGo: func(v pref.Value) (reflect.Value, error) {
+			if !v.Message().IsValid() {
+				return reflect.Zero(t), nil
+			}
+			rv, err := ts.TryGoValueOf(v)
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			nt := reflect.ValueOf(sql.NullTime{Time: rv.Interface().(time.Time), Valid: true})
+			if isPtr {
+				pv := reflect.New(nullTimeType)
+				pv.Elem().Set(nt)
+				return pv, nil
+			}
+			return nt, nil
+		},
+	}
+}
@@ -0,0 +1,325 @@
+package protoconv_test
+
+import (
+	"database/sql"
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+	_ "time/tzdata"
+
+	"github.com/dnscrypt/dnscrypt-proxy/protoconv"
+	pref "google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func strPtr(s string) *string { return &s }
+
+// valuesConverter is implemented by list converters that convert
+// every element up front.
+type valuesConverter interface {
+	TryPBValuesOf(reflect.Value) ([]pref.Value, error)
+}
+
+func TestWrapperConverter(t *testing.T) {
+	c := protoconv.NewWrapperConverter(reflect.TypeOf((*string)(nil)), field("sw"))
+	if m := c.PBValueOf(reflect.ValueOf((*string)(nil))).Message(); m.IsValid() {
+		t.Errorf("PBValueOf(nil) = %v, want an absent message", m)
+	}
+	v := c.PBValueOf(reflect.ValueOf(strPtr("x")))
+	if got := v.Message().Interface().(*StringValue).Value; got != "x" {
+		t.Errorf("PBValueOf(&%q) = %q", "x", got)
+	}
+	if got := c.GoValueOf(v).Interface().(*string); got == nil || *got != "x" {
+		t.Errorf("GoValueOf = %v, want &%q", got, "x")
+	}
+}
+
+func TestWrapperConverterRepeated(t *testing.T) {
+	c := protoconv.NewWrapperConverter(reflect.TypeOf([]*string(nil)), field("rsw"))
+	vs, err := c.(valuesConverter).TryPBValuesOf(reflect.ValueOf([]*string{strPtr("a"), strPtr("")}))
+	if err != nil {
+		t.Fatalf("TryPBValuesOf error: %v", err)
+	}
+	if got := vs[0].Message().Interface().(*StringValue).Value; got != "a" {
+		t.Errorf("element 0 = %q, want %q", got, "a")
+	}
+
+	_, err = c.(valuesConverter).TryPBValuesOf(reflect.ValueOf([]*string{strPtr("a"), nil}))
+	var errs protoconv.ListErrors
+	if !errors.As(err, &errs) || len(errs) != 1 || errs[0].Index != 1 {
+		t.Errorf("TryPBValuesOf with nil element error = %v, want error for element 1", err)
+	}
+}
+
+func TestTimestampConverter(t *testing.T) {
+	c := protoconv.NewTimestampConverter(field("ts"))
+	tests := []struct {
+		name    string
+		seconds int64
+		nanos   int32
+		wantErr bool
+	}{
+		{"valid", 1625142600, 999999999, false},
+		{"epoch", 0, 0, false},
+		{"nanos 1e9", 1625142600, 1e9, true},
+		{"negative nanos", 1625142600, -1, true},
+		{"before year 1", -62135596801, 0, true},
+		{"after year 9999", 253402300800, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &timestamppb.Timestamp{Seconds: tt.seconds, Nanos: tt.nanos}
+			got, err := c.TryGoValueOf(pref.ValueOfMessage(m.ProtoReflect()))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("TryGoValueOf(%v) error = %v, want error %v", m, err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if want := time.Unix(tt.seconds, int64(tt.nanos)).UTC(); got.Interface() != want {
+				t.Errorf("TryGoValueOf(%v) = %v, want %v", m, got, want)
+			}
+		})
+	}
+
+	if _, err := c.TryPBValueOf(reflect.ValueOf(time.Date(10000, 1, 1, 0, 0, 0, 0, time.UTC))); err == nil {
+		t.Error("TryPBValueOf(year 10000) succeeded, want error")
+	}
+}
+
+func TestZonedTimeConverter(t *testing.T) {
+	c := protoconv.NewZonedTimeConverter(blobField("event"), "at", "zone")
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name     string
+		in       time.Time
+		zone     string // if set, replaces the stored zone name
+		wantZone string
+	}{
+		{"zoned", time.Date(2021, 3, 4, 5, 6, 7, 8, newYork), "", "America/New_York"},
+		{"UTC", time.Date(2021, 3, 4, 5, 6, 7, 8, time.UTC), "", "UTC"},
+		{"unknown zone", time.Date(2021, 3, 4, 5, 6, 7, 8, newYork), "Mars/Olympus", "UTC"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v, err := c.TryPBValueOf(reflect.ValueOf(tt.in))
+			if err != nil {
+				t.Fatalf("TryPBValueOf error: %v", err)
+			}
+			e := v.Message().Interface().(*Event)
+			if tt.zone != "" {
+				e.Zone = tt.zone
+			}
+			rv, err := c.TryGoValueOf(v)
+			if err != nil {
+				t.Fatalf("TryGoValueOf error: %v", err)
+			}
+			got := rv.Interface().(time.Time)
+			if !got.Equal(tt.in) || got.Location().String() != tt.wantZone {
+				t.Errorf("TryGoValueOf = %v in %v, want %v in %v", got, got.Location(), tt.in, tt.wantZone)
+			}
+		})
+	}
+}
+
+func TestNullTimeConverter(t *testing.T) {
+	valid := sql.NullTime{Time: time.Unix(100, 5).UTC(), Valid: true}
+	tests := []struct {
+		name string
+		in   interface{}
+		want interface{} // the result of converting back
+	}{
+		{"invalid", sql.NullTime{}, sql.NullTime{}},
+		{"invalid with time", sql.NullTime{Time: valid.Time}, sql.NullTime{}},
+		{"valid", valid, valid},
+		{"nil pointer", (*sql.NullTime)(nil), (*sql.NullTime)(nil)},
+		{"invalid pointer", &sql.NullTime{}, (*sql.NullTime)(nil)},
+		{"valid pointer", &valid, &valid},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			in := reflect.ValueOf(tt.in)
+			c := protoconv.NewNullTimeConverter(in.Type(), field("ts"))
+			v, err := c.TryPBValueOf(in)
+			if err != nil {
+				t.Fatalf("TryPBValueOf error: %v", err)
+			}
+			wantPresent := reflect.DeepEqual(tt.want, valid) || reflect.DeepEqual(tt.want, &valid)
+			if v.Message().IsValid() != wantPresent {
+				t.Errorf("TryPBValueOf(%v) present = %v, want %v", tt.in, v.Message().IsValid(), wantPresent)
+			}
+			got, err := c.TryGoValueOf(v)
+			if err != nil {
+				t.Fatalf("TryGoValueOf error: %v", err)
+			}
+			if !reflect.DeepEqual(got.Interface(), tt.want) {
+				t.Errorf("round trip of %v = %v, want %v", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTimestampConverterMonotonic(t *testing.T) {
+	c := protoconv.NewTimestampConverter(field("ts"))
+	tests := []struct {
+		name string
+		in   time.Time
+	}{
+		{"now", time.Now()},
+		{"now in UTC", time.Now().UTC()},
+		{"wall clock", time.Date(2021, 3, 4, 5, 6, 7, 8, time.UTC)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := c.GoValueOf(c.PBValueOf(reflect.ValueOf(tt.in))).Interface().(time.Time)
+			if !got.Equal(tt.in) {
+				t.Errorf("round trip of %v = %v, want an equal time", tt.in, got)
+			}
+			if want := protoconv.StripMonotonic(tt.in).UTC(); got != want {
+				t.Errorf("round trip of %v = %v, want %v with ==", tt.in, got, want)
+			}
+			if got != protoconv.StripMonotonic(got) {
+				t.Errorf("round trip of %v has a monotonic clock reading", tt.in)
+			}
+		})
+	}
+}
+
+func TestTimestampListConverter(t *testing.T) {
+	c := protoconv.NewTimestampListConverter(field("rts"))
+	tests := []struct {
+		name string
+		in   []time.Time
+	}{
+		{"nil", nil},
+		{"zero", []time.Time{{}}},
+		{"pre-epoch", []time.Time{time.Date(1969, 7, 20, 20, 17, 40, 123, time.UTC)}},
+		{"mixed", []time.Time{{}, time.Date(1969, 7, 20, 20, 17, 40, 123, time.UTC), time.Unix(1e9, 5).UTC()}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			list := c.PBValueOf(reflect.ValueOf(tt.in)).List()
+			if list.Len() != len(tt.in) {
+				t.Fatalf("PBValueOf has %d elements, want %d", list.Len(), len(tt.in))
+			}
+			dst := c.New()
+			for i := 0; i < list.Len(); i++ {
+				ts := list.Get(i).Message().Interface().(*timestamppb.Timestamp)
+				if got := ts.AsTime(); !got.Equal(tt.in[i]) {
+					t.Errorf("element %d = %v, want %v", i, got, tt.in[i])
+				}
+				dst.List().Append(list.Get(i))
+			}
+			got := c.GoValueOf(dst).Interface().([]time.Time)
+			if len(got) != len(tt.in) {
+				t.Fatalf("GoValueOf has %d elements, want %d", len(got), len(tt.in))
+			}
+			for i := range got {
+				if got[i] != tt.in[i] {
+					t.Errorf("GoValueOf element %d = %v, want %v", i, got[i], tt.in[i])
+				}
+			}
+		})
+	}
+}
+
+func TestOptionalTimestampConverter(t *testing.T) {
+	tests := []struct {
+		name        string
+		c           protoconv.TryConverter
+		in          time.Time
+		wantPresent bool
+	}{
+		{"zero", protoconv.NewOptionalTimestampConverter(field("ts")), time.Time{}, false},
+		{"epoch", protoconv.NewOptionalTimestampConverter(field("ts")), time.Unix(0, 0).UTC(), true},
+		{"normal", protoconv.NewOptionalTimestampConverter(field("ts")), time.Date(2021, 5, 6, 7, 8, 9, 10, time.UTC), true},
+		{"zero without presence", protoconv.NewTimestampConverter(field("ts")), time.Time{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v, err := tt.c.TryPBValueOf(reflect.ValueOf(tt.in))
+			if err != nil {
+				t.Fatalf("TryPBValueOf(%v) error: %v", tt.in, err)
+			}
+			if got := v.Message().IsValid(); got != tt.wantPresent {
+				t.Errorf("TryPBValueOf(%v) present = %v, want %v", tt.in, got, tt.wantPresent)
+			}
+			got, err := tt.c.TryGoValueOf(v)
+			if err != nil || !got.Interface().(time.Time).Equal(tt.in) {
+				t.Errorf("TryGoValueOf = %v, %v, want %v", got, err, tt.in)
+			}
+		})
+	}
+}
+
+func TestDurationConverter(t *testing.T) {
+	c := protoconv.NewDurationConverter(field("dur"))
+	tests := []struct {
+		name    string
+		seconds int64
+		nanos   int32
+		want    time.Duration
+		wantErr bool
+	}{
+		{"positive", 1, 500000000, 1500 * time.Millisecond, false},
+		{"negative", -2, -3, -2*time.Second - 3, false},
+		{"zero", 0, 0, 0, false},
+		{"max", 9223372036, 854775807, math.MaxInt64, false},
+		{"min", -9223372036, -854775808, math.MinInt64, false},
+		{"overflow by nanos", 9223372036, 999999999, 0, true},
+		{"overflow by seconds", 1e11, 0, 0, true},
+		{"seconds out of range", 315576000001, 0, 0, true},
+		{"nanos out of range", 0, 1e9, 0, true},
+		{"mixed signs", -1, 1, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &durationpb.Duration{Seconds: tt.seconds, Nanos: tt.nanos}
+			got, err := c.TryGoValueOf(pref.ValueOfMessage(m.ProtoReflect()))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("TryGoValueOf(%v) error = %v, want error %v", m, err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if got.Interface() != tt.want {
+				t.Errorf("TryGoValueOf(%v) = %v, want %v", m, got, tt.want)
+			}
+			if back := c.GoValueOf(c.PBValueOf(got)).Interface(); back != tt.want {
+				t.Errorf("round trip of %v = %v", tt.want, back)
+			}
+		})
+	}
+	if got := c.GoValueOf(c.Zero()).Interface(); got != time.Duration(0) {
+		t.Errorf("GoValueOf(absent) = %v, want 0", got)
+	}
+}
+
+func TestDurationMapConverter(t *testing.T) {
+	c := protoconv.NewDurationMapConverter(reflect.TypeOf(map[string]time.Duration(nil)), field("mdur"))
+	tests := []struct {
+		name string
+		in   map[string]time.Duration
+	}{
+		{"multiple", map[string]time.Duration{"a": 1500 * time.Millisecond, "b": -2*time.Second - 3, "c": 0}},
+		{"extremes", map[string]time.Duration{"max": math.MaxInt64, "min": math.MinInt64}},
+		{"empty", map[string]time.Duration{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := c.PBValueOf(reflect.ValueOf(tt.in))
+			if got := v.Map().Len(); got != len(tt.in) {
+				t.Errorf("PBValueOf has %d entries, want %d", got, len(tt.in))
+			}
+			if got := c.GoValueOf(v).Interface(); !reflect.DeepEqual(got, tt.in) {
+				t.Errorf("GoValueOf = %v, want %v", got, tt.in)
+			}
+		})
+	}
+}
@@ -0,0 +1,280 @@
+package protoconv
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"sync"
+
+	pref "google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// The converters in this file wrap another Converter for the same field,
+// adding behavior around its conversions.
+
+// rangeConverter rejects integer values outside of [min, max].
+type rangeConverter struct {
+	Converter
+	min, max int64
+}
+
+// RangeValidatingConverter returns a TryConverter that wraps c,
+// which must convert an integer field, and rejects values outside
+// the inclusive range [min, max] in both directions.
+func RangeValidatingConverter(c Converter, min, max int64) TryConverter {
+	if min > max {
+		panic(fmt.Sprintf("invalid range [%d, %d]", min, max))
+	}
+	return &rangeConverter{c, min, max}
+}
+
+func (c *rangeConverter) TryPBValueOf(v reflect.Value) (pref.Value, error) {
+	pv, err := tryPBValueOf(c.Converter, v)
+	if err != nil {
+		return pref.Value{}, err
+	}
+	if err := c.check(pv); err != nil {
+		return pref.Value{}, err
+	}
+	return pv, nil
+}
+
+func (c *rangeConverter) TryGoValueOf(v pref.Value) (reflect.Value, error) {
+	if err := c.check(v); err != nil {
+		return reflect.Value{}, err
+	}
+	return tryGoValueOf(c.Converter, v)
+}
+
+func (c *rangeConverter) PBValueOf(v reflect.Value) pref.Value {
+	pv, err := c.TryPBValueOf(v)
+	if err != nil {
+		panic(err)
+	}
+	return pv
+}
+
+func (c *rangeConverter) GoValueOf(v pref.Value) reflect.Value {
+	rv, err := c.TryGoValueOf(v)
+	if err != nil {
+		panic(err)
+	}
+	return rv
+}
+
+func (c *rangeConverter) IsValidPB(v pref.Value) bool {
+	return c.Converter.IsValidPB(v) && c.check(v) == nil
+}
+
+// check reports an error if v is not an integer within the range.
+func (c *rangeConverter) check(v pref.Value) error {
+	switch x := v.Interface().(type) {
+	case int32, int64:
+		if n := v.Int(); n < c.min || n > c.max {
+			return fmt.Errorf("value %d out of range [%d, %d]", n, c.min, c.max)
+		}
+	case uint32, uint64:
+		if n := v.Uint(); n > math.MaxInt64 || int64(n) < c.min || int64(n) > c.max {
+			return fmt.Errorf("value %d out of range [%d, %d]", n, c.min, c.max)
+		}
+	default:
+		return fmt.Errorf("invalid value of type %T for range validation", x)
+	}
+	return nil
+}
+
+// deprecatedConverter warns once before its first conversion.
+type deprecatedConverter struct {
+	Converter
+	msg  string
+	warn func(string)
+	once sync.Once
+}
+
+// DeprecatedConverter returns a Converter that wraps c and passes msg to
+// warn the first time that any of its conversion methods is called.
+// This marks the converter of a specific field as deprecated in code,
+// independently of any options declared in the descriptor.
+// The returned Converter is a TryConverter whose Try methods call those
+// of c if it is a TryConverter, so errors that c reports are not turned
+// into panics. A nil warn disables the warning, so that callers can turn
+// deprecation reports off without unwrapping c.
+func DeprecatedConverter(c Converter, msg string, warn func(string)) Converter {
+	return &deprecatedConverter{Converter: c, msg: msg, warn: warn}
+}
+
+func (c *deprecatedConverter) TryPBValueOf(v reflect.Value) (pref.Value, error) {
+	c.once.Do(c.report)
+	return tryPBValueOf(c.Converter, v)
+}
+
+func (c *deprecatedConverter) TryGoValueOf(v pref.Value) (reflect.Value, error) {
+	c.once.Do(c.report)
+	return tryGoValueOf(c.Converter, v)
+}
+
+func (c *deprecatedConverter) PBValueOf(v reflect.Value) pref.Value {
+	c.once.Do(c.report)
+	return c.Converter.PBValueOf(v)
+}
+
+func (c *deprecatedConverter) GoValueOf(v pref.Value) reflect.Value {
+	c.once.Do(c.report)
+	return c.Converter.GoValueOf(v)
+}
+
+func (c *deprecatedConverter) report() {
+	if c.warn != nil {
+		c.warn(c.msg)
+	}
+}
+
+// reservedEnumConverter rejects enum numbers that are reserved.
+type reservedEnumConverter struct {
+	Converter
+	fd       pref.FieldDescriptor
+	reserved map[pref.EnumNumber]bool
+}
+
+// NewReservedEnumConverter returns a TryConverter between the Go enum type t
+// and the enum field fd, as by NewConverter, that rejects the reserved
+// enum numbers in both directions. Reserved numbers are set aside by the
+// schema authors and must not be used, even if the Go type declares them.
+func NewReservedEnumConverter(t reflect.Type, reserved []pref.EnumNumber, fd pref.FieldDescriptor) TryConverter {
+	if fd.Kind() != pref.EnumKind {
+		panic(fmt.Sprintf("invalid field %v for reserved enum numbers: got %v, want enum", fd.FullName(), fd.Kind()))
+	}
+	c := &reservedEnumConverter{
+		Converter: ConverterOptions{}.New(t, fd),
+		fd:        fd,
+		reserved:  make(map[pref.EnumNumber]bool, len(reserved)),
+	}
+	for _, n := range reserved {
+		c.reserved[n] = true
+	}
+	return c
+}
+
+func (c *reservedEnumConverter) TryPBValueOf(v reflect.Value) (pref.Value, error) {
+	pv, err := tryPBValueOf(c.Converter, v)
+	if err != nil {
+		return pref.Value{}, err
+	}
+	if err := c.check(pv); err != nil {
+		return pref.Value{}, err
+	}
+	return pv, nil
+}
+
+func (c *reservedEnumConverter) TryGoValueOf(v pref.Value) (reflect.Value, error) {
+	if err := c.check(v); err != nil {
+		return reflect.Value{}, err
+	}
+	return tryGoValueOf(c.Converter, v)
+}
+
+func (c *reservedEnumConverter) PBValueOf(v reflect.Value) pref.Value {
+	pv, err := c.TryPBValueOf(v)
+	if err != nil {
+		panic(err)
+	}
+	return pv
+}
+
+func (c *reservedEnumConverter) GoValueOf(v pref.Value) reflect.Value {
+	rv, err := c.TryGoValueOf(v)
+	if err != nil {
+		panic(err)
+	}
+	return rv
+}
+
+func (c *reservedEnumConverter) IsValidPB(v pref.Value) bool {
+	return c.Converter.IsValidPB(v) && c.check(v) == nil
+}
+
+// check reports an error if v is a reserved enum number.
+func (c *reservedEnumConverter) check(v pref.Value) error {
+	if n := v.Enum(); c.reserved[n] {
+		return fmt.Errorf("reserved number %d for enum %v of field %v", n, c.fd.Enum().FullName(), c.fd.FullName())
+	}
+	return nil
+}
+
+// CachingConverter creates Converters that skip the conversion by PBValueOf
+// if its argument equals the argument of the previous call, returning the
+// previous result instead. This helps when the same large value is converted
+// repeatedly.
+//
+// Since Go has no goroutine-local storage, the cache is scoped by the caller:
+// each goroutine obtains its own Converter from Local, whose cache entry is
+// not shared with any other Converter.
+type CachingConverter struct {
+	goType reflect.Type
+	c      Converter
+}
+
+// NewCachingConverter returns a CachingConverter wrapping c, which converts
+// the Go type t. Values of t are compared with ==, so t must be comparable
+// without panicking: it must not be an interface type, nor an array or struct
+// type holding one, as the dynamic value might not be comparable. Pointers are
+// equal only if they are identical, so the cache assumes that values are not
+// mutated between calls: a pointer whose pointee was modified still returns
+// the stale result.
+func NewCachingConverter(t reflect.Type, c Converter) *CachingConverter {
+	if !strictlyComparable(t) {
+		panic(fmt.Sprintf("invalid Go type %v for caching: want comparable type without interfaces", t))
+	}
+	if !c.IsValidGo(reflect.Zero(t)) {
+		panic(fmt.Sprintf("invalid Converter for Go type %v", t))
+	}
+	return &CachingConverter{goType: t, c: c}
+}
+
+// Local returns a Converter with its own cache entry, for use by a single
+// goroutine. It is not safe for concurrent use.
+func (cc *CachingConverter) Local() Converter {
+	return &cachingConverter{Converter: cc.c, goType: cc.goType}
+}
+
+// strictlyComparable reports whether values of t can be compared with ==
+// without panicking.
+func strictlyComparable(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Interface:
+		return false
+	case reflect.Array:
+		return strictlyComparable(t.Elem())
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			if !strictlyComparable(t.Field(i).Type) {
+				return false
+			}
+		}
+		return true
+	}
+	return t.Comparable()
+}
+
+// cachingConverter remembers the last value converted by PBValueOf.
+type cachingConverter struct {
+	Converter
+	goType reflect.Type
+
+	valid bool
+	goVal interface{}
+	pbVal pref.Value
+}
+
+func (c *cachingConverter) PBValueOf(v reflect.Value) pref.Value {
+	if v.Type() != c.goType {
+		panic(fmt.Sprintf("invalid type: got %v, want %v", v.Type(), c.goType))
+	}
+	x := v.Interface()
+	if c.valid && c.goVal == x {
+		return c.pbVal
+	}
+	pv := c.Converter.PBValueOf(v)
+	c.valid, c.goVal, c.pbVal = true, x, pv
+	return pv
+}
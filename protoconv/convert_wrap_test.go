@@ -0,0 +1,204 @@
+package protoconv_test
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/dnscrypt/dnscrypt-proxy/protoconv"
+	pref "google.golang.org/protobuf/reflect/protoreflect"
+)
+
+var int64Type = reflect.TypeOf(int64(0))
+
+func TestRangeValidatingConverter(t *testing.T) {
+	c := protoconv.RangeValidatingConverter(protoconv.NewConverter(int64Type, field("i64")), -10, 10)
+	tests := []struct {
+		name    string
+		in      int64
+		wantErr bool
+	}{
+		{"below min", -11, true},
+		{"min", -10, false},
+		{"in range", 3, false},
+		{"max", 10, false},
+		{"above max", 11, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := c.TryPBValueOf(reflect.ValueOf(tt.in)); (err != nil) != tt.wantErr {
+				t.Errorf("TryPBValueOf(%d) error = %v, want error %v", tt.in, err, tt.wantErr)
+			}
+			if _, err := c.TryGoValueOf(pref.ValueOfInt64(tt.in)); (err != nil) != tt.wantErr {
+				t.Errorf("TryGoValueOf(%d) error = %v, want error %v", tt.in, err, tt.wantErr)
+			}
+			if got := c.IsValidPB(pref.ValueOfInt64(tt.in)); got == tt.wantErr {
+				t.Errorf("IsValidPB(%d) = %v, want %v", tt.in, got, !tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDeprecatedConverter(t *testing.T) {
+	var mu sync.Mutex
+	var warnings []string
+	c := protoconv.DeprecatedConverter(protoconv.NewConverter(int64Type, field("i64")), "i64 is deprecated", func(msg string) {
+		mu.Lock()
+		defer mu.Unlock()
+		warnings = append(warnings, msg)
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			v := c.PBValueOf(reflect.ValueOf(int64(i)))
+			if got := c.GoValueOf(v).Int(); got != int64(i) {
+				t.Errorf("round trip of %d = %d", i, got)
+			}
+		}(i)
+	}
+	wg.Wait()
+	if len(warnings) != 1 || warnings[0] != "i64 is deprecated" {
+		t.Errorf("warnings = %q, want exactly one", warnings)
+	}
+}
+
+func TestDeprecatedConverterSilent(t *testing.T) {
+	c := protoconv.DeprecatedConverter(protoconv.NewConverter(int64Type, field("i64")), "i64 is deprecated", nil)
+	if got := c.GoValueOf(c.PBValueOf(reflect.ValueOf(int64(5)))).Int(); got != 5 {
+		t.Errorf("round trip of 5 = %d", got)
+	}
+}
+
+func TestDeprecatedConverterTry(t *testing.T) {
+	warnings := 0
+	c := protoconv.DeprecatedConverter(protoconv.RangeValidatingConverter(protoconv.NewConverter(int64Type, field("i64")), -10, 10), "i64 is deprecated", func(string) {
+		warnings++
+	}).(protoconv.TryConverter)
+	tests := []struct {
+		name    string
+		in      int64
+		wantErr bool
+	}{
+		{"in range", 3, false},
+		{"out of range", 11, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := c.TryPBValueOf(reflect.ValueOf(tt.in)); (err != nil) != tt.wantErr {
+				t.Errorf("TryPBValueOf(%d) error = %v, want error %v", tt.in, err, tt.wantErr)
+			}
+			if _, err := c.TryGoValueOf(pref.ValueOfInt64(tt.in)); (err != nil) != tt.wantErr {
+				t.Errorf("TryGoValueOf(%d) error = %v, want error %v", tt.in, err, tt.wantErr)
+			}
+		})
+	}
+	if warnings != 1 {
+		t.Errorf("warned %d times, want 1", warnings)
+	}
+}
+
+func TestReservedEnumConverter(t *testing.T) {
+	c := protoconv.NewReservedEnumConverter(reflect.TypeOf(E(0)), []pref.EnumNumber{2, 5}, field("e"))
+	tests := []struct {
+		name    string
+		in      E
+		wantErr bool
+	}{
+		{"zero", 0, false},
+		{"valid", 1, false},
+		{"reserved and declared", 2, true},
+		{"reserved and undeclared", 5, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := c.TryPBValueOf(reflect.ValueOf(tt.in)); (err != nil) != tt.wantErr {
+				t.Errorf("TryPBValueOf(%d) error = %v, want error %v", tt.in, err, tt.wantErr)
+			}
+			got, err := c.TryGoValueOf(pref.ValueOfEnum(pref.EnumNumber(tt.in)))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("TryGoValueOf(%d) error = %v, want error %v", tt.in, err, tt.wantErr)
+			}
+			if err == nil && got.Interface() != tt.in {
+				t.Errorf("TryGoValueOf(%d) = %v", tt.in, got)
+			}
+			if valid := c.IsValidPB(pref.ValueOfEnum(pref.EnumNumber(tt.in))); valid == tt.wantErr {
+				t.Errorf("IsValidPB(%d) = %v, want %v", tt.in, valid, !tt.wantErr)
+			}
+		})
+	}
+}
+
+// countingConverter counts the calls to PBValueOf.
+type countingConverter struct {
+	protoconv.Converter
+	n int
+}
+
+func (c *countingConverter) PBValueOf(v reflect.Value) pref.Value {
+	c.n++
+	return c.Converter.PBValueOf(v)
+}
+
+func TestCachingConverter(t *testing.T) {
+	inner := &countingConverter{Converter: protoconv.NewConverter(int64Type, field("i64"))}
+	c := protoconv.NewCachingConverter(int64Type, inner).Local()
+	tests := []struct {
+		in        int64
+		wantCalls int
+	}{
+		{1, 1},
+		{1, 1}, // hit
+		{2, 2}, // miss after a change
+		{2, 2}, // hit
+		{1, 3}, // only the last value is cached
+	}
+	for i, tt := range tests {
+		if got := c.PBValueOf(reflect.ValueOf(tt.in)).Int(); got != tt.in {
+			t.Errorf("step %d: PBValueOf(%d) = %d", i, tt.in, got)
+		}
+		if inner.n != tt.wantCalls {
+			t.Errorf("step %d: %d conversions, want %d", i, inner.n, tt.wantCalls)
+		}
+	}
+}
+
+func TestCachingConverterLocal(t *testing.T) {
+	inner := &countingConverter{Converter: protoconv.NewConverter(int64Type, field("i64"))}
+	cc := protoconv.NewCachingConverter(int64Type, inner)
+	a, b := cc.Local(), cc.Local()
+	a.PBValueOf(reflect.ValueOf(int64(1)))
+	b.PBValueOf(reflect.ValueOf(int64(2)))
+	a.PBValueOf(reflect.ValueOf(int64(1)))
+	b.PBValueOf(reflect.ValueOf(int64(2)))
+	if inner.n != 2 {
+		t.Errorf("%d conversions, want 2 as each Local Converter keeps its own entry", inner.n)
+	}
+}
+
+func TestCachingConverterInvalidType(t *testing.T) {
+	type withSlice struct{ s []int }
+	type withInterface struct{ x interface{} }
+	tests := []struct {
+		name string
+		t    reflect.Type
+	}{
+		{"interface", reflect.TypeOf((*interface{})(nil)).Elem()},
+		{"slice", reflect.TypeOf([]int(nil))},
+		{"struct with slice", reflect.TypeOf(withSlice{})},
+		{"struct with interface", reflect.TypeOf(withInterface{})},
+		{"array of interfaces", reflect.TypeOf([1]interface{}{})},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				if r := recover(); r == nil {
+					t.Errorf("NewCachingConverter(%v) did not panic", tt.t)
+				}
+			}()
+			protoconv.NewCachingConverter(tt.t, protoconv.NewConverter(int64Type, field("i64")))
+		})
+	}
+}
@@ -0,0 +1,14 @@
+// Package protoconv converts between Go values and protobuf field values,
+// so that Go types other than the ones protoc-gen-go generates can back
+// the fields of a message.
+//
+// A Converter pairs a Go type with a field descriptor. NewConverter matches
+// the types that protoc-gen-go generates, and the New*Converter functions
+// build converters for other Go types, such as time.Time for a Timestamp
+// field or net.IP for a bytes field. Converters may be registered for a
+// field with RegisterConverter, so that NewConverter returns them.
+//
+// The core of this package is derived from the converters of
+// google.golang.org/protobuf/internal/impl, which are not importable
+// outside of that module.
+package protoconv
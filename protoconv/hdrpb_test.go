@@ -0,0 +1,83 @@
+package protoconv_test
+
+import (
+	"reflect"
+
+	"google.golang.org/protobuf/encoding/prototext"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/runtime/protoimpl"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+type StringList struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Values []string `protobuf:"bytes,1,rep,name=values,proto3"`
+}
+
+func (x *StringList) ProtoReflect() protoreflect.Message { return hdrReflect(x, 0) }
+
+type Req struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Headers map[string]*StringList `protobuf:"bytes,1,rep,name=headers,proto3" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+}
+
+func (x *Req) ProtoReflect() protoreflect.Message { return hdrReflect(x, 1) }
+
+func hdrReflect(x interface{}, i int) protoreflect.Message {
+	mi := &hdrMsgTypes[i]
+	rv := reflect.ValueOf(x)
+	if protoimpl.UnsafeEnabled && !rv.IsNil() {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(rv.Pointer()))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+var hdrMsgTypes = make([]protoimpl.MessageInfo, 3)
+
+var hdrFile = func() protoreflect.FileDescriptor {
+	fdp := &descriptorpb.FileDescriptorProto{}
+	if err := prototext.Unmarshal([]byte(`
+		name: "hdr.proto" package: "hdr" syntax: "proto3"
+		message_type: [
+			{name: "StringList" field: [{name: "values" number: 1 label: LABEL_REPEATED type: TYPE_STRING json_name: "values"}]},
+			{name: "Req" field: [
+				{name: "headers" number: 1 label: LABEL_REPEATED type: TYPE_MESSAGE type_name: ".hdr.Req.HeadersEntry" json_name: "headers"}
+			] nested_type: [{name: "HeadersEntry" field: [
+				{name: "key" number: 1 label: LABEL_OPTIONAL type: TYPE_STRING json_name: "key"},
+				{name: "value" number: 2 label: LABEL_OPTIONAL type: TYPE_MESSAGE type_name: ".hdr.StringList" json_name: "value"}
+			] options: {map_entry: true}}]}
+		]
+	`), fdp); err != nil {
+		panic(err)
+	}
+	raw, err := proto.Marshal(fdp)
+	if err != nil {
+		panic(err)
+	}
+	type x struct{}
+	return protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: raw,
+			NumMessages:   3,
+		},
+		GoTypes:           []interface{}{(*StringList)(nil), (*Req)(nil), nil},
+		DependencyIndexes: []int32{2, 0, 2, 2, 2, 2, 0},
+		MessageInfos:      hdrMsgTypes,
+	}.Build().File
+}()
+
+func reqField(name string) protoreflect.FieldDescriptor {
+	return hdrFile.Messages().ByName("Req").Fields().ByName(protoreflect.Name(name))
+}
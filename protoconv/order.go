@@ -0,0 +1,70 @@
+package protoconv
+
+import (
+	"sort"
+
+	pref "google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// genericKeyOrder sorts false before true, numeric keys in ascending order,
+// and strings in lexicographical order by bytes.
+func genericKeyOrder(x, y pref.MapKey) bool {
+	switch x.Interface().(type) {
+	case bool:
+		return !x.Bool() && y.Bool()
+	case int32, int64:
+		return x.Int() < y.Int()
+	case uint32, uint64:
+		return x.Uint() < y.Uint()
+	case string:
+		return x.String() < y.String()
+	default:
+		panic("invalid map key type")
+	}
+}
+
+// rangeFields iterates over the populated fields of m in index order,
+// with extensions last, in order of their full names.
+func rangeFields(m pref.Message, f func(pref.FieldDescriptor, pref.Value) bool) {
+	type entry struct {
+		fd pref.FieldDescriptor
+		v  pref.Value
+	}
+	var entries []entry
+	m.Range(func(fd pref.FieldDescriptor, v pref.Value) bool {
+		entries = append(entries, entry{fd, v})
+		return true
+	})
+	sort.Slice(entries, func(i, j int) bool {
+		x, y := entries[i].fd, entries[j].fd
+		if x.IsExtension() != y.IsExtension() {
+			return !x.IsExtension()
+		}
+		if x.IsExtension() {
+			return x.FullName() < y.FullName()
+		}
+		return x.Index() < y.Index()
+	})
+	for _, e := range entries {
+		if !f(e.fd, e.v) {
+			return
+		}
+	}
+}
+
+// rangeEntries iterates over the entries of m in the order of genericKeyOrder.
+func rangeEntries(m pref.Map, f func(pref.MapKey, pref.Value) bool) {
+	var keys []pref.MapKey
+	m.Range(func(k pref.MapKey, _ pref.Value) bool {
+		keys = append(keys, k)
+		return true
+	})
+	sort.Slice(keys, func(i, j int) bool {
+		return genericKeyOrder(keys[i], keys[j])
+	})
+	for _, k := range keys {
+		if !f(k, m.Get(k)) {
+			return
+		}
+	}
+}
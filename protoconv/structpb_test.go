@@ -0,0 +1,173 @@
+package protoconv_test
+
+// Hand-written stand-in for the generated structpb package, which is not vendored.
+
+import (
+	"reflect"
+
+	"google.golang.org/protobuf/encoding/prototext"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/runtime/protoimpl"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+type NullValue int32
+
+func (NullValue) Descriptor() protoreflect.EnumDescriptor { return spEnumTypes[0].Descriptor() }
+func (NullValue) Type() protoreflect.EnumType             { return &spEnumTypes[0] }
+func (x NullValue) Number() protoreflect.EnumNumber       { return protoreflect.EnumNumber(x) }
+
+type Struct struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Fields map[string]*Value `protobuf:"bytes,1,rep,name=fields,proto3" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+}
+
+func (x *Struct) ProtoReflect() protoreflect.Message { return spReflect(x, 0) }
+
+type Value struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Kind isValue_Kind `protobuf_oneof:"kind"`
+}
+
+func (x *Value) ProtoReflect() protoreflect.Message { return spReflect(x, 1) }
+
+type isValue_Kind interface{ isValue_Kind() }
+
+type Value_NullValue struct {
+	NullValue NullValue `protobuf:"varint,1,opt,name=null_value,json=nullValue,proto3,enum=google.protobuf.NullValue,oneof"`
+}
+type Value_NumberValue struct {
+	NumberValue float64 `protobuf:"fixed64,2,opt,name=number_value,json=numberValue,proto3,oneof"`
+}
+type Value_StringValue struct {
+	StringValue string `protobuf:"bytes,3,opt,name=string_value,json=stringValue,proto3,oneof"`
+}
+type Value_BoolValue struct {
+	BoolValue bool `protobuf:"varint,4,opt,name=bool_value,json=boolValue,proto3,oneof"`
+}
+type Value_StructValue struct {
+	StructValue *Struct `protobuf:"bytes,5,opt,name=struct_value,json=structValue,proto3,oneof"`
+}
+type Value_ListValue struct {
+	ListValue *ListValue `protobuf:"bytes,6,opt,name=list_value,json=listValue,proto3,oneof"`
+}
+
+func (*Value_NullValue) isValue_Kind()   {}
+func (*Value_NumberValue) isValue_Kind() {}
+func (*Value_StringValue) isValue_Kind() {}
+func (*Value_BoolValue) isValue_Kind()   {}
+func (*Value_StructValue) isValue_Kind() {}
+func (*Value_ListValue) isValue_Kind()   {}
+
+type ListValue struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Values []*Value `protobuf:"bytes,1,rep,name=values,proto3"`
+}
+
+func (x *ListValue) ProtoReflect() protoreflect.Message { return spReflect(x, 2) }
+
+func spReflect(x interface{}, i int) protoreflect.Message {
+	mi := &spMsgTypes[i]
+	rv := reflect.ValueOf(x)
+	if protoimpl.UnsafeEnabled && !rv.IsNil() {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(rv.Pointer()))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+var (
+	spEnumTypes = make([]protoimpl.EnumInfo, 1)
+	spMsgTypes  = make([]protoimpl.MessageInfo, 4)
+	spGoTypes   = []interface{}{
+		(NullValue)(0),
+		(*Struct)(nil),
+		(*Value)(nil),
+		(*ListValue)(nil),
+		nil, // Struct.FieldsEntry
+	}
+	spDepIdxs = []int32{
+		4, // Struct.fields -> Struct.FieldsEntry
+		0, // Value.null_value -> NullValue
+		1, // Value.struct_value -> Struct
+		3, // Value.list_value -> ListValue
+		2, // ListValue.values -> Value
+		2, // Struct.FieldsEntry.value -> Value
+		6, 6, 6, 6, 0,
+	}
+	spRawDesc = func() []byte {
+		fdp := &descriptorpb.FileDescriptorProto{}
+		if err := prototext.Unmarshal([]byte(`
+			name: "google/protobuf/struct.proto"
+			package: "google.protobuf"
+			syntax: "proto3"
+			message_type: [{
+				name: "Struct"
+				field: [{name: "fields" number: 1 label: LABEL_REPEATED type: TYPE_MESSAGE type_name: ".google.protobuf.Struct.FieldsEntry" json_name: "fields"}]
+				nested_type: [{name: "FieldsEntry" options: {map_entry: true} field: [
+					{name: "key" number: 1 label: LABEL_OPTIONAL type: TYPE_STRING json_name: "key"},
+					{name: "value" number: 2 label: LABEL_OPTIONAL type: TYPE_MESSAGE type_name: ".google.protobuf.Value" json_name: "value"}]}]
+			}, {
+				name: "Value"
+				field: [
+					{name: "null_value" number: 1 label: LABEL_OPTIONAL type: TYPE_ENUM type_name: ".google.protobuf.NullValue" oneof_index: 0 json_name: "nullValue"},
+					{name: "number_value" number: 2 label: LABEL_OPTIONAL type: TYPE_DOUBLE oneof_index: 0 json_name: "numberValue"},
+					{name: "string_value" number: 3 label: LABEL_OPTIONAL type: TYPE_STRING oneof_index: 0 json_name: "stringValue"},
+					{name: "bool_value" number: 4 label: LABEL_OPTIONAL type: TYPE_BOOL oneof_index: 0 json_name: "boolValue"},
+					{name: "struct_value" number: 5 label: LABEL_OPTIONAL type: TYPE_MESSAGE type_name: ".google.protobuf.Struct" oneof_index: 0 json_name: "structValue"},
+					{name: "list_value" number: 6 label: LABEL_OPTIONAL type: TYPE_MESSAGE type_name: ".google.protobuf.ListValue" oneof_index: 0 json_name: "listValue"}
+				]
+				oneof_decl: [{name: "kind"}]
+			}, {
+				name: "ListValue"
+				field: [{name: "values" number: 1 label: LABEL_REPEATED type: TYPE_MESSAGE type_name: ".google.protobuf.Value" json_name: "values"}]
+			}]
+			enum_type: [{name: "NullValue" value: [{name: "NULL_VALUE" number: 0}]}]
+		`), fdp); err != nil {
+			panic(err)
+		}
+		b, err := proto.Marshal(fdp)
+		if err != nil {
+			panic(err)
+		}
+		return b
+	}()
+)
+
+// spFile is referenced by testMD so that it is built first.
+var spFile = func() protoreflect.FileDescriptor {
+	spMsgTypes[1].OneofWrappers = []interface{}{
+		(*Value_NullValue)(nil),
+		(*Value_NumberValue)(nil),
+		(*Value_StringValue)(nil),
+		(*Value_BoolValue)(nil),
+		(*Value_StructValue)(nil),
+		(*Value_ListValue)(nil),
+	}
+	type x struct{}
+	return protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: spRawDesc,
+			NumEnums:      1,
+			NumMessages:   4,
+		},
+		GoTypes:           spGoTypes,
+		DependencyIndexes: spDepIdxs,
+		EnumInfos:         spEnumTypes,
+		MessageInfos:      spMsgTypes,
+	}.Build().File
+}()
@@ -0,0 +1,135 @@
+package protoconv_test
+
+import (
+	"google.golang.org/protobuf/encoding/prototext"
+	"google.golang.org/protobuf/reflect/protodesc"
+	pref "google.golang.org/protobuf/reflect/protoreflect"
+	preg "google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	_ "google.golang.org/protobuf/types/known/durationpb"
+	_ "google.golang.org/protobuf/types/known/timestamppb"
+)
+
+var testMD = func() pref.MessageDescriptor {
+	_, _ = spFile, wpFile
+	fdp := &descriptorpb.FileDescriptorProto{}
+	if err := prototext.Unmarshal([]byte(`
+		name: "scratch.proto" package: "scratch" syntax: "proto3"
+		dependency: ["google/protobuf/duration.proto", "google/protobuf/timestamp.proto", "google/protobuf/struct.proto", "google/protobuf/wrappers.proto"]
+		enum_type: [{name: "E" value: [{name: "E_ZERO" number: 0}, {name: "MY_VALUE" number: 1}, {name: "OTHER" number: 2}]}]
+		message_type: [{
+			name: "M"
+			field: [
+				{name: "dur" number: 1 label: LABEL_OPTIONAL type: TYPE_MESSAGE type_name: ".google.protobuf.Duration"},
+				{name: "ts" number: 2 label: LABEL_OPTIONAL type: TYPE_MESSAGE type_name: ".google.protobuf.Timestamp"},
+				{name: "s" number: 3 label: LABEL_OPTIONAL type: TYPE_STRING},
+				{name: "rs" number: 4 label: LABEL_REPEATED type: TYPE_STRING},
+				{name: "b" number: 5 label: LABEL_OPTIONAL type: TYPE_BYTES},
+				{name: "e" number: 6 label: LABEL_OPTIONAL type: TYPE_ENUM type_name: ".scratch.E"},
+				{name: "re" number: 7 label: LABEL_REPEATED type: TYPE_ENUM type_name: ".scratch.E"},
+				{name: "i32" number: 8 label: LABEL_OPTIONAL type: TYPE_INT32},
+				{name: "i64" number: 9 label: LABEL_OPTIONAL type: TYPE_INT64},
+				{name: "ru32" number: 10 label: LABEL_REPEATED type: TYPE_UINT32},
+				{name: "rbool" number: 11 label: LABEL_REPEATED type: TYPE_BOOL},
+				{name: "rf" number: 12 label: LABEL_REPEATED type: TYPE_FLOAT},
+				{name: "rd" number: 13 label: LABEL_REPEATED type: TYPE_DOUBLE},
+				{name: "d" number: 14 label: LABEL_OPTIONAL type: TYPE_DOUBLE},
+				{name: "rts" number: 15 label: LABEL_REPEATED type: TYPE_MESSAGE type_name: ".google.protobuf.Timestamp"},
+				{name: "mdur" number: 16 label: LABEL_REPEATED type: TYPE_MESSAGE type_name: ".scratch.M.MdurEntry"},
+				{name: "mss" number: 17 label: LABEL_REPEATED type: TYPE_MESSAGE type_name: ".scratch.M.MssEntry"},
+				{name: "mi64" number: 18 label: LABEL_REPEATED type: TYPE_MESSAGE type_name: ".scratch.M.Mi64Entry"},
+				{name: "ob" number: 19 label: LABEL_OPTIONAL type: TYPE_BOOL proto3_optional: true oneof_index: 0},
+				{name: "ri32" number: 20 label: LABEL_REPEATED type: TYPE_INT32},
+				{name: "rf64" number: 21 label: LABEL_REPEATED type: TYPE_FIXED64},
+				{name: "u64" number: 22 label: LABEL_OPTIONAL type: TYPE_FIXED64},
+				{name: "msi" number: 23 label: LABEL_REPEATED type: TYPE_MESSAGE type_name: ".scratch.M.MsiEntry"},
+				{name: "mi32" number: 24 label: LABEL_REPEATED type: TYPE_MESSAGE type_name: ".scratch.M.Mi32Entry"},
+				{name: "mu64" number: 25 label: LABEL_REPEATED type: TYPE_MESSAGE type_name: ".scratch.M.Mu64Entry"},
+				{name: "st" number: 26 label: LABEL_OPTIONAL type: TYPE_MESSAGE type_name: ".google.protobuf.Struct"},
+				{name: "sv" number: 27 label: LABEL_OPTIONAL type: TYPE_MESSAGE type_name: ".google.protobuf.Value"},
+				{name: "sl" number: 28 label: LABEL_OPTIONAL type: TYPE_MESSAGE type_name: ".google.protobuf.ListValue"},
+				{name: "msi32" number: 29 label: LABEL_REPEATED type: TYPE_MESSAGE type_name: ".scratch.M.Msi32Entry"},
+				{name: "sw" number: 30 label: LABEL_OPTIONAL type: TYPE_MESSAGE type_name: ".google.protobuf.StringValue"},
+				{name: "rsw" number: 31 label: LABEL_REPEATED type: TYPE_MESSAGE type_name: ".google.protobuf.StringValue"},
+				{name: "iw" number: 32 label: LABEL_OPTIONAL type: TYPE_MESSAGE type_name: ".google.protobuf.Int64Value"},
+				{name: "rb" number: 33 label: LABEL_REPEATED type: TYPE_BYTES}
+			]
+			oneof_decl: [{name: "_ob"}]
+			nested_type: [
+				{name: "MdurEntry" options: {map_entry: true} field: [
+					{name: "key" number: 1 label: LABEL_OPTIONAL type: TYPE_STRING},
+					{name: "value" number: 2 label: LABEL_OPTIONAL type: TYPE_MESSAGE type_name: ".google.protobuf.Duration"}]},
+				{name: "MssEntry" options: {map_entry: true} field: [
+					{name: "key" number: 1 label: LABEL_OPTIONAL type: TYPE_STRING},
+					{name: "value" number: 2 label: LABEL_OPTIONAL type: TYPE_STRING}]},
+				{name: "Mi64Entry" options: {map_entry: true} field: [
+					{name: "key" number: 1 label: LABEL_OPTIONAL type: TYPE_INT64},
+					{name: "value" number: 2 label: LABEL_OPTIONAL type: TYPE_STRING}]},
+				{name: "MsiEntry" options: {map_entry: true} field: [
+					{name: "key" number: 1 label: LABEL_OPTIONAL type: TYPE_STRING},
+					{name: "value" number: 2 label: LABEL_OPTIONAL type: TYPE_INT64}]},
+				{name: "Mi32Entry" options: {map_entry: true} field: [
+					{name: "key" number: 1 label: LABEL_OPTIONAL type: TYPE_SINT32},
+					{name: "value" number: 2 label: LABEL_OPTIONAL type: TYPE_STRING}]},
+				{name: "Mu64Entry" options: {map_entry: true} field: [
+					{name: "key" number: 1 label: LABEL_OPTIONAL type: TYPE_FIXED64},
+					{name: "value" number: 2 label: LABEL_OPTIONAL type: TYPE_BOOL}]},
+				{name: "Msi32Entry" options: {map_entry: true} field: [
+					{name: "key" number: 1 label: LABEL_OPTIONAL type: TYPE_STRING},
+					{name: "value" number: 2 label: LABEL_OPTIONAL type: TYPE_INT32}]}
+			]
+		}]
+	`), fdp); err != nil {
+		panic(err)
+	}
+	fd, err := protodesc.NewFile(fdp, preg.GlobalFiles)
+	if err != nil {
+		panic(err)
+	}
+	return fd.Messages().Get(0)
+}()
+
+func field(name string) pref.FieldDescriptor {
+	return testMD.Fields().ByName(pref.Name(name))
+}
+
+var defaultsMD = func() pref.MessageDescriptor {
+	fdp := &descriptorpb.FileDescriptorProto{}
+	if err := prototext.Unmarshal([]byte(`
+		name: "defaults.proto" package: "defaults" syntax: "proto2"
+		enum_type: [{name: "Level" value: [{name: "LOW" number: 1}, {name: "HIGH" number: 2}]}]
+		message_type: [{
+			name: "D"
+			field: [
+				{name: "i32" number: 1 label: LABEL_OPTIONAL type: TYPE_INT32 default_value: "7"},
+				{name: "s" number: 2 label: LABEL_OPTIONAL type: TYPE_STRING default_value: "hi"},
+				{name: "d" number: 3 label: LABEL_OPTIONAL type: TYPE_DOUBLE default_value: "nan"},
+				{name: "level" number: 4 label: LABEL_OPTIONAL type: TYPE_ENUM type_name: ".defaults.Level" default_value: "HIGH"},
+				{name: "b" number: 5 label: LABEL_OPTIONAL type: TYPE_BYTES default_value: "ab"}
+			]
+		}]
+	`), fdp); err != nil {
+		panic(err)
+	}
+	fd, err := protodesc.NewFile(fdp, preg.GlobalFiles)
+	if err != nil {
+		panic(err)
+	}
+	return fd.Messages().Get(0)
+}()
+
+func defaultField(name string) pref.FieldDescriptor {
+	return defaultsMD.Fields().ByName(pref.Name(name))
+}
+
+// E is the Go enum type of scratch.E.
+type E int32
+
+func (E) Descriptor() pref.EnumDescriptor { return testMD.ParentFile().Enums().Get(0) }
+func (E) Type() pref.EnumType             { return eType{} }
+func (e E) Number() pref.EnumNumber       { return pref.EnumNumber(e) }
+
+type eType struct{}
+
+func (eType) New(n pref.EnumNumber) pref.Enum { return E(n) }
+func (eType) Descriptor() pref.EnumDescriptor { return E(0).Descriptor() }
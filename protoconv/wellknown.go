@@ -0,0 +1,41 @@
+package protoconv
+
+import (
+	pref "google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// Names and numbers of the well-known types used by the converters.
+// The packages for some of these types are not available to this module,
+// so their message types are resolved from the global registry by name.
+const (
+	durationFile  = "google/protobuf/duration.proto"
+	structFile    = "google/protobuf/struct.proto"
+	timestampFile = "google/protobuf/timestamp.proto"
+	wrappersFile  = "google/protobuf/wrappers.proto"
+
+	timestampMessageName pref.FullName = "google.protobuf.Timestamp"
+	structMessageName    pref.FullName = "google.protobuf.Struct"
+	valueMessageName     pref.FullName = "google.protobuf.Value"
+	listValueMessageName pref.FullName = "google.protobuf.ListValue"
+
+	durationSecondsField  pref.FieldNumber = 1
+	durationNanosField    pref.FieldNumber = 2
+	timestampSecondsField pref.FieldNumber = 1
+	timestampNanosField   pref.FieldNumber = 2
+	structFieldsField     pref.FieldNumber = 1
+	listValueValuesField  pref.FieldNumber = 1
+	wrapperValueField     pref.FieldNumber = 1
+
+	valueNullField   pref.FieldNumber = 1
+	valueNumberField pref.FieldNumber = 2
+	valueStringField pref.FieldNumber = 3
+	valueBoolField   pref.FieldNumber = 4
+	valueStructField pref.FieldNumber = 5
+	valueListField   pref.FieldNumber = 6
+
+	valueKindOneof pref.Name = "kind"
+
+	// stateGoName is the name of the field holding the internal state
+	// of generated message structs.
+	stateGoName = "state"
+)
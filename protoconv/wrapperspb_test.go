@@ -0,0 +1,77 @@
+package protoconv_test
+
+// Hand-written stand-in for the generated wrapperspb package, which is not vendored.
+
+import (
+	"reflect"
+
+	"google.golang.org/protobuf/encoding/prototext"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/runtime/protoimpl"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+type StringValue struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Value string `protobuf:"bytes,1,opt,name=value,proto3"`
+}
+
+func (x *StringValue) ProtoReflect() protoreflect.Message { return wpReflect(x, 0) }
+
+type Int64Value struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Value int64 `protobuf:"varint,1,opt,name=value,proto3"`
+}
+
+func (x *Int64Value) ProtoReflect() protoreflect.Message { return wpReflect(x, 1) }
+
+func wpReflect(x interface{}, i int) protoreflect.Message {
+	mi := &wpMsgTypes[i]
+	rv := reflect.ValueOf(x)
+	if protoimpl.UnsafeEnabled && !rv.IsNil() {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(rv.Pointer()))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+var wpMsgTypes = make([]protoimpl.MessageInfo, 2)
+
+// wpFile is referenced by testMD so that it is built first.
+var wpFile = func() protoreflect.FileDescriptor {
+	fdp := &descriptorpb.FileDescriptorProto{}
+	if err := prototext.Unmarshal([]byte(`
+		name: "google/protobuf/wrappers.proto" package: "google.protobuf" syntax: "proto3"
+		message_type: [
+			{name: "StringValue" field: [{name: "value" number: 1 label: LABEL_OPTIONAL type: TYPE_STRING json_name: "value"}]},
+			{name: "Int64Value" field: [{name: "value" number: 1 label: LABEL_OPTIONAL type: TYPE_INT64 json_name: "value"}]}
+		]
+	`), fdp); err != nil {
+		panic(err)
+	}
+	raw, err := proto.Marshal(fdp)
+	if err != nil {
+		panic(err)
+	}
+	type x struct{}
+	return protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: raw,
+			NumMessages:   2,
+		},
+		GoTypes:           []interface{}{(*StringValue)(nil), (*Int64Value)(nil)},
+		DependencyIndexes: []int32{0, 0, 0, 0, 0},
+		MessageInfos:      wpMsgTypes,
+	}.Build().File
+}()